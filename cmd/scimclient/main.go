@@ -0,0 +1,162 @@
+// Command scimclient plays the identity provider against a locally running server, so the SCIM
+// endpoints can be checked without a real identity provider. It provisions a user, changes them,
+// puts them in a group and deactivates them, stopping at the first response that is not as expected.
+//
+//	SCIM_BEARER_TOKEN=... go run ./cmd/scimclient -url http://localhost:8080/scim/v2
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	userSchema       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	groupSchema      = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	enterpriseSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	patchSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+)
+
+type scimClient struct {
+	baseURL string
+	token   string
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080/scim/v2", "SCIM base URL")
+	group := flag.String("group", "", "group display name; map it to a department in the SCIM settings to check department moves")
+	flag.Parse()
+
+	client := scimClient{baseURL: *baseURL, token: os.Getenv("SCIM_BEARER_TOKEN")}
+	run := time.Now().Unix()
+	email := fmt.Sprintf("scim.fixture.%d@example.com", run)
+	employeeNumber := fmt.Sprintf("SCIM-%d", run)
+	if *group == "" {
+		*group = fmt.Sprintf("SCIM Fixture %d", run)
+	}
+
+	client.call("service provider config", http.MethodGet, "/ServiceProviderConfig", nil, http.StatusOK)
+
+	user := client.call("create user", http.MethodPost, "/Users", map[string]interface{}{
+		"schemas":    []string{userSchema, enterpriseSchema},
+		"userName":   email,
+		"externalId": fmt.Sprintf("idp-%d", run),
+		"name":       map[string]interface{}{"givenName": "Scim", "familyName": "Fixture"},
+		"emails":     []interface{}{map[string]interface{}{"value": email, "type": "work", "primary": true}},
+		"title":      "G5",
+		"userType":   "full_time",
+		"active":     true,
+		enterpriseSchema: map[string]interface{}{
+			"employeeNumber": employeeNumber,
+		},
+	}, http.StatusCreated)
+	userID := user["id"].(string)
+
+	found := client.call("find user by userName", http.MethodGet, fmt.Sprintf("/Users?filter=userName%%20eq%%20%%22%s%%22", email), nil, http.StatusOK)
+	expect(found["totalResults"] == float64(1), "filter should find exactly one user, got %v", found["totalResults"])
+
+	client.call("create duplicate user", http.MethodPost, "/Users", map[string]interface{}{
+		"schemas":        []string{userSchema},
+		"userName":       email,
+		"name":           map[string]interface{}{"givenName": "Scim", "familyName": "Fixture"},
+		enterpriseSchema: map[string]interface{}{"employeeNumber": employeeNumber + "-2"},
+	}, http.StatusConflict)
+
+	patched := client.call("patch title", http.MethodPatch, "/Users/"+userID, map[string]interface{}{
+		"schemas":    []string{patchSchema},
+		"Operations": []interface{}{map[string]interface{}{"op": "Replace", "path": "title", "value": "G6"}},
+	}, http.StatusOK)
+	expect(patched["title"] == "G6", "title should be G6, got %v", patched["title"])
+
+	created := client.call("create group", http.MethodPost, "/Groups", map[string]interface{}{
+		"schemas":     []string{groupSchema},
+		"displayName": *group,
+		"members":     []interface{}{},
+	}, http.StatusCreated)
+	groupID := created["id"].(string)
+
+	withMember := client.call("add group member", http.MethodPatch, "/Groups/"+groupID, map[string]interface{}{
+		"schemas":    []string{patchSchema},
+		"Operations": []interface{}{map[string]interface{}{"op": "add", "path": "members", "value": []interface{}{map[string]interface{}{"value": userID}}}},
+	}, http.StatusOK)
+	expect(len(withMember["members"].([]interface{})) == 1, "group should have one member")
+
+	deactivated := client.call("deactivate user", http.MethodPatch, "/Users/"+userID, map[string]interface{}{
+		"schemas":    []string{patchSchema},
+		"Operations": []interface{}{map[string]interface{}{"op": "replace", "value": map[string]interface{}{"active": "False"}}},
+	}, http.StatusOK)
+	expect(deactivated["active"] == false, "user should be inactive")
+
+	reactivated := client.call("reactivate user", http.MethodPut, "/Users/"+userID, map[string]interface{}{
+		"schemas":        []string{userSchema, enterpriseSchema},
+		"userName":       email,
+		"name":           map[string]interface{}{"givenName": "Scim", "familyName": "Fixture"},
+		"active":         true,
+		enterpriseSchema: map[string]interface{}{"employeeNumber": employeeNumber},
+	}, http.StatusOK)
+	expect(reactivated["active"] == true, "user should be active again")
+
+	client.call("remove group member", http.MethodPatch, "/Groups/"+groupID, map[string]interface{}{
+		"schemas":    []string{patchSchema},
+		"Operations": []interface{}{map[string]interface{}{"op": "remove", "path": fmt.Sprintf("members[value eq %q]", userID)}},
+	}, http.StatusOK)
+	client.call("delete group", http.MethodDelete, "/Groups/"+groupID, nil, http.StatusNoContent)
+	client.call("delete user", http.MethodDelete, "/Users/"+userID, nil, http.StatusNoContent)
+
+	final := client.call("read deleted user", http.MethodGet, "/Users/"+userID, nil, http.StatusOK)
+	expect(final["active"] == false, "deleted user should be inactive")
+
+	fmt.Println("all SCIM checks passed")
+}
+
+// call sends one request and fails unless the response has the wanted status
+func (s scimClient) call(step, method, path string, body interface{}, wantStatus int) map[string]interface{} {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			log.Fatalf("%s: %v", step, err)
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, s.baseURL+path, payload)
+	if err != nil {
+		log.Fatalf("%s: %v", step, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/scim+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("%s: %v", step, err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != wantStatus {
+		log.Fatalf("%s: got status %d, want %d: %s", step, resp.StatusCode, wantStatus, raw)
+	}
+	fmt.Printf("ok   %-24s %s %s -> %d\n", step, method, path, resp.StatusCode)
+
+	result := map[string]interface{}{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &result); err != nil {
+			log.Fatalf("%s: response is not JSON: %s", step, raw)
+		}
+	}
+	return result
+}
+
+func expect(ok bool, format string, args ...interface{}) {
+	if !ok {
+		log.Fatalf("check failed: "+format, args...)
+	}
+}
@@ -0,0 +1,302 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var errAdjustmentNotPending = errors.New("adjustment is no longer waiting for approval")
+
+// CreateBalanceAdjustment - Admin credits or debits an employee's company coupons with a reason.
+// Adjustments larger than ADJUSTMENT_APPROVAL_THRESHOLD coupons wait for a second admin's approval.
+func CreateBalanceAdjustment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		employeeID := c.Param("id")
+
+		var req models.CreateBalanceAdjustmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: employeeID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		now := time.Now()
+		adjustment := models.BalanceAdjustment{
+			AdjustmentID:      bson.NewObjectID().Hex(),
+			EmployeeID:        employeeID,
+			Quantity:          req.Quantity,
+			ReasonCode:        req.ReasonCode,
+			Note:              req.Note,
+			Status:            "applied",
+			RequestedByUserID: adminUserID,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+
+		threshold := utils.GetEnvAsInt("ADJUSTMENT_APPROVAL_THRESHOLD", 10)
+		needsApproval := abs(req.Quantity) > threshold
+		if needsApproval {
+			adjustment.Status = "pending_approval"
+		} else {
+			adjustment.AppliedAt = &now
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		adjustmentCollection := database.OpenCollection("balance_adjustments", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			if _, err := adjustmentCollection.InsertOne(sessCtx, adjustment); err != nil {
+				return nil, err
+			}
+			if !needsApproval {
+				return nil, applyBalanceAdjustment(sessCtx, client, adjustment, employee, adminUserID)
+			}
+
+			err := recordAudit(sessCtx, client, adminUserID, "adjustment.requested", "employee", employeeID, bson.M{
+				"adjustment_id": adjustment.AdjustmentID,
+				"quantity":      adjustment.Quantity,
+				"reason_code":   adjustment.ReasonCode,
+				"note":          adjustment.Note,
+			})
+			if err != nil {
+				return nil, err
+			}
+			message := fmt.Sprintf("An adjustment of %+d coupon(s) for %s (%s) needs a second admin's approval.", adjustment.Quantity, employee.Name, employee.EmployeeCode)
+			return nil, notifyAdmins(sessCtx, client, "adjustment_pending", "Balance adjustment awaiting approval", message, adjustment.AdjustmentID)
+		})
+		if errors.Is(err, errInsufficientBalance) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The employee does not have enough company coupons for this debit"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create adjustment"})
+			return
+		}
+
+		if needsApproval {
+			c.JSON(http.StatusAccepted, gin.H{
+				"message":       "Adjustment is waiting for a second admin's approval",
+				"adjustment_id": adjustment.AdjustmentID,
+				"status":        adjustment.Status,
+			})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"message":       "Adjustment applied successfully",
+			"adjustment_id": adjustment.AdjustmentID,
+			"status":        adjustment.Status,
+		})
+	}
+}
+
+// DecideBalanceAdjustment - A second admin approves and applies, or rejects, a pending adjustment
+func DecideBalanceAdjustment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adjustmentID := c.Param("id")
+
+		var req models.AdjustmentDecisionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		adjustmentCollection := database.OpenCollection("balance_adjustments", client)
+		var adjustment models.BalanceAdjustment
+		err = adjustmentCollection.FindOne(ctx, bson.D{{Key: "adjustment_id", Value: adjustmentID}}).Decode(&adjustment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Adjustment not found"})
+			return
+		}
+		if adjustment.Status != "pending_approval" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Adjustment has already been processed", "status": adjustment.Status})
+			return
+		}
+		if adjustment.RequestedByUserID == adminUserID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "An adjustment must be approved by a different admin than the one who requested it"})
+			return
+		}
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: adjustment.EmployeeID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		now := time.Now()
+		newStatus := "rejected"
+		update := bson.M{
+			"status":          newStatus,
+			"decision_reason": req.Reason,
+			"decided_at":      now,
+			"updated_at":      now,
+		}
+		if req.Approved {
+			newStatus = "applied"
+			update["status"] = newStatus
+			update["approved_by_user_id"] = adminUserID
+			update["applied_at"] = now
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			result, err := adjustmentCollection.UpdateOne(
+				sessCtx,
+				bson.D{
+					{Key: "adjustment_id", Value: adjustmentID},
+					{Key: "status", Value: "pending_approval"},
+				},
+				bson.D{{Key: "$set", Value: update}},
+			)
+			if err != nil {
+				return nil, err
+			}
+			if result.MatchedCount == 0 {
+				return nil, errAdjustmentNotPending
+			}
+
+			if !req.Approved {
+				return nil, recordAudit(sessCtx, client, adminUserID, "adjustment.rejected", "employee", adjustment.EmployeeID, bson.M{
+					"adjustment_id": adjustment.AdjustmentID,
+					"quantity":      adjustment.Quantity,
+					"reason":        req.Reason,
+				})
+			}
+			adjustment.ApprovedByUserID = adminUserID
+			return nil, applyBalanceAdjustment(sessCtx, client, adjustment, employee, adminUserID)
+		})
+		switch {
+		case errors.Is(err, errAdjustmentNotPending):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Adjustment has already been processed"})
+			return
+		case errors.Is(err, errInsufficientBalance):
+			c.JSON(http.StatusConflict, gin.H{"error": "The employee no longer has enough company coupons for this debit"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process adjustment"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":       "Adjustment " + newStatus,
+			"adjustment_id": adjustmentID,
+			"status":        newStatus,
+		})
+	}
+}
+
+// GetBalanceAdjustments - Admin lists adjustments, newest first (?status=, ?employee_id=)
+func GetBalanceAdjustments(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.D{}
+		if status := c.Query("status"); status != "" {
+			filter = append(filter, bson.E{Key: "status", Value: status})
+		}
+		if employeeID := c.Query("employee_id"); employeeID != "" {
+			filter = append(filter, bson.E{Key: "employee_id", Value: employeeID})
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		adjustmentCollection := database.OpenCollection("balance_adjustments", client)
+		cursor, err := adjustmentCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch adjustments"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		adjustments := []models.BalanceAdjustment{}
+		if err = cursor.All(ctx, &adjustments); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode adjustments"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"adjustments": adjustments,
+			"total":       len(adjustments),
+		})
+	}
+}
+
+// applyBalanceAdjustment moves the coupons, records the adjustment in the audit log and tells the employee.
+// It must be called inside a session transaction.
+func applyBalanceAdjustment(ctx context.Context, client *mongo.Client, adjustment models.BalanceAdjustment, employee models.Employee, actorUserID string) error {
+	description := fmt.Sprintf("Manual adjustment (%s): %s", adjustment.ReasonCode, adjustment.Note)
+	updated, _, err := applyBalanceChange(ctx, client, adjustment.EmployeeID, couponBalanceChange{Company: adjustment.Quantity}, "adjustment", adjustment.AdjustmentID, description)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(ctx, client, actorUserID, "adjustment.applied", "employee", adjustment.EmployeeID, bson.M{
+		"adjustment_id":        adjustment.AdjustmentID,
+		"quantity":             adjustment.Quantity,
+		"reason_code":          adjustment.ReasonCode,
+		"note":                 adjustment.Note,
+		"requested_by_user_id": adjustment.RequestedByUserID,
+		"approved_by_user_id":  adjustment.ApprovedByUserID,
+		"balance_after":        updated.CurrentBalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Your coupon balance was adjusted by %+d. Reason: %s", adjustment.Quantity, adjustment.Note)
+	return notifyUser(ctx, client, employee.UserID, "balance_adjusted", "Coupon balance adjusted", message, adjustment.AdjustmentID)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var errAlreadyAllocated = errors.New("employee already received this month's allocation")
+
+// RunAllocation - Admin runs the monthly allocation now instead of waiting for the nightly job
+func RunAllocation(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		allocated, err := RunMonthlyAllocation(ctx, client, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Allocation run failed", "details": err.Error(), "allocated": allocated})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Allocation run completed",
+			"allocated": allocated,
+		})
+	}
+}
+
+// RunMonthlyAllocation applies the carry-over policy and issues the monthly allocation to every
+// active employee not yet allocated this month. Employees on leave are allocated pro rata when
+// they return. It is safe to run repeatedly and returns how many employees were allocated.
+func RunMonthlyAllocation(ctx context.Context, client *mongo.Client, now time.Time) (int, error) {
+	settings, err := loadOrganizationSettings(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	policies, err := loadAllocationPolicies(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	employeeCollection := database.OpenCollection("employees", client)
+	cursor, err := employeeCollection.Find(ctx, bson.D{
+		{Key: "status", Value: "active"},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "last_allocation_date", Value: bson.D{{Key: "$lt", Value: monthStart}}}},
+			bson.D{{Key: "last_allocation_date", Value: nil}},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var employees []models.Employee
+	if err = cursor.All(ctx, &employees); err != nil {
+		return 0, err
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.EndSession(ctx)
+
+	allocated := 0
+	for _, employee := range employees {
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			return nil, allocateEmployee(sessCtx, client, employee.EmployeeID, settings, policies, monthStart, now)
+		})
+		if errors.Is(err, errAlreadyAllocated) {
+			continue
+		}
+		if err != nil {
+			return allocated, err
+		}
+		allocated++
+	}
+	return allocated, nil
+}
+
+// allocateEmployee forfeits what the carry-over policy does not keep and issues the monthly allocation.
+// It must be called inside a session transaction.
+func allocateEmployee(ctx context.Context, client *mongo.Client, employeeID string, settings models.OrganizationSettings, policies []models.AllocationPolicy, monthStart, now time.Time) error {
+	employee, err := claimMonthlyAllocation(ctx, client, employeeID, monthStart, now)
+	if err != nil {
+		return err
+	}
+
+	policy := settings.CarryOverPolicy
+	forfeit := 0
+	switch policy.Mode {
+	case "none":
+		forfeit = employee.CurrentBalance
+	case "capped":
+		forfeit = employee.CurrentBalance - policy.Cap
+	}
+	if forfeit > 0 {
+		description := fmt.Sprintf("%d unused coupon(s) not carried over", forfeit)
+		if _, _, err := applyBalanceChange(ctx, client, employeeID, couponBalanceChange{Company: -forfeit}, "expiry", "", description); err != nil {
+			return err
+		}
+	}
+
+	// Hires starting later this month only get the working days from their first day
+	if !employee.HireDate.Before(monthStart.AddDate(0, 1, 0)) {
+		return nil
+	}
+	rate := resolveAllocationRate(employee, policies, settings)
+	_, err = allocateFrom(ctx, client, employee, settings, rate, latest(monthStart, employee.HireDate), now, "")
+	return err
+}
+
+// claimMonthlyAllocation marks the employee as allocated for the month so concurrent runs allocate once.
+// It returns the employee as it was before the claim, or errAlreadyAllocated.
+func claimMonthlyAllocation(ctx context.Context, client *mongo.Client, employeeID string, monthStart, now time.Time) (models.Employee, error) {
+	employeeCollection := database.OpenCollection("employees", client)
+	var employee models.Employee
+	err := employeeCollection.FindOneAndUpdate(
+		ctx,
+		bson.D{
+			{Key: "employee_id", Value: employeeID},
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "last_allocation_date", Value: bson.D{{Key: "$lt", Value: monthStart}}}},
+				bson.D{{Key: "last_allocation_date", Value: nil}},
+			}},
+		},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "last_allocation_date", Value: now},
+			{Key: "updated_at", Value: now},
+		}}},
+	).Decode(&employee)
+	if err == mongo.ErrNoDocuments {
+		return employee, errAlreadyAllocated
+	}
+	return employee, err
+}
+
+// allocateFrom issues the allocation at the given rate for the working days from the given day to the end
+// of its month and records the full month's entitlement on the employee. Nothing is issued while the
+// employee's cost center is over its hard budget threshold. It returns the coupons issued.
+func allocateFrom(ctx context.Context, client *mongo.Client, employee models.Employee, settings models.OrganizationSettings, rate allocationRate, from, now time.Time, note string) (int, error) {
+	employeeID := employee.EmployeeID
+	quantity, monthly, description, err := proratedAllocation(ctx, client, settings, rate, from)
+	if err != nil {
+		return 0, err
+	}
+
+	budget, err := blockingBudget(ctx, client, employee, now)
+	if err != nil {
+		return 0, err
+	}
+	if budget != nil {
+		description = fmt.Sprintf("Allocation withheld, cost center %s is over its %s meal budget (%s%s)", budget.CostCenter, budget.Period, description, note)
+		_, _, err = applyBalanceChange(ctx, client, employeeID, couponBalanceChange{}, "allocation", budget.BudgetID, description)
+		return 0, err
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	_, err = employeeCollection.UpdateOne(
+		ctx,
+		bson.D{{Key: "employee_id", Value: employeeID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "monthly_coupon_allocation", Value: monthly}}}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return quantity, issueAllocation(ctx, client, employeeID, quantity, settings.CarryOverPolicy, now, description+note)
+}
+
+// issueAllocation credits an allocation as a new lot that expires according to the carry-over policy
+func issueAllocation(ctx context.Context, client *mongo.Client, employeeID string, quantity int, policy models.CarryOverPolicy, now time.Time, description string) error {
+	if quantity <= 0 {
+		return nil
+	}
+
+	lot := models.LotDraw{Quantity: quantity}
+	if (policy.Mode == "full" || policy.Mode == "") && policy.ExpiryMonths > 0 {
+		expiresAt := now.AddDate(0, policy.ExpiryMonths, 0)
+		lot.ExpiresAt = &expiresAt
+	}
+
+	_, _, err := applyBalanceChange(ctx, client, employeeID, couponBalanceChange{Company: quantity, Lots: []models.LotDraw{lot}}, "allocation", "", description)
+	return err
+}
+
+// proratedAllocation works out the coupons for the working days from the given day to the end of its month,
+// and for the whole month. It also returns the balance history description explaining the amount.
+func proratedAllocation(ctx context.Context, client *mongo.Client, settings models.OrganizationSettings, rate allocationRate, from time.Time) (int, int, string, error) {
+	monthStart := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	period := monthStart.Format("January 2006")
+	if rate.AssignedBy != "default" {
+		period += " (" + rate.PolicyName + ")"
+	}
+
+	closedDates, err := closedCalendarDates(ctx, client, monthStart, monthEnd)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	quantity, monthly, description := prorateMonth(rate, from, settings.WeekendDays, closedDates, period)
+	return quantity, monthly, description, nil
+}
+
+// prorateMonth is the arithmetic of proratedAllocation once the month's closed dates are known
+func prorateMonth(rate allocationRate, from time.Time, weekendDays []int, closedDates map[string]bool, period string) (int, int, string) {
+	monthStart := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	totalDays := utils.WorkingDaysBetween(monthStart, monthEnd, weekendDays, closedDates)
+	remainingDays := utils.WorkingDaysBetween(from, monthEnd, weekendDays, closedDates)
+
+	monthly := rate.coupons(totalDays, totalDays)
+	if remainingDays >= totalDays {
+		return monthly, monthly, fmt.Sprintf("Allocation for %s: %d working days", period, totalDays)
+	}
+	return rate.coupons(remainingDays, totalDays), monthly, fmt.Sprintf("Pro-rated allocation for %s: %d of %d working days", period, remainingDays, totalDays)
+}
+
+// applyStatusChange adjusts the balance for an employee moving between statuses:
+// termination forfeits or freezes unused coupons, and a return to active issues the rest of the
+// month's allocation pro rata if none was issued this month. It must be called inside a session transaction.
+func applyStatusChange(ctx context.Context, client *mongo.Client, employee models.Employee, newStatus string, settings models.OrganizationSettings, now time.Time) error {
+	if newStatus == employee.Status {
+		return nil
+	}
+
+	switch {
+	case newStatus == "terminated":
+		return terminateBalance(ctx, client, employee, settings.TerminationPolicy, now)
+
+	case newStatus == "active" && (employee.Status == "on_leave" || employee.Status == "terminated"):
+		if employee.BalanceFrozenAt != nil {
+			if err := unfreezeBalance(ctx, client, employee, now); err != nil {
+				return err
+			}
+		}
+
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		_, err := claimMonthlyAllocation(ctx, client, employee.EmployeeID, monthStart, now)
+		if errors.Is(err, errAlreadyAllocated) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rate, err := resolveEmployeeAllocationRate(ctx, client, employee, settings)
+		if err != nil {
+			return err
+		}
+		_, err = allocateFrom(ctx, client, employee, settings, rate, now, now, " after returning")
+		return err
+	}
+	return nil
+}
+
+// terminateBalance releases open pre-orders, then forfeits or freezes the company coupons left.
+// Paid coupons were bought by the employee and are left alone.
+func terminateBalance(ctx context.Context, client *mongo.Client, employee models.Employee, policy models.TerminationPolicy, now time.Time) error {
+	preorders, err := findPreOrders(ctx, client, bson.D{
+		{Key: "employee_id", Value: employee.EmployeeID},
+		{Key: "status", Value: "reserved"},
+	})
+	if err != nil {
+		return err
+	}
+	for _, preorder := range preorders {
+		if err := releasePreOrder(ctx, client, preorder, "cancelled", "Employee terminated"); err != nil {
+			return err
+		}
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	if err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: employee.EmployeeID}}).Decode(&employee); err != nil {
+		return err
+	}
+
+	if policy.UnusedCoupons == "forfeit" {
+		if employee.CurrentBalance <= 0 {
+			return nil
+		}
+		description := fmt.Sprintf("%d unused coupon(s) forfeited on termination", employee.CurrentBalance)
+		_, _, err = applyBalanceChange(ctx, client, employee.EmployeeID, couponBalanceChange{Company: -employee.CurrentBalance}, "termination", "", description)
+		return err
+	}
+
+	_, err = employeeCollection.UpdateOne(
+		ctx,
+		bson.D{{Key: "employee_id", Value: employee.EmployeeID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "balance_frozen_at", Value: now}}}},
+	)
+	if err != nil {
+		return err
+	}
+	description := fmt.Sprintf("Balance of %d coupon(s) frozen on termination", employee.CurrentBalance)
+	_, _, err = applyBalanceChange(ctx, client, employee.EmployeeID, couponBalanceChange{}, "freeze", "", description)
+	return err
+}
+
+// unfreezeBalance makes a frozen balance spendable again when a terminated employee is reinstated
+func unfreezeBalance(ctx context.Context, client *mongo.Client, employee models.Employee, now time.Time) error {
+	employeeCollection := database.OpenCollection("employees", client)
+	_, err := employeeCollection.UpdateOne(
+		ctx,
+		bson.D{{Key: "employee_id", Value: employee.EmployeeID}},
+		bson.D{{Key: "$unset", Value: bson.D{{Key: "balance_frozen_at", Value: ""}}}},
+	)
+	if err != nil {
+		return err
+	}
+	description := fmt.Sprintf("Frozen balance of %d coupon(s) released on reinstatement", employee.CurrentBalance)
+	_, _, err = applyBalanceChange(ctx, client, employee.EmployeeID, couponBalanceChange{}, "unfreeze", "", description)
+	return err
+}
+
+// latest returns the later of two times
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
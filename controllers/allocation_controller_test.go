@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProrateMonth(t *testing.T) {
+	weekend := []int{0, 6}
+	perDay := allocationRate{AssignedBy: "default", CouponsPerWorkingDay: 1}
+	monthly := allocationRate{AssignedBy: "default", MonthlyAllocation: 30}
+	closed := map[string]bool{"2026-03-20": true}
+
+	tests := []struct {
+		name            string
+		rate            allocationRate
+		from            time.Time
+		closedDates     map[string]bool
+		wantQuantity    int
+		wantMonthly     int
+		wantDescription string
+	}{
+		{
+			name: "whole month", rate: perDay, from: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+			wantQuantity: 22, wantMonthly: 22, wantDescription: "Allocation for March 2026: 22 working days",
+		},
+		{
+			name: "mid-month hire", rate: perDay, from: time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+			wantQuantity: 12, wantMonthly: 22, wantDescription: "Pro-rated allocation for March 2026: 12 of 22 working days",
+		},
+		{
+			name: "closed date is not a working day", rate: perDay, from: time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), closedDates: closed,
+			wantQuantity: 11, wantMonthly: 21, wantDescription: "Pro-rated allocation for March 2026: 11 of 21 working days",
+		},
+		{
+			name: "start on a weekend", rate: perDay, from: time.Date(2026, 3, 28, 0, 0, 0, 0, time.UTC),
+			wantQuantity: 2, wantMonthly: 22, wantDescription: "Pro-rated allocation for March 2026: 2 of 22 working days",
+		},
+		{
+			name: "fixed monthly budget rounds down", rate: monthly, from: time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+			wantQuantity: 16, wantMonthly: 30, wantDescription: "Pro-rated allocation for March 2026: 12 of 22 working days",
+		},
+		{
+			name: "fixed monthly budget rounds up", rate: monthly, from: time.Date(2026, 3, 28, 0, 0, 0, 0, time.UTC),
+			wantQuantity: 3, wantMonthly: 30, wantDescription: "Pro-rated allocation for March 2026: 2 of 22 working days",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quantity, monthlyQuantity, description := prorateMonth(tt.rate, tt.from, weekend, tt.closedDates, "March 2026")
+			if quantity != tt.wantQuantity || monthlyQuantity != tt.wantMonthly || description != tt.wantDescription {
+				t.Errorf("prorateMonth() = %d, %d, %q; want %d, %d, %q",
+					quantity, monthlyQuantity, description, tt.wantQuantity, tt.wantMonthly, tt.wantDescription)
+			}
+		})
+	}
+}
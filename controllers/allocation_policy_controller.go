@@ -0,0 +1,423 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// allocationRate is the entitlement an employee resolves to, from a policy or the organization default
+type allocationRate struct {
+	PolicyID             string
+	PolicyName           string
+	AssignedBy           string // explicit | rule | default
+	CouponsPerWorkingDay int
+	MonthlyAllocation    int
+}
+
+// coupons works out the allocation for the given working days out of the month's total.
+// A fixed monthly budget is pro-rated by the share of working days, rounded to the nearest coupon.
+func (r allocationRate) coupons(workingDays, totalDays int) int {
+	if r.MonthlyAllocation == 0 {
+		return workingDays * r.CouponsPerWorkingDay
+	}
+	if workingDays >= totalDays {
+		return r.MonthlyAllocation
+	}
+	return (r.MonthlyAllocation*workingDays + totalDays/2) / totalDays
+}
+
+// resolveAllocationRate picks the employee's entitlement: an explicitly assigned active policy first,
+// then the highest-priority active policy with a matching rule, then the organization default.
+// Policies must be sorted by priority, highest first.
+func resolveAllocationRate(employee models.Employee, policies []models.AllocationPolicy, settings models.OrganizationSettings) allocationRate {
+	if employee.AllocationPolicyID != "" {
+		for _, policy := range policies {
+			if policy.PolicyID == employee.AllocationPolicyID {
+				return policyRate(policy, "explicit")
+			}
+		}
+	}
+
+	for _, policy := range policies {
+		for _, rule := range policy.Rules {
+			if allocationRuleMatches(rule, employee) {
+				return policyRate(policy, "rule")
+			}
+		}
+	}
+
+	return allocationRate{
+		PolicyName:           "Organization default",
+		AssignedBy:           "default",
+		CouponsPerWorkingDay: settings.CouponsPerWorkingDay,
+	}
+}
+
+func policyRate(policy models.AllocationPolicy, assignedBy string) allocationRate {
+	return allocationRate{
+		PolicyID:             policy.PolicyID,
+		PolicyName:           policy.Name,
+		AssignedBy:           assignedBy,
+		CouponsPerWorkingDay: policy.CouponsPerWorkingDay,
+		MonthlyAllocation:    policy.MonthlyAllocation,
+	}
+}
+
+// allocationRuleMatches reports whether the employee has every attribute the rule sets
+func allocationRuleMatches(rule models.AllocationRule, employee models.Employee) bool {
+	return (rule.DepartmentID == "" || rule.DepartmentID == employee.DepartmentID) &&
+		(rule.JobGrade == "" || rule.JobGrade == employee.JobGrade) &&
+		(rule.ShiftPattern == "" || rule.ShiftPattern == employee.ShiftPattern) &&
+		(rule.EmploymentType == "" || rule.EmploymentType == employee.EmploymentType)
+}
+
+// validateAllocationPolicy checks the policy has exactly one kind of entitlement and no empty rules
+func validateAllocationPolicy(policy models.AllocationPolicy) error {
+	if (policy.CouponsPerWorkingDay > 0) == (policy.MonthlyAllocation > 0) {
+		return errors.New("Set either coupons_per_working_day or monthly_allocation")
+	}
+	for _, rule := range policy.Rules {
+		if rule == (models.AllocationRule{}) {
+			return errors.New("Each rule needs at least one of department_id, job_grade, shift_pattern or employment_type")
+		}
+	}
+	return nil
+}
+
+// loadAllocationPolicies returns the active policies, highest priority first
+func loadAllocationPolicies(ctx context.Context, client *mongo.Client) ([]models.AllocationPolicy, error) {
+	return findAllocationPolicies(ctx, client, bson.D{{Key: "is_active", Value: true}})
+}
+
+// resolveEmployeeAllocationRate loads the active policies and resolves the employee's entitlement
+func resolveEmployeeAllocationRate(ctx context.Context, client *mongo.Client, employee models.Employee, settings models.OrganizationSettings) (allocationRate, error) {
+	policies, err := loadAllocationPolicies(ctx, client)
+	if err != nil {
+		return allocationRate{}, err
+	}
+	return resolveAllocationRate(employee, policies, settings), nil
+}
+
+// CreateAllocationPolicy - Admin creates a named allocation policy with its matching rules
+func CreateAllocationPolicy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateAllocationPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		now := time.Now()
+		policy := models.AllocationPolicy{
+			PolicyID:             bson.NewObjectID().Hex(),
+			Name:                 req.Name,
+			Description:          req.Description,
+			CouponsPerWorkingDay: req.CouponsPerWorkingDay,
+			MonthlyAllocation:    req.MonthlyAllocation,
+			Rules:                req.Rules,
+			Priority:             req.Priority,
+			IsActive:             true,
+			CreatedByUserID:      adminUserID,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		}
+		if policy.Rules == nil {
+			policy.Rules = []models.AllocationRule{}
+		}
+		if err := validateAllocationPolicy(policy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		policyCollection := database.OpenCollection("allocation_policies", client)
+		if _, err := policyCollection.InsertOne(ctx, policy); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create allocation policy"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":   "Allocation policy created successfully",
+			"policy_id": policy.PolicyID,
+		})
+	}
+}
+
+// GetAllocationPolicies - Admin lists allocation policies, highest priority first (?active=true for active only)
+func GetAllocationPolicies(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		filter := bson.D{}
+		if c.Query("active") == "true" {
+			filter = append(filter, bson.E{Key: "is_active", Value: true})
+		}
+
+		policies, err := findAllocationPolicies(ctx, client, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allocation policies"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"policies": policies,
+			"total":    len(policies),
+		})
+	}
+}
+
+// UpdateAllocationPolicy - Admin changes a policy's entitlement, rules or priority, or deactivates it.
+// Allocations already issued are not recalculated.
+func UpdateAllocationPolicy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policyID := c.Param("id")
+
+		var req models.UpdateAllocationPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		policyCollection := database.OpenCollection("allocation_policies", client)
+		var policy models.AllocationPolicy
+		err := policyCollection.FindOne(ctx, bson.D{{Key: "policy_id", Value: policyID}}).Decode(&policy)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Allocation policy not found"})
+			return
+		}
+
+		if req.Name != "" {
+			policy.Name = req.Name
+		}
+		if req.Description != nil {
+			policy.Description = *req.Description
+		}
+		if req.CouponsPerWorkingDay != nil {
+			policy.CouponsPerWorkingDay = *req.CouponsPerWorkingDay
+		}
+		if req.MonthlyAllocation != nil {
+			policy.MonthlyAllocation = *req.MonthlyAllocation
+		}
+		if req.Rules != nil {
+			policy.Rules = *req.Rules
+		}
+		if req.Priority != nil {
+			policy.Priority = *req.Priority
+		}
+		if req.IsActive != nil {
+			policy.IsActive = *req.IsActive
+		}
+		if err := validateAllocationPolicy(policy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		policy.UpdatedAt = time.Now()
+
+		_, err = policyCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "policy_id", Value: policyID}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "name", Value: policy.Name},
+				{Key: "description", Value: policy.Description},
+				{Key: "coupons_per_working_day", Value: policy.CouponsPerWorkingDay},
+				{Key: "monthly_allocation", Value: policy.MonthlyAllocation},
+				{Key: "rules", Value: policy.Rules},
+				{Key: "priority", Value: policy.Priority},
+				{Key: "is_active", Value: policy.IsActive},
+				{Key: "updated_at", Value: policy.UpdatedAt},
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update allocation policy"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Allocation policy updated successfully",
+			"policy":  policy,
+		})
+	}
+}
+
+// AssignAllocationPolicy - Admin assigns a policy to an employee explicitly, or clears the assignment
+func AssignAllocationPolicy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		employeeID := c.Param("id")
+
+		var req models.AssignAllocationPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		update := bson.D{{Key: "$unset", Value: bson.D{{Key: "allocation_policy_id", Value: ""}}}}
+		if req.PolicyID != "" {
+			policyCollection := database.OpenCollection("allocation_policies", client)
+			var policy models.AllocationPolicy
+			err := policyCollection.FindOne(ctx, bson.D{{Key: "policy_id", Value: req.PolicyID}}).Decode(&policy)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Allocation policy not found"})
+				return
+			}
+			if !policy.IsActive {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Allocation policy is not active"})
+				return
+			}
+			update = bson.D{{Key: "$set", Value: bson.D{{Key: "allocation_policy_id", Value: req.PolicyID}}}}
+		}
+
+		employeeCollection := database.OpenCollection("employees", client)
+		result, err := employeeCollection.UpdateOne(ctx, bson.D{{Key: "employee_id", Value: employeeID}}, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign allocation policy"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Allocation policy assignment updated successfully"})
+	}
+}
+
+// PreviewAllocation - Admin previews what each employee would receive for a month (?month=YYYY-MM, defaults to next month)
+func PreviewAllocation(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		monthStart := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+		if value := c.Query("month"); value != "" {
+			parsed, err := time.ParseInLocation("2006-01", value, now.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid month. Use YYYY-MM"})
+				return
+			}
+			monthStart = parsed
+		}
+		monthEnd := monthStart.AddDate(0, 1, -1)
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+		policies, err := loadAllocationPolicies(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allocation policies"})
+			return
+		}
+		closedDates, err := closedCalendarDates(ctx, client, monthStart, monthEnd)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar"})
+			return
+		}
+
+		employeeCollection := database.OpenCollection("employees", client)
+		cursor, err := employeeCollection.Find(
+			ctx,
+			bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"active", "on_leave"}}}}},
+			options.Find().SetSort(bson.D{{Key: "employee_code", Value: 1}}),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch employees"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var employees []models.Employee
+		if err = cursor.All(ctx, &employees); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode employees"})
+			return
+		}
+
+		totalDays := utils.WorkingDaysBetween(monthStart, monthEnd, settings.WeekendDays, closedDates)
+		rows := []models.AllocationPreviewRow{}
+		totalCoupons := 0
+		for _, employee := range employees {
+			if employee.HireDate.After(monthEnd) {
+				continue
+			}
+
+			rate := resolveAllocationRate(employee, policies, settings)
+			row := models.AllocationPreviewRow{
+				EmployeeID:   employee.EmployeeID,
+				EmployeeCode: employee.EmployeeCode,
+				Name:         employee.Name,
+				Status:       employee.Status,
+				PolicyID:     rate.PolicyID,
+				PolicyName:   rate.PolicyName,
+				AssignedBy:   rate.AssignedBy,
+			}
+			switch {
+			case employee.Status == "on_leave":
+				row.Note = "On leave, allocated pro rata on return"
+			case employee.HireDate.After(monthStart):
+				row.WorkingDays = utils.WorkingDaysBetween(employee.HireDate, monthEnd, settings.WeekendDays, closedDates)
+				row.Coupons = rate.coupons(row.WorkingDays, totalDays)
+				row.Note = "Pro-rated from hire date " + employee.HireDate.Format("2006-01-02")
+			default:
+				row.WorkingDays = totalDays
+				row.Coupons = rate.coupons(totalDays, totalDays)
+			}
+			totalCoupons += row.Coupons
+			rows = append(rows, row)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"month":         monthStart.Format("2006-01"),
+			"working_days":  totalDays,
+			"employees":     rows,
+			"total_coupons": totalCoupons,
+		})
+	}
+}
+
+func findAllocationPolicies(ctx context.Context, client *mongo.Client, filter bson.D) ([]models.AllocationPolicy, error) {
+	policyCollection := database.OpenCollection("allocation_policies", client)
+	cursor, err := policyCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	policies := []models.AllocationPolicy{}
+	if err = cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		if policies[i].Priority != policies[j].Priority {
+			return policies[i].Priority > policies[j].Priority
+		}
+		return policies[i].CreatedAt.Before(policies[j].CreatedAt)
+	})
+	return policies, nil
+}
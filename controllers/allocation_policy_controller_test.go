@@ -0,0 +1,30 @@
+package controllers
+
+import "testing"
+
+func TestAllocationRateCoupons(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        allocationRate
+		workingDays int
+		totalDays   int
+		want        int
+	}{
+		{name: "per working day", rate: allocationRate{CouponsPerWorkingDay: 2}, workingDays: 10, totalDays: 22, want: 20},
+		{name: "per working day, no days", rate: allocationRate{CouponsPerWorkingDay: 2}, workingDays: 0, totalDays: 22, want: 0},
+		{name: "monthly budget, full month", rate: allocationRate{MonthlyAllocation: 20}, workingDays: 22, totalDays: 22, want: 20},
+		{name: "monthly budget, more days than the month", rate: allocationRate{MonthlyAllocation: 20}, workingDays: 23, totalDays: 22, want: 20},
+		{name: "monthly budget, half month", rate: allocationRate{MonthlyAllocation: 20}, workingDays: 11, totalDays: 22, want: 10},
+		{name: "monthly budget rounds to nearest", rate: allocationRate{MonthlyAllocation: 20}, workingDays: 7, totalDays: 22, want: 6},
+		{name: "monthly budget rounds half up", rate: allocationRate{MonthlyAllocation: 11}, workingDays: 1, totalDays: 2, want: 6},
+		{name: "monthly budget wins over per day", rate: allocationRate{MonthlyAllocation: 20, CouponsPerWorkingDay: 3}, workingDays: 11, totalDays: 22, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rate.coupons(tt.workingDays, tt.totalDays); got != tt.want {
+				t.Errorf("coupons(%d, %d) = %d, want %d", tt.workingDays, tt.totalDays, got, tt.want)
+			}
+		})
+	}
+}
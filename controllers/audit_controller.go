@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// recordAudit appends an entry to the audit log.
+// Pass a session context to make the entry part of the change it records.
+func recordAudit(ctx context.Context, client *mongo.Client, actorUserID, action, entityType, entityID string, details bson.M) error {
+	entry := models.AuditLogEntry{
+		AuditID:     bson.NewObjectID().Hex(),
+		ActorUserID: actorUserID,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Details:     details,
+		CreatedAt:   time.Now(),
+	}
+
+	auditCollection := database.OpenCollection("audit_log", client)
+	_, err := auditCollection.InsertOne(ctx, entry)
+	return err
+}
+
+// GetAuditLog - Admin searches the audit log, newest first
+// (?entity_type=, ?entity_id=, ?actor_user_id=, ?action=, ?from=&to=, ?limit= up to 500)
+func GetAuditLog(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.D{}
+		for _, field := range []string{"entity_type", "entity_id", "actor_user_id", "action"} {
+			if value := c.Query(field); value != "" {
+				filter = append(filter, bson.E{Key: field, Value: value})
+			}
+		}
+		createdAt, err := dateRangeQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(createdAt) > 0 {
+			filter = append(filter, bson.E{Key: "created_at", Value: createdAt})
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit < 1 || limit > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit. Use 1 to 500"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		auditCollection := database.OpenCollection("audit_log", client)
+		cursor, err := auditCollection.Find(
+			ctx,
+			filter,
+			options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit)),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		entries := []models.AuditLogEntry{}
+		if err = cursor.All(ctx, &entries); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode audit log"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"entries": entries,
+			"total":   len(entries),
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CreateAutoApprovalRule - Admin creates an organization or employee auto-approval rule
+func CreateAutoApprovalRule(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateAutoApprovalRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		if req.Scope == "" {
+			req.Scope = "organization"
+		}
+		if req.Scope == "employee" && req.EmployeeID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "employee_id is required for employee rules"})
+			return
+		}
+		if req.Scope == "organization" {
+			req.EmployeeID = ""
+		}
+		if err := validateMealWindows(req.MealWindows); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if req.Scope == "employee" {
+			employeeCollection := database.OpenCollection("employees", client)
+			count, err := employeeCollection.CountDocuments(ctx, bson.D{{Key: "employee_id", Value: req.EmployeeID}})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check employee"})
+				return
+			}
+			if count == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+				return
+			}
+		}
+
+		rule := newAutoApprovalRule(req, adminUserID)
+		ruleCollection := database.OpenCollection("auto_approval_rules", client)
+		if _, err = ruleCollection.InsertOne(ctx, rule); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Auto-approval rule created successfully",
+			"rule":    rule,
+		})
+	}
+}
+
+// GetAutoApprovalRules - Admin lists auto-approval rules
+func GetAutoApprovalRules(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		filter := bson.D{}
+		if scope := c.Query("scope"); scope != "" {
+			filter = append(filter, bson.E{Key: "scope", Value: scope})
+		}
+		if employeeID := c.Query("employee_id"); employeeID != "" {
+			filter = append(filter, bson.E{Key: "employee_id", Value: employeeID})
+		}
+
+		rules, err := findAutoApprovalRules(ctx, client, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rules": rules,
+			"total": len(rules),
+		})
+	}
+}
+
+// UpdateAutoApprovalRule - Admin updates or disables a rule, or signs off an employee rule by activating it
+func UpdateAutoApprovalRule(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ruleID := c.Param("id")
+
+		var req models.UpdateAutoApprovalRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		updateData := bson.M{"updated_at": time.Now()}
+		if req.Name != "" {
+			updateData["name"] = req.Name
+		}
+		if req.MaxCoupons > 0 {
+			updateData["max_coupons"] = req.MaxCoupons
+		}
+		if req.SupplierIDs != nil {
+			updateData["supplier_ids"] = *req.SupplierIDs
+		}
+		if req.MealWindows != nil {
+			if err := validateMealWindows(*req.MealWindows); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateData["meal_windows"] = *req.MealWindows
+		}
+		if req.IsActive != nil {
+			updateData["is_active"] = *req.IsActive
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		ruleCollection := database.OpenCollection("auto_approval_rules", client)
+		result, err := ruleCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "rule_id", Value: ruleID}},
+			bson.D{{Key: "$set", Value: updateData}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Auto-approval rule updated successfully",
+			"updated": result.ModifiedCount,
+		})
+	}
+}
+
+// DeleteAutoApprovalRule - Admin removes a rule
+func DeleteAutoApprovalRule(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		ruleCollection := database.OpenCollection("auto_approval_rules", client)
+		result, err := ruleCollection.DeleteOne(ctx, bson.D{{Key: "rule_id", Value: c.Param("id")}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Auto-approval rule deleted successfully"})
+	}
+}
+
+// CreateMyAutoApprovalRule - Employee opts in to auto-approval for their own transactions
+func CreateMyAutoApprovalRule(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateAutoApprovalRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if err := validateMealWindows(req.MealWindows); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		// Employees may not auto-approve more coupons than the organization rules allow
+		limit, err := organizationAutoApprovalLimit(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+			return
+		}
+
+		req.Scope = "employee"
+		req.EmployeeID = employee.EmployeeID
+		rule := newAutoApprovalRule(req, userID)
+		// Above the limit the rule stays inactive until an admin activates it
+		needsSignOff := rule.MaxCoupons > limit
+		if needsSignOff {
+			rule.IsActive = false
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		ruleCollection := database.OpenCollection("auto_approval_rules", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			if _, err := ruleCollection.InsertOne(sessCtx, rule); err != nil {
+				return nil, err
+			}
+			if !needsSignOff {
+				return nil, nil
+			}
+
+			message := fmt.Sprintf("%s (%s) asked to auto-approve up to %d coupon(s) per transaction, above the organization limit of %d. Activate the rule to allow it.", employee.Name, employee.EmployeeCode, rule.MaxCoupons, limit)
+			return nil, notifyAdmins(sessCtx, client, "auto_approval_rule_pending", "Auto-approval rule awaiting sign-off", message, rule.RuleID)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+			return
+		}
+
+		if needsSignOff {
+			c.JSON(http.StatusAccepted, gin.H{
+				"message":            "Auto-approval rule is waiting for an admin to activate it",
+				"rule":               rule,
+				"organization_limit": limit,
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Auto-approval rule created successfully",
+			"rule":    rule,
+		})
+	}
+}
+
+// GetMyAutoApprovalRules - Employee lists the rules that apply to them
+func GetMyAutoApprovalRules(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		rules, err := findAutoApprovalRules(ctx, client, autoApprovalRuleFilter(employee.EmployeeID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rules": rules,
+			"total": len(rules),
+		})
+	}
+}
+
+// DeleteMyAutoApprovalRule - Employee removes one of their own rules
+func DeleteMyAutoApprovalRule(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		ruleCollection := database.OpenCollection("auto_approval_rules", client)
+		result, err := ruleCollection.DeleteOne(ctx, bson.D{
+			{Key: "rule_id", Value: c.Param("id")},
+			{Key: "scope", Value: "employee"},
+			{Key: "employee_id", Value: employee.EmployeeID},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Auto-approval rule deleted successfully"})
+	}
+}
+
+// matchAutoApprovalRule returns the first active rule that covers the transaction, or nil.
+func matchAutoApprovalRule(ctx context.Context, client *mongo.Client, employeeID, supplierID string, couponsUsed int, at time.Time) (*models.AutoApprovalRule, error) {
+	filter := append(autoApprovalRuleFilter(employeeID), bson.E{Key: "is_active", Value: true})
+	rules, err := findAutoApprovalRules(ctx, client, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		if autoApprovalRuleApplies(rules[i], supplierID, couponsUsed, at) {
+			return &rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func autoApprovalRuleApplies(rule models.AutoApprovalRule, supplierID string, couponsUsed int, at time.Time) bool {
+	if couponsUsed > rule.MaxCoupons {
+		return false
+	}
+
+	if len(rule.SupplierIDs) > 0 {
+		whitelisted := false
+		for _, id := range rule.SupplierIDs {
+			if id == supplierID {
+				whitelisted = true
+				break
+			}
+		}
+		if !whitelisted {
+			return false
+		}
+	}
+
+	if len(rule.MealWindows) > 0 {
+		for _, window := range rule.MealWindows {
+			if utils.WithinTimeWindow(window.Start, window.End, at) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// organizationAutoApprovalLimit returns the highest coupon cap of the active organization rules,
+// 0 when there is none
+func organizationAutoApprovalLimit(ctx context.Context, client *mongo.Client) (int, error) {
+	rules, err := findAutoApprovalRules(ctx, client, bson.D{
+		{Key: "scope", Value: "organization"},
+		{Key: "is_active", Value: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	limit := 0
+	for _, rule := range rules {
+		if rule.MaxCoupons > limit {
+			limit = rule.MaxCoupons
+		}
+	}
+	return limit, nil
+}
+
+// autoApprovalRuleFilter selects organization rules plus the employee's own rules
+func autoApprovalRuleFilter(employeeID string) bson.D {
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "scope", Value: "organization"}},
+		bson.D{
+			{Key: "scope", Value: "employee"},
+			{Key: "employee_id", Value: employeeID},
+		},
+	}}}
+}
+
+func findAutoApprovalRules(ctx context.Context, client *mongo.Client, filter bson.D) ([]models.AutoApprovalRule, error) {
+	ruleCollection := database.OpenCollection("auto_approval_rules", client)
+	cursor, err := ruleCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rules []models.AutoApprovalRule
+	if err = cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func newAutoApprovalRule(req models.CreateAutoApprovalRuleRequest, createdBy string) models.AutoApprovalRule {
+	now := time.Now()
+	return models.AutoApprovalRule{
+		RuleID:          bson.NewObjectID().Hex(),
+		Name:            req.Name,
+		Scope:           req.Scope,
+		EmployeeID:      req.EmployeeID,
+		MaxCoupons:      req.MaxCoupons,
+		SupplierIDs:     req.SupplierIDs,
+		MealWindows:     req.MealWindows,
+		IsActive:        true,
+		CreatedByUserID: createdBy,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+}
+
+func validateMealWindows(windows []models.MealWindow) error {
+	for _, window := range windows {
+		if _, err := utils.ParseClockTime(window.Start); err != nil {
+			return err
+		}
+		if _, err := utils.ParseClockTime(window.End); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCreateMyAutoApprovalRule(t *testing.T) {
+	// A rule without a coupon cap is refused while binding, before the database is used
+	w := serve(t, CreateMyAutoApprovalRule(nil), http.MethodPost, "/auto-approval-rules", "/auto-approval-rules", "user", "EMPLOYEE",
+		json.RawMessage(`{"name": "Lunch"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("no max_coupons: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	client := testClient(t)
+	userID := bson.NewObjectID().Hex()
+	employeeID := bson.NewObjectID().Hex()
+	insertDocuments(t, client, "users", models.User{UserID: "admin", Email: "admin@example.com", Role: "ADMIN"})
+	insertDocuments(t, client, "employees", models.Employee{
+		EmployeeID:   employeeID,
+		UserID:       userID,
+		EmployeeCode: "E-" + employeeID,
+		Status:       "active",
+	})
+	// Only active organization rules set the limit
+	insertDocuments(t, client, "auto_approval_rules",
+		models.AutoApprovalRule{RuleID: bson.NewObjectID().Hex(), Scope: "organization", MaxCoupons: 1, IsActive: true},
+		models.AutoApprovalRule{RuleID: bson.NewObjectID().Hex(), Scope: "organization", MaxCoupons: 3},
+	)
+
+	tests := []struct {
+		name       string
+		maxCoupons int
+		wantStatus int
+		wantActive bool
+	}{
+		{name: "within the organization limit", maxCoupons: 1, wantStatus: http.StatusCreated, wantActive: true},
+		{name: "above the organization limit", maxCoupons: 2, wantStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, CreateMyAutoApprovalRule(client), http.MethodPost, "/auto-approval-rules", "/auto-approval-rules", userID, "EMPLOYEE",
+				gin.H{"name": tt.name, "max_coupons": tt.maxCoupons})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			var rule models.AutoApprovalRule
+			findDocument(t, client, "auto_approval_rules", bson.D{{Key: "employee_id", Value: employeeID}, {Key: "name", Value: tt.name}}, &rule)
+			if rule.IsActive != tt.wantActive {
+				t.Errorf("is_active = %v, want %v", rule.IsActive, tt.wantActive)
+			}
+			signOffs := countDocuments(t, client, "notifications", bson.D{{Key: "reference_id", Value: rule.RuleID}})
+			if (signOffs > 0) == tt.wantActive {
+				t.Errorf("%d sign-off notification(s) for an active rule %v", signOffs, tt.wantActive)
+			}
+		})
+	}
+}
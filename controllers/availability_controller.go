@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// UpdateServiceSlots - Supplier declares opening windows and hourly capacity
+func UpdateServiceSlots(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateServiceSlotsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		for _, slot := range req.ServiceSlots {
+			if err := validateMealWindows([]models.MealWindow{{Start: slot.Start, End: slot.End}}); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		supplierCollection := database.OpenCollection("suppliers", client)
+		_, err = supplierCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "supplier_id", Value: supplier.SupplierID}},
+			bson.D{{Key: "$set", Value: bson.M{
+				"service_slots": req.ServiceSlots,
+				"updated_at":    time.Now(),
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service slots"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":       "Service slots updated successfully",
+			"service_slots": req.ServiceSlots,
+		})
+	}
+}
+
+// GetSupplierAvailability - Open state, load and estimated wait of suppliers near the caller
+func GetSupplierAvailability(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		latitude, _ := strconv.ParseFloat(c.Query("latitude"), 64)
+		longitude, _ := strconv.ParseFloat(c.Query("longitude"), 64)
+		radius := 3000
+		if radiusStr := c.Query("radius"); radiusStr != "" {
+			if r, err := strconv.Atoi(radiusStr); err == nil && r > 0 {
+				radius = r
+			}
+		}
+		hasLocation := latitude != 0 && longitude != 0
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplierCollection := database.OpenCollection("suppliers", client)
+		cursor, err := supplierCollection.Find(ctx, bson.D{
+			{Key: "is_active", Value: true},
+			{Key: "is_verified", Value: true},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suppliers"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var suppliers []models.Supplier
+		if err = cursor.All(ctx, &suppliers); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode suppliers"})
+			return
+		}
+
+		now := time.Now()
+		windowMinutes := utils.GetEnvAsInt("THROUGHPUT_WINDOW_MINUTES", 15)
+		recent, err := countRecentTransactions(ctx, client, now.Add(-time.Duration(windowMinutes)*time.Minute))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to measure throughput"})
+			return
+		}
+
+		var availability []models.SupplierAvailabilityResponse
+		for _, supplier := range suppliers {
+			entry := supplierAvailability(supplier, recent[supplier.SupplierID], windowMinutes, now)
+
+			if hasLocation {
+				distance := utils.CalculateDistance(latitude, longitude, supplier.Latitude, supplier.Longitude)
+				if distance > float64(radius) {
+					continue
+				}
+				entry.DistanceMeters = int(distance)
+			}
+			availability = append(availability, entry)
+		}
+
+		// Open suppliers first, then shortest wait, then nearest
+		sort.SliceStable(availability, func(i, j int) bool {
+			a, b := availability[i], availability[j]
+			if a.IsOpen != b.IsOpen {
+				return a.IsOpen
+			}
+			if a.EstimatedWaitMinutes != b.EstimatedWaitMinutes {
+				return a.EstimatedWaitMinutes < b.EstimatedWaitMinutes
+			}
+			return a.DistanceMeters < b.DistanceMeters
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"suppliers":                 availability,
+			"total":                     len(availability),
+			"throughput_window_minutes": windowMinutes,
+			"generated_at":              now,
+		})
+	}
+}
+
+func supplierAvailability(supplier models.Supplier, recentTransactions, windowMinutes int, now time.Time) models.SupplierAvailabilityResponse {
+	entry := models.SupplierAvailabilityResponse{
+		SupplierID:         supplier.SupplierID,
+		BusinessName:       supplier.BusinessName,
+		Address:            supplier.Address,
+		RecentTransactions: recentTransactions,
+		DemandPerHour:      float64(recentTransactions) * 60 / float64(windowMinutes),
+	}
+
+	// Without declared slots the supplier counts as open with unknown capacity
+	if len(supplier.ServiceSlots) == 0 {
+		entry.IsOpen = true
+		entry.LoadLevel = "unknown"
+		return entry
+	}
+
+	for _, slot := range supplier.ServiceSlots {
+		if utils.WithinTimeWindow(slot.Start, slot.End, now) {
+			entry.IsOpen = true
+			entry.OpenUntil = slot.End
+			entry.CapacityPerHour = slot.MealsPerHour
+			break
+		}
+	}
+	if !entry.IsOpen {
+		entry.LoadLevel = "closed"
+		return entry
+	}
+
+	maxWait := utils.GetEnvAsInt("MAX_ESTIMATED_WAIT_MINUTES", 60)
+	entry.EstimatedWaitMinutes = utils.EstimateWaitMinutes(entry.DemandPerHour, float64(entry.CapacityPerHour), maxWait)
+
+	utilization := entry.DemandPerHour / float64(entry.CapacityPerHour)
+	switch {
+	case utilization < 0.5:
+		entry.LoadLevel = "low"
+	case utilization < 0.85:
+		entry.LoadLevel = "moderate"
+	default:
+		entry.LoadLevel = "high"
+	}
+	return entry
+}
+
+// countRecentTransactions counts transactions initiated since the given time, per supplier
+func countRecentTransactions(ctx context.Context, client *mongo.Client, since time.Time) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$supplier_id"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	transactionCollection := database.OpenCollection("transactions", client)
+	cursor, err := transactionCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		SupplierID string `bson:"_id"`
+		Count      int    `bson:"count"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.SupplierID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// couponBalanceChange is one signed change across the balance buckets of an employee
+type couponBalanceChange struct {
+	Company int // spendable, company-funded
+	Paid    int // spendable, bought through top-ups
+	Held    int // reserved for pre-orders
+
+	// Lots narrows which lots the company coupons come from or go to. Debits draw from the
+	// listed lots instead of first-expiring-first; credits restore draws that carry a lot ID
+	// and issue new lots for the rest. A credit without lots issues one lot that never expires.
+	Lots []models.LotDraw
+}
+
+// applyBalanceChange applies a change to the employee's balance buckets, moves the matching coupon lots
+// and records it in the balance history. It returns the lots the company coupons came from or went to.
+// Every bucket that decreases must cover the decrease, otherwise errInsufficientBalance is returned.
+// Pass a session context so the change commits or rolls back with the rest of the operation.
+func applyBalanceChange(ctx context.Context, client *mongo.Client, employeeID string, change couponBalanceChange, entryType, referenceID, description string) (models.Employee, []models.LotDraw, error) {
+	filter := bson.D{{Key: "employee_id", Value: employeeID}}
+	inc := bson.D{}
+	for _, bucket := range []struct {
+		field string
+		delta int
+	}{
+		{"current_coupon_balance", change.Company},
+		{"paid_coupon_balance", change.Paid},
+		{"held_coupon_balance", change.Held},
+	} {
+		if bucket.delta == 0 {
+			continue
+		}
+		if bucket.delta < 0 {
+			filter = append(filter, bson.E{Key: bucket.field, Value: bson.D{{Key: "$gte", Value: -bucket.delta}}})
+		}
+		inc = append(inc, bson.E{Key: bucket.field, Value: bucket.delta})
+	}
+
+	// An empty change only records an event, such as a freeze, against the current balance
+	employeeCollection := database.OpenCollection("employees", client)
+	var employee models.Employee
+	var err error
+	if len(inc) == 0 {
+		err = employeeCollection.FindOne(ctx, filter).Decode(&employee)
+	} else {
+		err = employeeCollection.FindOneAndUpdate(
+			ctx,
+			filter,
+			bson.D{{Key: "$inc", Value: inc}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&employee)
+	}
+	if err == mongo.ErrNoDocuments && len(filter) > 1 {
+		return employee, nil, errInsufficientBalance
+	}
+	if err != nil {
+		return employee, nil, err
+	}
+
+	now := time.Now()
+	var lots []models.LotDraw
+	switch {
+	case change.Company < 0:
+		lots, err = drawCouponLots(ctx, client, employeeID, -change.Company, change.Lots, now)
+	case change.Company > 0:
+		lots, err = creditCouponLots(ctx, client, employeeID, change.Company, change.Lots, entryType, now)
+	}
+	if err != nil {
+		return employee, nil, err
+	}
+
+	entry := models.BalanceHistoryEntry{
+		EntryID:          bson.NewObjectID().Hex(),
+		EmployeeID:       employeeID,
+		Type:             entryType,
+		Change:           change.Company,
+		BalanceAfter:     employee.CurrentBalance,
+		PaidChange:       change.Paid,
+		PaidBalanceAfter: employee.PaidBalance,
+		Lots:             lots,
+		ReferenceID:      referenceID,
+		Description:      description,
+		CreatedAt:        now,
+	}
+	historyCollection := database.OpenCollection("balance_history", client)
+	if _, err = historyCollection.InsertOne(ctx, entry); err != nil {
+		return employee, nil, err
+	}
+	return employee, lots, nil
+}
+
+// adjustCouponBalance changes the company-funded balance, optionally moving coupons to or from the held bucket
+func adjustCouponBalance(ctx context.Context, client *mongo.Client, employeeID string, change, heldChange int, entryType, referenceID, description string) (int, error) {
+	employee, _, err := applyBalanceChange(ctx, client, employeeID, couponBalanceChange{Company: change, Held: heldChange}, entryType, referenceID, description)
+	return employee.CurrentBalance, err
+}
+
+// spendCoupons takes coupons from the company-funded balance first and the paid balance after it.
+// It returns how many paid coupons were used.
+func spendCoupons(ctx context.Context, client *mongo.Client, employeeID string, coupons int, referenceID, description string) (int, error) {
+	employeeCollection := database.OpenCollection("employees", client)
+	var employee models.Employee
+	if err := employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: employeeID}}).Decode(&employee); err != nil {
+		return 0, err
+	}
+
+	company := min(coupons, max(employee.CurrentBalance, 0))
+	paid := coupons - company
+	_, _, err := applyBalanceChange(ctx, client, employeeID, couponBalanceChange{Company: -company, Paid: -paid}, "meal", referenceID, description)
+	return paid, err
+}
+
+// spendableCoupons is what the employee can charge right now, company-funded and paid together
+func spendableCoupons(employee models.Employee) int {
+	return employee.CurrentBalance + employee.PaidBalance
+}
+
+// GetMyBalanceHistory - Employee lists the changes to their coupon balance, newest first
+func GetMyBalanceHistory(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employee, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		respondBalanceHistory(c, ctx, client, employee)
+	}
+}
+
+// GetEmployeeBalanceHistory - Admin views the balance history of an employee
+func GetEmployeeBalanceHistory(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		employeeID := c.Param("id")
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err := employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: employeeID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		respondBalanceHistory(c, ctx, client, employee)
+	}
+}
+
+// respondBalanceHistory writes the history of one employee, optionally narrowed by ?type=
+func respondBalanceHistory(c *gin.Context, ctx context.Context, client *mongo.Client, employee models.Employee) {
+	filter := bson.D{{Key: "employee_id", Value: employee.EmployeeID}}
+	if entryType := c.Query("type"); entryType != "" {
+		filter = append(filter, bson.E{Key: "type", Value: entryType})
+	}
+
+	historyCollection := database.OpenCollection("balance_history", client)
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(200)
+	cursor, err := historyCollection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch balance history"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.BalanceHistoryEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode balance history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"employee_id":     employee.EmployeeID,
+		"current_balance": employee.CurrentBalance,
+		"paid_balance":    employee.PaidBalance,
+		"history":         entries,
+		"total":           len(entries),
+	})
+}
+
+// findEmployeeForUser loads the employee profile of the calling user.
+// It writes the error response itself, so callers just return on error.
+func findEmployeeForUser(c *gin.Context, ctx context.Context, client *mongo.Client) (models.Employee, error) {
+	var employee models.Employee
+
+	userID, err := utils.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return employee, err
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	err = employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&employee)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+		return employee, err
+	}
+	return employee, nil
+}
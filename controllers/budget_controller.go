@@ -0,0 +1,462 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// parseBudgetPeriod turns a YYYY-MM month or YYYY-Qn quarter into its start and exclusive end
+func parseBudgetPeriod(period string) (time.Time, time.Time, error) {
+	if year, quarter, found := strings.Cut(period, "-Q"); found {
+		y, err := strconv.Atoi(year)
+		q, qErr := strconv.Atoi(quarter)
+		if err != nil || qErr != nil || q < 1 || q > 4 {
+			return time.Time{}, time.Time{}, errors.New("Invalid period. Use YYYY-MM or YYYY-Qn")
+		}
+		start := time.Date(y, time.Month(3*(q-1)+1), 1, 0, 0, 0, 0, time.Local)
+		return start, start.AddDate(0, 3, 0), nil
+	}
+
+	start, err := time.ParseInLocation("2006-01", period, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("Invalid period. Use YYYY-MM or YYYY-Qn")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// recordBudgetSpend adds a settled meal's company-funded amount to the cost center's budget covering
+// the settlement time and alerts the admins when a threshold is crossed. Cost centers without a budget are ignored.
+func recordBudgetSpend(ctx context.Context, client *mongo.Client, costCenter string, amount float64, at time.Time) error {
+	if costCenter == "" || amount <= 0 {
+		return nil
+	}
+
+	budgetCollection := database.OpenCollection("meal_budgets", client)
+	var budget models.MealBudget
+	err := budgetCollection.FindOneAndUpdate(
+		ctx,
+		bson.D{
+			{Key: "cost_center", Value: costCenter},
+			{Key: "period_start", Value: bson.D{{Key: "$lte", Value: at}}},
+			{Key: "period_end", Value: bson.D{{Key: "$gt", Value: at}}},
+		},
+		bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "spent", Value: amount}}},
+			{Key: "$set", Value: bson.D{{Key: "updated_at", Value: at}}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&budget)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkBudgetThresholds(ctx, client, budget, at)
+}
+
+// checkBudgetThresholds alerts the admins the first time the budget's spend reaches each threshold.
+// Thresholds the spend has dropped below again, after the budget was raised, are re-armed.
+func checkBudgetThresholds(ctx context.Context, client *mongo.Client, budget models.MealBudget, now time.Time) error {
+	budgetCollection := database.OpenCollection("meal_budgets", client)
+	percentUsed := budgetPercentUsed(budget)
+
+	thresholds := []struct {
+		field     string
+		percent   int
+		alertedAt *time.Time
+		label     string
+	}{
+		{"hard_alerted_at", budget.HardThresholdPercent, budget.HardAlertedAt, "hard"},
+		{"soft_alerted_at", budget.SoftThresholdPercent, budget.SoftAlertedAt, "soft"},
+	}
+
+	alerted := false
+	for _, threshold := range thresholds {
+		if percentUsed < float64(threshold.percent) {
+			if threshold.alertedAt != nil {
+				_, err := budgetCollection.UpdateOne(
+					ctx,
+					bson.D{{Key: "budget_id", Value: budget.BudgetID}},
+					bson.D{{Key: "$unset", Value: bson.D{{Key: threshold.field, Value: ""}}}},
+				)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if threshold.alertedAt != nil {
+			continue
+		}
+
+		// Claim the alert so concurrent settlements send it once
+		result, err := budgetCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "budget_id", Value: budget.BudgetID},
+				{Key: threshold.field, Value: nil},
+			},
+			bson.D{{Key: "$set", Value: bson.D{{Key: threshold.field, Value: now}}}},
+		)
+		if err != nil {
+			return err
+		}
+		// Crossing both at once sends only the hard alert
+		if result.ModifiedCount == 0 || alerted {
+			continue
+		}
+		alerted = true
+
+		title := "Meal budget threshold reached"
+		message := fmt.Sprintf("Cost center %s has used %.0f%% of its %s meal budget, passing the %d%% %s threshold.",
+			budget.CostCenter, percentUsed, budget.Period, threshold.percent, threshold.label)
+		if threshold.label == "hard" {
+			title = "Meal budget exceeded"
+			message += " Allocations and top-ups for its employees are blocked until the budget is raised."
+		}
+		if err := notifyAdmins(ctx, client, "budget_"+threshold.label+"_threshold", title, message, budget.BudgetID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockingBudget returns the budget of the employee's cost center covering the given time
+// when it has reached its hard threshold, or nil when nothing blocks the employee
+func blockingBudget(ctx context.Context, client *mongo.Client, employee models.Employee, at time.Time) (*models.MealBudget, error) {
+	costCenter, err := resolveCostCenter(ctx, client, employee.DepartmentID)
+	if err != nil || costCenter == "" {
+		return nil, err
+	}
+
+	budgetCollection := database.OpenCollection("meal_budgets", client)
+	var budget models.MealBudget
+	err = budgetCollection.FindOne(ctx, bson.D{
+		{Key: "cost_center", Value: costCenter},
+		{Key: "period_start", Value: bson.D{{Key: "$lte", Value: at}}},
+		{Key: "period_end", Value: bson.D{{Key: "$gt", Value: at}}},
+	}).Decode(&budget)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if budgetPercentUsed(budget) < float64(budget.HardThresholdPercent) {
+		return nil, nil
+	}
+	return &budget, nil
+}
+
+func budgetPercentUsed(budget models.MealBudget) float64 {
+	if budget.Amount <= 0 {
+		return 0
+	}
+	return budget.Spent / budget.Amount * 100
+}
+
+// costCenterSpend sums the company-funded amount of completed meals charged to the cost center in a period
+func costCenterSpend(ctx context.Context, client *mongo.Client, costCenter string, start, end time.Time) (float64, error) {
+	employeeFunded := bson.D{{Key: "$ifNull", Value: bson.A{"$employee_funded_amount", 0}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "status", Value: "completed"},
+			{Key: "cost_center", Value: costCenter},
+			{Key: "processed_at", Value: bson.D{{Key: "$gte", Value: start}, {Key: "$lt", Value: end}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "spent", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{"$total_amount", employeeFunded}}}}}},
+		}}},
+	}
+
+	transactionCollection := database.OpenCollection("transactions", client)
+	cursor, err := transactionCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []struct {
+		Spent float64 `bson:"spent"`
+	}
+	if err = cursor.All(ctx, &totals); err != nil || len(totals) == 0 {
+		return 0, err
+	}
+	return totals[0].Spent, nil
+}
+
+// CreateMealBudget - Admin sets a cost center's meal budget for a month or quarter.
+// Meals already completed in the period count against it straight away.
+func CreateMealBudget(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateMealBudgetRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		periodStart, periodEnd, err := parseBudgetPeriod(req.Period)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.SoftThresholdPercent == 0 {
+			req.SoftThresholdPercent = 80
+		}
+		if req.HardThresholdPercent == 0 {
+			req.HardThresholdPercent = 100
+		}
+		if req.SoftThresholdPercent > req.HardThresholdPercent {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The soft threshold cannot be above the hard threshold"})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		// Budgets of one cost center must not overlap, or a meal would count against two of them
+		budgetCollection := database.OpenCollection("meal_budgets", client)
+		count, err := budgetCollection.CountDocuments(ctx, bson.D{
+			{Key: "cost_center", Value: req.CostCenter},
+			{Key: "period_start", Value: bson.D{{Key: "$lt", Value: periodEnd}}},
+			{Key: "period_end", Value: bson.D{{Key: "$gt", Value: periodStart}}},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing budgets"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The cost center already has a budget overlapping this period"})
+			return
+		}
+
+		spent, err := costCenterSpend(ctx, client, req.CostCenter, periodStart, periodEnd)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate spend so far"})
+			return
+		}
+
+		now := time.Now()
+		budget := models.MealBudget{
+			BudgetID:             bson.NewObjectID().Hex(),
+			CostCenter:           req.CostCenter,
+			Period:               req.Period,
+			PeriodStart:          periodStart,
+			PeriodEnd:            periodEnd,
+			Amount:               req.Amount,
+			Spent:                spent,
+			SoftThresholdPercent: req.SoftThresholdPercent,
+			HardThresholdPercent: req.HardThresholdPercent,
+			CreatedByUserID:      adminUserID,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		}
+		if _, err := budgetCollection.InsertOne(ctx, budget); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create budget"})
+			return
+		}
+		if err := checkBudgetThresholds(ctx, client, budget, now); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Budget created but threshold alerts failed", "budget_id": budget.BudgetID})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":   "Budget created successfully",
+			"budget_id": budget.BudgetID,
+			"spent":     spent,
+		})
+	}
+}
+
+// GetMealBudgets - Admin lists budgets (?cost_center=, ?period=)
+func GetMealBudgets(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		budgets, err := findMealBudgets(ctx, client, mealBudgetFilter(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"budgets": budgets,
+			"total":   len(budgets),
+		})
+	}
+}
+
+// UpdateMealBudget - Admin changes a budget's amount or thresholds. Raising the budget lifts a block.
+func UpdateMealBudget(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budgetID := c.Param("id")
+
+		var req models.UpdateMealBudgetRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		budgetCollection := database.OpenCollection("meal_budgets", client)
+		var budget models.MealBudget
+		err := budgetCollection.FindOne(ctx, bson.D{{Key: "budget_id", Value: budgetID}}).Decode(&budget)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+			return
+		}
+
+		if req.Amount != nil {
+			budget.Amount = *req.Amount
+		}
+		if req.SoftThresholdPercent != nil {
+			budget.SoftThresholdPercent = *req.SoftThresholdPercent
+		}
+		if req.HardThresholdPercent != nil {
+			budget.HardThresholdPercent = *req.HardThresholdPercent
+		}
+		if budget.SoftThresholdPercent > budget.HardThresholdPercent {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The soft threshold cannot be above the hard threshold"})
+			return
+		}
+
+		now := time.Now()
+		_, err = budgetCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "budget_id", Value: budgetID}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "amount", Value: budget.Amount},
+				{Key: "soft_threshold_percent", Value: budget.SoftThresholdPercent},
+				{Key: "hard_threshold_percent", Value: budget.HardThresholdPercent},
+				{Key: "updated_at", Value: now},
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update budget"})
+			return
+		}
+		if err := checkBudgetThresholds(ctx, client, budget, now); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Budget updated but threshold alerts failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Budget updated successfully"})
+	}
+}
+
+// GetBudgetVsActual - Admin compares budgets with spend so far and a forecast of the end-of-period spend
+// (?cost_center=, ?period=). The forecast extends the average spend per working day so far to the whole period.
+func GetBudgetVsActual(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		budgets, err := findMealBudgets(ctx, client, mealBudgetFilter(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
+			return
+		}
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		now := time.Now()
+		rows := []models.BudgetVsActual{}
+		for _, budget := range budgets {
+			lastDay := budget.PeriodEnd.AddDate(0, 0, -1)
+			closedDates, err := closedCalendarDates(ctx, client, budget.PeriodStart, lastDay)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar"})
+				return
+			}
+
+			row := models.BudgetVsActual{
+				MealBudget:  budget,
+				Remaining:   budget.Amount - budget.Spent,
+				PercentUsed: budgetPercentUsed(budget),
+				WorkingDays: utils.WorkingDaysBetween(budget.PeriodStart, lastDay, settings.WeekendDays, closedDates),
+				Status:      "ok",
+			}
+			switch {
+			case !now.Before(budget.PeriodEnd):
+				row.WorkingDaysElapsed = row.WorkingDays
+			case !now.Before(budget.PeriodStart):
+				row.WorkingDaysElapsed = utils.WorkingDaysBetween(budget.PeriodStart, now, settings.WeekendDays, closedDates)
+			}
+
+			row.ForecastSpend = budget.Spent
+			if row.WorkingDaysElapsed > 0 && row.WorkingDaysElapsed < row.WorkingDays {
+				row.ForecastSpend = budget.Spent / float64(row.WorkingDaysElapsed) * float64(row.WorkingDays)
+			}
+			if budget.Amount > 0 {
+				row.ForecastPercentUsed = row.ForecastSpend / budget.Amount * 100
+			}
+
+			if row.PercentUsed >= float64(budget.HardThresholdPercent) {
+				row.Status = "hard_exceeded"
+			} else if row.PercentUsed >= float64(budget.SoftThresholdPercent) {
+				row.Status = "soft_exceeded"
+			}
+			rows = append(rows, row)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"budgets": rows,
+			"total":   len(rows),
+		})
+	}
+}
+
+func mealBudgetFilter(c *gin.Context) bson.D {
+	filter := bson.D{}
+	if costCenter := c.Query("cost_center"); costCenter != "" {
+		filter = append(filter, bson.E{Key: "cost_center", Value: costCenter})
+	}
+	if period := c.Query("period"); period != "" {
+		filter = append(filter, bson.E{Key: "period", Value: period})
+	}
+	return filter
+}
+
+func findMealBudgets(ctx context.Context, client *mongo.Client, filter bson.D) ([]models.MealBudget, error) {
+	budgetCollection := database.OpenCollection("meal_budgets", client)
+	cursor, err := budgetCollection.Find(ctx, filter, options.Find().SetSort(bson.D{
+		{Key: "period_start", Value: -1},
+		{Key: "cost_center", Value: 1},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	budgets := []models.MealBudget{}
+	if err = cursor.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
@@ -0,0 +1,439 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// closedCalendarDates returns the public holidays and site closures between two dates, both inclusive, keyed by YYYY-MM-DD
+func closedCalendarDates(ctx context.Context, client *mongo.Client, from, to time.Time) (map[string]bool, error) {
+	days, err := findCalendarDays(ctx, client, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	closed := make(map[string]bool, len(days))
+	for _, day := range days {
+		closed[day.Date] = true
+	}
+	return closed, nil
+}
+
+// programClosedOn reports whether the canteen program is closed on the date of t and gives the reason
+func programClosedOn(ctx context.Context, client *mongo.Client, t time.Time) (bool, string, error) {
+	settings, err := loadOrganizationSettings(ctx, client)
+	if err != nil {
+		return false, "", err
+	}
+	if utils.IsWeekendDay(t, settings.WeekendDays) {
+		return true, t.Weekday().String() + " is not a working day", nil
+	}
+
+	calendarCollection := database.OpenCollection("calendar_days", client)
+	var day models.CalendarDay
+	err = calendarCollection.FindOne(ctx, bson.D{{Key: "date", Value: t.Format("2006-01-02")}}).Decode(&day)
+	if err == mongo.ErrNoDocuments {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, day.Name, nil
+}
+
+// checkProgramOpen writes a 403 response and returns false when the canteen program is closed on the date of t
+func checkProgramOpen(c *gin.Context, ctx context.Context, client *mongo.Client, t time.Time) bool {
+	closed, reason, err := programClosedOn(ctx, client, t)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the organization calendar"})
+		return false
+	}
+	if closed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "The canteen program is closed on " + t.Format("2006-01-02"),
+			"reason": reason,
+		})
+		return false
+	}
+	return true
+}
+
+// GetCalendar - Admin lists the closed dates in a range (?from=YYYY-MM-DD&to=YYYY-MM-DD, defaults to this year)
+func GetCalendar(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		from := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+		to := time.Date(now.Year(), 12, 31, 0, 0, 0, 0, now.Location())
+
+		if value := c.Query("from"); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+				return
+			}
+			from = parsed
+		}
+		if value := c.Query("to"); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+				return
+			}
+			to = parsed
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		days, err := findCalendarDays(ctx, client, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"weekend_days":            settings.WeekendDays,
+			"coupons_per_working_day": settings.CouponsPerWorkingDay,
+			"closed_days":             days,
+		})
+	}
+}
+
+// GetMonthCalendar - Shows the working days and closures of a month (?month=YYYY-MM, defaults to this month)
+func GetMonthCalendar(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		if value := c.Query("month"); value != "" {
+			parsed, err := time.ParseInLocation("2006-01", value, now.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid month. Use YYYY-MM"})
+				return
+			}
+			monthStart = parsed
+		}
+		monthEnd := monthStart.AddDate(0, 1, -1)
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		days, err := findCalendarDays(ctx, client, monthStart, monthEnd)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar"})
+			return
+		}
+		closed := make(map[string]bool, len(days))
+		for _, day := range days {
+			closed[day.Date] = true
+		}
+
+		workingDays := utils.WorkingDaysBetween(monthStart, monthEnd, settings.WeekendDays, closed)
+		c.JSON(http.StatusOK, gin.H{
+			"month":             monthStart.Format("2006-01"),
+			"weekend_days":      settings.WeekendDays,
+			"working_days":      workingDays,
+			"coupon_allocation": workingDays * settings.CouponsPerWorkingDay,
+			"closed_days":       days,
+		})
+	}
+}
+
+// UpdateWorkWeek - Admin sets the weekend days and how many coupons each working day is worth
+func UpdateWorkWeek(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateWorkWeekRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		// Allocations already issued this month are not recalculated
+		settings.WeekendDays = req.WeekendDays
+		settings.CouponsPerWorkingDay = req.CouponsPerWorkingDay
+		settings.UpdatedByUserID = adminUserID
+		settings.UpdatedAt = time.Now()
+
+		if err := saveOrganizationSettings(ctx, client, settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update work week"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":                 "Work week updated successfully",
+			"weekend_days":            settings.WeekendDays,
+			"coupons_per_working_day": settings.CouponsPerWorkingDay,
+		})
+	}
+}
+
+// CreateCalendarDay - Admin marks a date as a public holiday or site closure, replacing any entry for that date
+func CreateCalendarDay(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateCalendarDayRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		day := models.CalendarDay{
+			Date:            req.Date,
+			Type:            req.Type,
+			Name:            req.Name,
+			Source:          "manual",
+			CreatedByUserID: adminUserID,
+		}
+		if err := saveCalendarDay(ctx, client, day, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar day"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Calendar day saved successfully",
+			"date":    day.Date,
+		})
+	}
+}
+
+// DeleteCalendarDay - Admin reopens a date that was marked as closed
+func DeleteCalendarDay(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		date := c.Param("date")
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		calendarCollection := database.OpenCollection("calendar_days", client)
+		result, err := calendarCollection.DeleteOne(ctx, bson.D{{Key: "date", Value: date}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar day"})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar day not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Calendar day deleted successfully"})
+	}
+}
+
+// ImportCalendar - Admin uploads an iCal (.ics) or CSV file of closed dates (?type=public_holiday|site_closure).
+// CSV rows are date,name[,type] with an optional header row. Dates already in the calendar are replaced.
+func ImportCalendar(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the calendar as the file form field"})
+			return
+		}
+
+		defaultType := c.DefaultQuery("type", "public_holiday")
+		if defaultType != "public_holiday" && defaultType != "site_closure" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type. Use public_holiday or site_closure"})
+			return
+		}
+
+		format := strings.ToLower(c.Query("format"))
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+
+		var days []models.CalendarDay
+		var result models.CalendarImportResult
+		switch format {
+		case "ics", "ical":
+			events, err := utils.ParseICalDays(file)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCal file", "details": err.Error()})
+				return
+			}
+			for _, event := range events {
+				days = append(days, models.CalendarDay{Date: event.Date, Type: defaultType, Name: event.Summary, Source: "ical"})
+			}
+		case "csv":
+			days, result.Errors, err = parseCalendarCSV(file, defaultType)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV file", "details": err.Error()})
+				return
+			}
+			result.Skipped = len(result.Errors)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format. Upload an .ics or .csv file"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		for _, day := range days {
+			day.CreatedByUserID = adminUserID
+			if day.Name == "" {
+				day.Name = "Closed"
+			}
+			if err := saveCalendarDay(ctx, client, day, now); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar day", "date": day.Date, "result": result})
+				return
+			}
+			result.Imported++
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// parseCalendarCSV reads date,name[,type] rows. Rows that cannot be used are reported and skipped.
+func parseCalendarCSV(r io.Reader, defaultType string) ([]models.CalendarDay, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var days []models.CalendarDay
+	var rowErrors []string
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		date := strings.TrimSpace(record[0])
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			if line == 1 {
+				continue // header row
+			}
+			rowErrors = append(rowErrors, fmt.Sprintf("line %d: invalid date %q", line, date))
+			continue
+		}
+
+		day := models.CalendarDay{Date: date, Type: defaultType, Source: "csv"}
+		if len(record) > 1 {
+			day.Name = strings.TrimSpace(record[1])
+		}
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			day.Type = strings.TrimSpace(record[2])
+			if day.Type != "public_holiday" && day.Type != "site_closure" {
+				rowErrors = append(rowErrors, fmt.Sprintf("line %d: invalid type %q", line, day.Type))
+				continue
+			}
+		}
+		days = append(days, day)
+	}
+	return days, rowErrors, nil
+}
+
+// saveCalendarDay stores a closed date, replacing the existing entry for the same date
+func saveCalendarDay(ctx context.Context, client *mongo.Client, day models.CalendarDay, now time.Time) error {
+	calendarCollection := database.OpenCollection("calendar_days", client)
+	_, err := calendarCollection.UpdateOne(
+		ctx,
+		bson.D{{Key: "date", Value: day.Date}},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "type", Value: day.Type},
+				{Key: "name", Value: day.Name},
+				{Key: "source", Value: day.Source},
+				{Key: "created_by_user_id", Value: day.CreatedByUserID},
+				{Key: "updated_at", Value: now},
+			}},
+			{Key: "$setOnInsert", Value: bson.D{{Key: "created_at", Value: now}}},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+func findCalendarDays(ctx context.Context, client *mongo.Client, from, to time.Time) ([]models.CalendarDay, error) {
+	calendarCollection := database.OpenCollection("calendar_days", client)
+	cursor, err := calendarCollection.Find(
+		ctx,
+		bson.D{{Key: "date", Value: bson.D{
+			{Key: "$gte", Value: from.Format("2006-01-02")},
+			{Key: "$lte", Value: to.Format("2006-01-02")},
+		}}},
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	days := []models.CalendarDay{}
+	if err = cursor.All(ctx, &days); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var errLotChanged = errors.New("coupon lot changed concurrently")
+
+// drawCouponLots takes coupons out of the employee's lots, from the listed lots when given and
+// first-expiring-first otherwise. Coupons not covered by any lot predate lot tracking and are
+// taken last without a draw.
+func drawCouponLots(ctx context.Context, client *mongo.Client, employeeID string, quantity int, from []models.LotDraw, now time.Time) ([]models.LotDraw, error) {
+	lotCollection := database.OpenCollection("coupon_lots", client)
+
+	if len(from) == 0 {
+		lots, err := findCouponLots(ctx, client, bson.D{
+			{Key: "employee_id", Value: employeeID},
+			{Key: "status", Value: "active"},
+			{Key: "remaining", Value: bson.D{{Key: "$gt", Value: 0}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		sortLotsForSpending(lots)
+
+		for _, lot := range lots {
+			if quantity == 0 {
+				break
+			}
+			take := min(quantity, lot.Remaining)
+			from = append(from, models.LotDraw{LotID: lot.LotID, Quantity: take, ExpiresAt: lot.ExpiresAt})
+			quantity -= take
+		}
+	}
+
+	for _, draw := range from {
+		update := bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "remaining", Value: -draw.Quantity}}},
+			{Key: "$set", Value: bson.D{{Key: "updated_at", Value: now}}},
+		}
+		result, err := lotCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "lot_id", Value: draw.LotID},
+				{Key: "remaining", Value: bson.D{{Key: "$gte", Value: draw.Quantity}}},
+			},
+			update,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, errLotChanged
+		}
+
+		_, err = lotCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "lot_id", Value: draw.LotID},
+				{Key: "remaining", Value: 0},
+				{Key: "status", Value: "active"},
+			},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: "exhausted"}}}},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return from, nil
+}
+
+// creditCouponLots puts coupons into the employee's lots. Draws with a lot ID go back into that lot,
+// the others become new lots with their own expiry, and anything left over becomes a lot that never expires.
+func creditCouponLots(ctx context.Context, client *mongo.Client, employeeID string, quantity int, lots []models.LotDraw, source string, now time.Time) ([]models.LotDraw, error) {
+	lotCollection := database.OpenCollection("coupon_lots", client)
+
+	credited := 0
+	for _, draw := range lots {
+		credited += draw.Quantity
+	}
+	if credited < quantity {
+		lots = append(lots, models.LotDraw{Quantity: quantity - credited})
+	}
+
+	applied := make([]models.LotDraw, 0, len(lots))
+	for _, draw := range lots {
+		if draw.Quantity <= 0 {
+			continue
+		}
+
+		if draw.LotID != "" {
+			_, err := lotCollection.UpdateOne(
+				ctx,
+				bson.D{{Key: "lot_id", Value: draw.LotID}},
+				bson.D{
+					{Key: "$inc", Value: bson.D{{Key: "remaining", Value: draw.Quantity}}},
+					{Key: "$set", Value: bson.D{
+						{Key: "status", Value: "active"},
+						{Key: "updated_at", Value: now},
+					}},
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+			applied = append(applied, draw)
+			continue
+		}
+
+		lot := models.CouponLot{
+			LotID:      bson.NewObjectID().Hex(),
+			EmployeeID: employeeID,
+			Source:     source,
+			Quantity:   draw.Quantity,
+			Remaining:  draw.Quantity,
+			Status:     "active",
+			IssuedAt:   now,
+			ExpiresAt:  draw.ExpiresAt,
+			UpdatedAt:  now,
+		}
+		if _, err := lotCollection.InsertOne(ctx, lot); err != nil {
+			return nil, err
+		}
+		applied = append(applied, models.LotDraw{LotID: lot.LotID, Quantity: lot.Quantity, ExpiresAt: lot.ExpiresAt})
+	}
+	return applied, nil
+}
+
+// ExpireCouponLots forfeits what is left of every lot past its expiry date through the balance
+// ledger and records it in the balance history. A lot the company balance cannot cover means the lot
+// bookkeeping has drifted from the balance. Such a lot is left active and the admins are alerted, so
+// the difference is corrected by an adjustment instead of coming out of lots that have not expired.
+// It returns how many coupons were forfeited and the lots that could not be expired.
+func ExpireCouponLots(ctx context.Context, client *mongo.Client, now time.Time) (int, []models.CouponLot, error) {
+	lots, err := findCouponLots(ctx, client, bson.D{
+		{Key: "status", Value: "active"},
+		{Key: "remaining", Value: bson.D{{Key: "$gt", Value: 0}}},
+		{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}},
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer session.EndSession(ctx)
+
+	lotCollection := database.OpenCollection("coupon_lots", client)
+	forfeited := 0
+	var drifted []models.CouponLot
+	for _, lot := range lots {
+		_, err := session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			description := fmt.Sprintf("%d coupon(s) issued on %s expired", lot.Remaining, lot.IssuedAt.Format("2006-01-02"))
+			draw := []models.LotDraw{{LotID: lot.LotID, Quantity: lot.Remaining, ExpiresAt: lot.ExpiresAt}}
+			_, _, err := applyBalanceChange(sessCtx, client, lot.EmployeeID, couponBalanceChange{Company: -lot.Remaining, Lots: draw}, "expiry", lot.LotID, description)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = lotCollection.UpdateOne(sessCtx, bson.D{{Key: "lot_id", Value: lot.LotID}}, bson.D{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "expired"},
+				{Key: "forfeited_quantity", Value: lot.Remaining},
+				{Key: "expired_at", Value: now},
+				{Key: "updated_at", Value: now},
+			}}})
+			return nil, err
+		})
+		// The lot was spent since it was read; the next run picks up what is left
+		if errors.Is(err, errLotChanged) {
+			continue
+		}
+		if errors.Is(err, errInsufficientBalance) {
+			message := fmt.Sprintf("Coupon lot %s of employee %s has %d coupon(s) left to expire, more than their company balance. Correct the balance with an adjustment; the lot stays active until then.", lot.LotID, lot.EmployeeID, lot.Remaining)
+			if err := notifyAdmins(ctx, client, "coupon_lot_drift", "Coupon lot out of step with balance", message, lot.LotID); err != nil {
+				return forfeited, drifted, err
+			}
+			drifted = append(drifted, lot)
+			continue
+		}
+		if err != nil {
+			return forfeited, drifted, err
+		}
+		forfeited += lot.Remaining
+	}
+	return forfeited, drifted, nil
+}
+
+// upcomingExpirations sums the employee's expiring coupons per day up to the given time
+func upcomingExpirations(ctx context.Context, client *mongo.Client, employeeID string, until time.Time) ([]models.UpcomingExpiration, error) {
+	lots, err := findCouponLots(ctx, client, bson.D{
+		{Key: "employee_id", Value: employeeID},
+		{Key: "status", Value: "active"},
+		{Key: "remaining", Value: bson.D{{Key: "$gt", Value: 0}}},
+		{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: until}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortLotsForSpending(lots)
+
+	expirations := []models.UpcomingExpiration{}
+	for _, lot := range lots {
+		date := lot.ExpiresAt.Format("2006-01-02")
+		if n := len(expirations); n > 0 && expirations[n-1].Date == date {
+			expirations[n-1].Quantity += lot.Remaining
+			continue
+		}
+		expirations = append(expirations, models.UpcomingExpiration{Date: date, Quantity: lot.Remaining})
+	}
+	return expirations, nil
+}
+
+// GetForfeitureReport - Admin reports coupons forfeited through expiry and carry-over, per month
+func GetForfeitureReport(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		match := bson.D{{Key: "type", Value: "expiry"}}
+		createdAt, err := dateRangeQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(createdAt) > 0 {
+			match = append(match, bson.E{Key: "created_at", Value: createdAt})
+		}
+
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+					{Key: "format", Value: "%Y-%m"},
+					{Key: "date", Value: "$created_at"},
+				}}}},
+				{Key: "coupons", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$multiply", Value: bson.A{"$change", -1}}}}}},
+				{Key: "employee_ids", Value: bson.D{{Key: "$addToSet", Value: "$employee_id"}}},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "coupons", Value: 1},
+				{Key: "employees", Value: bson.D{{Key: "$size", Value: "$employee_ids"}}},
+			}}},
+			{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		historyCollection := database.OpenCollection("balance_history", client)
+		cursor, err := historyCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build forfeiture report"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var rows []models.ForfeitureReportRow
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode forfeiture report"})
+			return
+		}
+
+		totalCoupons := 0
+		for _, row := range rows {
+			totalCoupons += row.Coupons
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rows":          rows,
+			"total_coupons": totalCoupons,
+		})
+	}
+}
+
+// sortLotsForSpending orders lots first-expiring-first, lots that never expire last, oldest first within a tie
+func sortLotsForSpending(lots []models.CouponLot) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		a, b := lots[i], lots[j]
+		switch {
+		case a.ExpiresAt == nil && b.ExpiresAt != nil:
+			return false
+		case a.ExpiresAt != nil && b.ExpiresAt == nil:
+			return true
+		case a.ExpiresAt != nil && !a.ExpiresAt.Equal(*b.ExpiresAt):
+			return a.ExpiresAt.Before(*b.ExpiresAt)
+		}
+		return a.IssuedAt.Before(b.IssuedAt)
+	})
+}
+
+func findCouponLots(ctx context.Context, client *mongo.Client, filter bson.D) ([]models.CouponLot, error) {
+	lotCollection := database.OpenCollection("coupon_lots", client)
+	cursor, err := lotCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var lots []models.CouponLot
+	if err = cursor.All(ctx, &lots); err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
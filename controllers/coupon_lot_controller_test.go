@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestExpireCouponLots(t *testing.T) {
+	client := testClient(t)
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.AddDate(0, 1, 0)
+
+	insertDocuments(t, client, "users", models.User{UserID: "admin", Email: "admin@example.com", Role: "ADMIN"})
+	insertDocuments(t, client, "employees",
+		models.Employee{EmployeeID: "in-step", EmployeeCode: "E-1", Status: "active", CurrentBalance: 5},
+		// Lots hold 4 coupons but the balance is 1: the bookkeeping has drifted
+		models.Employee{EmployeeID: "drifted", EmployeeCode: "E-2", Status: "active", CurrentBalance: 1},
+	)
+	insertDocuments(t, client, "coupon_lots",
+		models.CouponLot{LotID: "in-step-expired", EmployeeID: "in-step", Quantity: 3, Remaining: 3, Status: "active", ExpiresAt: &past},
+		models.CouponLot{LotID: "in-step-current", EmployeeID: "in-step", Quantity: 2, Remaining: 2, Status: "active", ExpiresAt: &future},
+		models.CouponLot{LotID: "drifted-expired", EmployeeID: "drifted", Quantity: 3, Remaining: 3, Status: "active", ExpiresAt: &past},
+		models.CouponLot{LotID: "drifted-current", EmployeeID: "drifted", Quantity: 1, Remaining: 1, Status: "active", ExpiresAt: &future},
+	)
+
+	forfeited, drifted, err := ExpireCouponLots(context.Background(), client, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forfeited != 3 {
+		t.Errorf("forfeited = %d, want 3", forfeited)
+	}
+	if len(drifted) != 1 || drifted[0].LotID != "drifted-expired" {
+		t.Errorf("drifted = %v, want only drifted-expired", drifted)
+	}
+
+	lots := []struct {
+		lotID         string
+		wantStatus    string
+		wantRemaining int
+		wantForfeited int
+	}{
+		{lotID: "in-step-expired", wantStatus: "expired", wantRemaining: 0, wantForfeited: 3},
+		{lotID: "in-step-current", wantStatus: "active", wantRemaining: 2},
+		{lotID: "drifted-expired", wantStatus: "active", wantRemaining: 3},
+		{lotID: "drifted-current", wantStatus: "active", wantRemaining: 1},
+	}
+	for _, tt := range lots {
+		var lot models.CouponLot
+		findDocument(t, client, "coupon_lots", bson.D{{Key: "lot_id", Value: tt.lotID}}, &lot)
+		if lot.Status != tt.wantStatus || lot.Remaining != tt.wantRemaining || lot.ForfeitedQuantity != tt.wantForfeited {
+			t.Errorf("lot %s = %s, %d remaining, %d forfeited; want %s, %d, %d",
+				tt.lotID, lot.Status, lot.Remaining, lot.ForfeitedQuantity, tt.wantStatus, tt.wantRemaining, tt.wantForfeited)
+		}
+	}
+
+	balances := map[string]int{"in-step": 2, "drifted": 1}
+	for employeeID, want := range balances {
+		var employee models.Employee
+		findDocument(t, client, "employees", bson.D{{Key: "employee_id", Value: employeeID}}, &employee)
+		if employee.CurrentBalance != want {
+			t.Errorf("balance of %s = %d, want %d", employeeID, employee.CurrentBalance, want)
+		}
+	}
+
+	var entry models.BalanceHistoryEntry
+	findDocument(t, client, "balance_history", bson.D{{Key: "employee_id", Value: "in-step"}, {Key: "type", Value: "expiry"}}, &entry)
+	if entry.Change != -3 || entry.BalanceAfter != 2 {
+		t.Errorf("expiry entry = %+d, balance after %d; want -3, 2", entry.Change, entry.BalanceAfter)
+	}
+	if n := countDocuments(t, client, "balance_history", bson.D{{Key: "employee_id", Value: "drifted"}}); n != 0 {
+		t.Errorf("%d balance history entries for the drifted employee, want none", n)
+	}
+	if n := countDocuments(t, client, "notifications", bson.D{{Key: "type", Value: "coupon_lot_drift"}, {Key: "reference_id", Value: "drifted-expired"}}); n != 1 {
+		t.Errorf("%d drift alerts, want 1", n)
+	}
+}
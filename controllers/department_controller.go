@@ -0,0 +1,494 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// maxDepartmentDepth bounds walks up the hierarchy so a damaged parent chain cannot loop forever
+const maxDepartmentDepth = 20
+
+var errDepartmentCycle = errors.New("A department cannot be placed under itself or one of its sub-departments")
+
+// resolveCostCenter returns the department's cost center, or the nearest parent's when it has none
+func resolveCostCenter(ctx context.Context, client *mongo.Client, departmentID string) (string, error) {
+	departmentCollection := database.OpenCollection("departments", client)
+	for depth := 0; departmentID != "" && depth < maxDepartmentDepth; depth++ {
+		var department models.Department
+		err := departmentCollection.FindOne(ctx, bson.D{{Key: "department_id", Value: departmentID}}).Decode(&department)
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if department.CostCenter != "" {
+			return department.CostCenter, nil
+		}
+		departmentID = department.ParentDepartmentID
+	}
+	return "", nil
+}
+
+// snapshotCostCenter records the employee's current department and cost center on the transaction,
+// so later reassignments do not move meals already charged
+func snapshotCostCenter(ctx context.Context, client *mongo.Client, transaction *models.Transaction) error {
+	employeeCollection := database.OpenCollection("employees", client)
+	var employee models.Employee
+	err := employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: transaction.EmployeeID}}).Decode(&employee)
+	if err != nil {
+		return err
+	}
+
+	costCenter, err := resolveCostCenter(ctx, client, employee.DepartmentID)
+	if err != nil {
+		return err
+	}
+	transaction.DepartmentID = employee.DepartmentID
+	transaction.CostCenter = costCenter
+	return nil
+}
+
+// assignEmployeeDepartment closes the employee's current department assignment and opens a new one.
+// It must be called inside a session transaction.
+func assignEmployeeDepartment(ctx context.Context, client *mongo.Client, employeeID, departmentID, assignedByUserID string, now time.Time) error {
+	assignmentCollection := database.OpenCollection("department_assignments", client)
+	_, err := assignmentCollection.UpdateMany(
+		ctx,
+		bson.D{
+			{Key: "employee_id", Value: employeeID},
+			{Key: "ended_at", Value: nil},
+		},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "ended_at", Value: now}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	costCenter, err := resolveCostCenter(ctx, client, departmentID)
+	if err != nil {
+		return err
+	}
+	assignment := models.DepartmentAssignment{
+		AssignmentID:     bson.NewObjectID().Hex(),
+		EmployeeID:       employeeID,
+		DepartmentID:     departmentID,
+		CostCenter:       costCenter,
+		StartedAt:        now,
+		AssignedByUserID: assignedByUserID,
+	}
+	_, err = assignmentCollection.InsertOne(ctx, assignment)
+	return err
+}
+
+// checkDepartmentAssignable writes an error response and returns false unless the department exists and is active
+func checkDepartmentAssignable(c *gin.Context, ctx context.Context, client *mongo.Client, departmentID string) bool {
+	departmentCollection := database.OpenCollection("departments", client)
+	var department models.Department
+	err := departmentCollection.FindOne(ctx, bson.D{{Key: "department_id", Value: departmentID}}).Decode(&department)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Department not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch department"})
+		return false
+	}
+	if !department.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Department is not active"})
+		return false
+	}
+	return true
+}
+
+// checkDepartmentParent walks up from the proposed parent and fails if it reaches the department itself
+func checkDepartmentParent(ctx context.Context, client *mongo.Client, departmentID, parentID string) error {
+	departmentCollection := database.OpenCollection("departments", client)
+	for depth := 0; parentID != ""; depth++ {
+		if parentID == departmentID || depth >= maxDepartmentDepth {
+			return errDepartmentCycle
+		}
+		var parent models.Department
+		err := departmentCollection.FindOne(ctx, bson.D{{Key: "department_id", Value: parentID}}).Decode(&parent)
+		if err != nil {
+			return err
+		}
+		parentID = parent.ParentDepartmentID
+	}
+	return nil
+}
+
+// CreateDepartment - Admin adds a department, optionally under a parent department
+func CreateDepartment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateDepartmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		departmentCollection := database.OpenCollection("departments", client)
+		count, err := departmentCollection.CountDocuments(ctx, bson.D{{Key: "code", Value: req.Code}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check department code"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Department code already exists"})
+			return
+		}
+		if req.ParentDepartmentID != "" && !checkDepartmentAssignable(c, ctx, client, req.ParentDepartmentID) {
+			return
+		}
+
+		now := time.Now()
+		department := models.Department{
+			DepartmentID:       bson.NewObjectID().Hex(),
+			Name:               req.Name,
+			Code:               req.Code,
+			ParentDepartmentID: req.ParentDepartmentID,
+			CostCenter:         req.CostCenter,
+			ManagerEmployeeID:  req.ManagerEmployeeID,
+			IsActive:           true,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+		if _, err := departmentCollection.InsertOne(ctx, department); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create department"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":       "Department created successfully",
+			"department_id": department.DepartmentID,
+		})
+	}
+}
+
+// GetDepartments - Admin lists departments with the cost center each one charges to (?parent_id= for one level)
+func GetDepartments(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		departmentCollection := database.OpenCollection("departments", client)
+		cursor, err := departmentCollection.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "code", Value: 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch departments"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var departments []models.Department
+		if err = cursor.All(ctx, &departments); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode departments"})
+			return
+		}
+
+		// Resolve inherited cost centers from the full list rather than one lookup per department
+		byID := make(map[string]models.Department, len(departments))
+		for _, department := range departments {
+			byID[department.DepartmentID] = department
+		}
+
+		parentID, filterByParent := c.GetQuery("parent_id")
+		response := []gin.H{}
+		for _, department := range departments {
+			if filterByParent && department.ParentDepartmentID != parentID {
+				continue
+			}
+			costCenter := ""
+			node, depth := department, 0
+			for ; depth < maxDepartmentDepth; depth++ {
+				if node.CostCenter != "" {
+					costCenter = node.CostCenter
+					break
+				}
+				parent, found := byID[node.ParentDepartmentID]
+				if !found {
+					break
+				}
+				node = parent
+			}
+			response = append(response, gin.H{
+				"department":            department,
+				"effective_cost_center": costCenter,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"departments": response,
+			"total":       len(response),
+		})
+	}
+}
+
+// UpdateDepartment - Admin renames, moves or deactivates a department, or changes its cost center.
+// Meals already charged keep the cost center they were approved under.
+func UpdateDepartment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		departmentID := c.Param("id")
+
+		var req models.UpdateDepartmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		updateData := bson.M{"updated_at": time.Now()}
+		if req.Name != "" {
+			updateData["name"] = req.Name
+		}
+		if req.ParentDepartmentID != nil {
+			if err := checkDepartmentParent(ctx, client, departmentID, *req.ParentDepartmentID); err != nil {
+				if errors.Is(err, errDepartmentCycle) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Parent department not found"})
+				return
+			}
+			updateData["parent_department_id"] = *req.ParentDepartmentID
+		}
+		if req.CostCenter != nil {
+			updateData["cost_center"] = *req.CostCenter
+		}
+		if req.ManagerEmployeeID != nil {
+			updateData["manager_employee_id"] = *req.ManagerEmployeeID
+		}
+		if req.IsActive != nil {
+			updateData["is_active"] = *req.IsActive
+		}
+
+		departmentCollection := database.OpenCollection("departments", client)
+		result, err := departmentCollection.UpdateOne(ctx, bson.D{{Key: "department_id", Value: departmentID}}, bson.D{{Key: "$set", Value: updateData}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update department"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Department updated successfully"})
+	}
+}
+
+// GetEmployeeDepartmentHistory - Admin lists the departments an employee has belonged to, most recent first
+func GetEmployeeDepartmentHistory(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		employeeID := c.Param("id")
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		assignmentCollection := database.OpenCollection("department_assignments", client)
+		cursor, err := assignmentCollection.Find(
+			ctx,
+			bson.D{{Key: "employee_id", Value: employeeID}},
+			options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch department history"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		assignments := []models.DepartmentAssignment{}
+		if err = cursor.All(ctx, &assignments); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode department history"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"assignments": assignments,
+			"total":       len(assignments),
+		})
+	}
+}
+
+// GetChargebackReport - Admin reports completed meal costs per cost center per month (?from=&to=).
+// Meals with no cost center are reported under "unassigned".
+func GetChargebackReport(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		match, err := chargebackMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		employeeFunded := bson.D{{Key: "$ifNull", Value: bson.A{"$employee_funded_amount", 0}}}
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{
+					{Key: "cost_center", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$cost_center", "unassigned"}}}},
+					{Key: "period", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+						{Key: "format", Value: "%Y-%m"},
+						{Key: "date", Value: "$processed_at"},
+					}}}},
+				}},
+				{Key: "meals", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "coupons", Value: bson.D{{Key: "$sum", Value: "$coupons_used"}}},
+				{Key: "total_amount", Value: bson.D{{Key: "$sum", Value: "$total_amount"}}},
+				{Key: "company_funded_amount", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{"$total_amount", employeeFunded}}}}}},
+				{Key: "employee_ids", Value: bson.D{{Key: "$addToSet", Value: "$employee_id"}}},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "cost_center", Value: "$_id.cost_center"},
+				{Key: "period", Value: "$_id.period"},
+				{Key: "meals", Value: 1},
+				{Key: "coupons", Value: 1},
+				{Key: "total_amount", Value: 1},
+				{Key: "company_funded_amount", Value: 1},
+				{Key: "employees", Value: bson.D{{Key: "$size", Value: "$employee_ids"}}},
+			}}},
+			{{Key: "$sort", Value: bson.D{{Key: "cost_center", Value: 1}, {Key: "period", Value: 1}}}},
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		transactionCollection := database.OpenCollection("transactions", client)
+		cursor, err := transactionCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build chargeback report"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		rows := []models.ChargebackReportRow{}
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode chargeback report"})
+			return
+		}
+
+		totalAmount := 0.0
+		for _, row := range rows {
+			totalAmount += row.TotalAmount
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rows":         rows,
+			"total_amount": totalAmount,
+		})
+	}
+}
+
+// GetChargebackEmployees - Admin drills into one cost center's meal costs per employee per month (?from=&to=)
+func GetChargebackEmployees(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		match, err := chargebackMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		costCenter := c.Param("cost_center")
+		if costCenter == "unassigned" {
+			match = append(match, bson.E{Key: "cost_center", Value: nil})
+		} else {
+			match = append(match, bson.E{Key: "cost_center", Value: costCenter})
+		}
+
+		employeeFunded := bson.D{{Key: "$ifNull", Value: bson.A{"$employee_funded_amount", 0}}}
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{
+					{Key: "employee_id", Value: "$employee_id"},
+					{Key: "period", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+						{Key: "format", Value: "%Y-%m"},
+						{Key: "date", Value: "$processed_at"},
+					}}}},
+				}},
+				{Key: "meals", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "coupons", Value: bson.D{{Key: "$sum", Value: "$coupons_used"}}},
+				{Key: "total_amount", Value: bson.D{{Key: "$sum", Value: "$total_amount"}}},
+				{Key: "company_funded_amount", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{"$total_amount", employeeFunded}}}}}},
+			}}},
+			{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "employees"},
+				{Key: "localField", Value: "_id.employee_id"},
+				{Key: "foreignField", Value: "employee_id"},
+				{Key: "as", Value: "employee"},
+			}}},
+			{{Key: "$unwind", Value: bson.D{
+				{Key: "path", Value: "$employee"},
+				{Key: "preserveNullAndEmptyArrays", Value: true},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "employee_id", Value: "$_id.employee_id"},
+				{Key: "employee_code", Value: "$employee.employee_code"},
+				{Key: "name", Value: "$employee.name"},
+				{Key: "period", Value: "$_id.period"},
+				{Key: "meals", Value: 1},
+				{Key: "coupons", Value: 1},
+				{Key: "total_amount", Value: 1},
+				{Key: "company_funded_amount", Value: 1},
+			}}},
+			{{Key: "$sort", Value: bson.D{{Key: "period", Value: 1}, {Key: "employee_code", Value: 1}}}},
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		transactionCollection := database.OpenCollection("transactions", client)
+		cursor, err := transactionCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build chargeback report"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		rows := []models.ChargebackEmployeeRow{}
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode chargeback report"})
+			return
+		}
+
+		totalAmount := 0.0
+		for _, row := range rows {
+			totalAmount += row.TotalAmount
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"cost_center":  costCenter,
+			"rows":         rows,
+			"total_amount": totalAmount,
+		})
+	}
+}
+
+// chargebackMatch selects completed employee meals in the requested date range; guest meals are not charged back
+func chargebackMatch(c *gin.Context) (bson.D, error) {
+	match := bson.D{
+		{Key: "status", Value: "completed"},
+		{Key: "employee_id", Value: bson.D{{Key: "$nin", Value: bson.A{nil, ""}}}},
+	}
+	processedAt, err := dateRangeQuery(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(processedAt) > 0 {
+		match = append(match, bson.E{Key: "processed_at", Value: processedAt})
+	}
+	return match, nil
+}
@@ -58,16 +58,32 @@ func CreateEmployee(client *mongo.Client) gin.HandlerFunc {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 			return
 		}
+		if user.Role != "EMPLOYEE" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User must have EMPLOYEE role"})
+			return
+		}
+		count, err = employeeCollection.CountDocuments(ctx, bson.D{{Key: "user_id", Value: req.UserID}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing employee"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Employee profile already exists for this user"})
+			return
+		}
 		// Parse hire date
 		hireDate, err := time.Parse("2006-01-02", req.HireDate)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hire date format. Use YYYY-MM-DD"})
 			return
 		}
-		// Get monthly allocation from env or default to 26
-		monthlyAllocation := 26
-		if alloc := utils.GetEnvAsInt("MONTHLY_ALLOCATION", 26); alloc > 0 {
-			monthlyAllocation = alloc
+		if req.DepartmentID != "" && !checkDepartmentAssignable(c, ctx, client, req.DepartmentID) {
+			return
+		}
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allocation settings"})
+			return
 		}
 
 		// Create employee
@@ -80,9 +96,11 @@ func CreateEmployee(client *mongo.Client) gin.HandlerFunc {
 			Email:              req.Email,
 			Phone:              req.Phone,
 			Status:             "active",
-			MonthlyAllocation:  monthlyAllocation,
-			CurrentBalance:     monthlyAllocation,
-			LastAllocationDate: &now,
+			DepartmentID:       req.DepartmentID,
+			ManagerEmployeeID:  req.ManagerEmployeeID,
+			JobGrade:           req.JobGrade,
+			ShiftPattern:       req.ShiftPattern,
+			EmploymentType:     req.EmploymentType,
 			HireDate:           hireDate,
 			CreatedByAdminID:   adminUserID,
 			IsVerified:         true,
@@ -90,20 +108,66 @@ func CreateEmployee(client *mongo.Client) gin.HandlerFunc {
 			CreatedAt:          now,
 			UpdatedAt:          now,
 		}
-		result, err := employeeCollection.InsertOne(ctx, employee)
+		// Insert the employee and issue the first allocation together
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		allocation := 0
+		result, err := session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			result, issued, err := insertNewEmployee(sessCtx, client, employee, settings, adminUserID, now)
+			allocation = issued
+			return result, err
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create employee"})
 			return
 		}
 
 		c.JSON(http.StatusCreated, gin.H{
-			"message":     "Employee created successfully",
-			"employee_id": employee.EmployeeID,
-			"result":      result,
+			"message":            "Employee created successfully",
+			"employee_id":        employee.EmployeeID,
+			"initial_allocation": allocation,
+			"result":             result,
 		})
 	}
 }
 
+// insertNewEmployee stores a new employee, opens their department assignment and issues the first
+// allocation for the working days from the hire date. Hires starting in a later month are left to
+// the monthly allocation run of that month. It must be called inside a session transaction.
+func insertNewEmployee(ctx context.Context, client *mongo.Client, employee models.Employee, settings models.OrganizationSettings, adminUserID string, now time.Time) (*mongo.InsertOneResult, int, error) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	allocateNow := employee.HireDate.Before(monthStart.AddDate(0, 1, 0))
+	if allocateNow {
+		employee.LastAllocationDate = &now
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	result, err := employeeCollection.InsertOne(ctx, employee)
+	if err != nil {
+		return nil, 0, err
+	}
+	if employee.DepartmentID != "" {
+		if err := assignEmployeeDepartment(ctx, client, employee.EmployeeID, employee.DepartmentID, adminUserID, now); err != nil {
+			return nil, 0, err
+		}
+	}
+	if !allocateNow {
+		return result, 0, nil
+	}
+
+	rate, err := resolveEmployeeAllocationRate(ctx, client, employee, settings)
+	if err != nil {
+		return nil, 0, err
+	}
+	allocation, err := allocateFrom(ctx, client, employee, settings, rate, latest(monthStart, employee.HireDate), now, "")
+	return result, allocation, err
+}
+
 func GetAllEmployees(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -149,8 +213,15 @@ func GetAllEmployees(client *mongo.Client) gin.HandlerFunc {
 				Email:              emp.Email,
 				Phone:              emp.Phone,
 				Status:             emp.Status,
+				DepartmentID:       emp.DepartmentID,
+				ManagerEmployeeID:  emp.ManagerEmployeeID,
+				JobGrade:           emp.JobGrade,
+				ShiftPattern:       emp.ShiftPattern,
+				EmploymentType:     emp.EmploymentType,
+				AllocationPolicyID: emp.AllocationPolicyID,
 				MonthlyAllocation:  emp.MonthlyAllocation,
 				CurrentBalance:     emp.CurrentBalance,
+				PaidBalance:        emp.PaidBalance,
 				LastAllocationDate: emp.LastAllocationDate,
 				HireDate:           emp.HireDate,
 				IsVerified:         emp.IsVerified,
@@ -213,6 +284,11 @@ func UpdateEmployee(client *mongo.Client) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invaild data input"})
 			return
 		}
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
 		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
@@ -249,35 +325,122 @@ func UpdateEmployee(client *mongo.Client) gin.HandlerFunc {
 				updateData["termination_date"] = now
 			}
 		}
+		if req.DepartmentID != "" {
+			if !checkDepartmentAssignable(c, ctx, client, req.DepartmentID) {
+				return
+			}
+			updateData["department_id"] = req.DepartmentID
+		}
+		if req.ManagerEmployeeID != "" {
+			if req.ManagerEmployeeID == employeeID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "An employee cannot be their own manager"})
+				return
+			}
+			updateData["manager_employee_id"] = req.ManagerEmployeeID
+		}
+		if req.JobGrade != "" {
+			updateData["job_grade"] = req.JobGrade
+		}
+		if req.ShiftPattern != "" {
+			updateData["shift_pattern"] = req.ShiftPattern
+		}
+		if req.EmploymentType != "" {
+			updateData["employment_type"] = req.EmploymentType
+		}
 		if req.Notes != "" {
 			updateData["notes"] = req.Notes
 		}
 
-		employeeCollection := database.OpenCollection("employees", client)
-		result, err := employeeCollection.UpdateOne(
-			ctx,
-			bson.D{{Key: "employee_id", Value: employeeID}},
-			bson.D{{Key: "$set", Value: updateData}},
-		)
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
 
+		// Update the employee and adjust the balance for a status change together
+		session, err := client.StartSession()
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update employee"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
 			return
 		}
+		defer session.EndSession(ctx)
 
-		if result.MatchedCount == 0 {
+		employeeCollection := database.OpenCollection("employees", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			var previous models.Employee
+			err := employeeCollection.FindOneAndUpdate(
+				sessCtx,
+				bson.D{{Key: "employee_id", Value: employeeID}},
+				bson.D{{Key: "$set", Value: updateData}},
+			).Decode(&previous)
+			if err != nil {
+				return nil, err
+			}
+			if req.DepartmentID != "" && req.DepartmentID != previous.DepartmentID {
+				if err := assignEmployeeDepartment(sessCtx, client, employeeID, req.DepartmentID, adminUserID, time.Now()); err != nil {
+					return nil, err
+				}
+			}
+			if req.Status == "" {
+				return nil, nil
+			}
+			if err := setAccountAccess(sessCtx, client, previous, req.Status, adminUserID, time.Now()); err != nil {
+				return nil, err
+			}
+			return nil, applyStatusChange(sessCtx, client, previous, req.Status, settings, time.Now())
+		})
+
+		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
 			return
 		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update employee"})
+			return
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Employee updated successfully",
-			"updated": result.ModifiedCount,
 		})
 	}
 
 }
 
+// accountDisabledStatuses are the employee statuses that lock the employee's user account
+var accountDisabledStatuses = map[string]bool{"suspended": true, "terminated": true}
+
+// setAccountAccess deactivates the employee's user account and signs it out everywhere when the
+// new status disables it, and reactivates it when the employee comes back, the same way SCIM does.
+// It must be called inside a session transaction.
+func setAccountAccess(ctx context.Context, client *mongo.Client, employee models.Employee, newStatus, adminUserID string, now time.Time) error {
+	disable := accountDisabledStatuses[newStatus]
+	if employee.UserID == "" || disable == accountDisabledStatuses[employee.Status] {
+		return nil
+	}
+
+	userCollection := database.OpenCollection("users", client)
+	if !disable {
+		_, err := userCollection.UpdateOne(ctx, bson.D{{Key: "user_id", Value: employee.UserID}}, bson.D{{Key: "$unset", Value: bson.M{"deactivated_at": ""}}})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, client, adminUserID, "employee.account_reactivated", "employee", employee.EmployeeID, bson.M{"user_id": employee.UserID, "status": newStatus})
+	}
+
+	_, err := userCollection.UpdateOne(
+		ctx,
+		bson.D{{Key: "user_id", Value: employee.UserID}, {Key: "deactivated_at", Value: nil}},
+		bson.D{{Key: "$set", Value: bson.M{"deactivated_at": now}}},
+	)
+	if err != nil {
+		return err
+	}
+	if err := revokeUserSessions(ctx, client, employee.UserID, "deactivated", now); err != nil {
+		return err
+	}
+	return recordAudit(ctx, client, adminUserID, "employee.account_deactivated", "employee", employee.EmployeeID, bson.M{"user_id": employee.UserID, "status": newStatus})
+}
+
 // GetMyProfile - Employee gets their own profile
 func GetMyProfile(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -330,13 +493,24 @@ func GetMyBalance(client *mongo.Client) gin.HandlerFunc {
 			return
 		}
 
+		warningDays := utils.GetEnvAsInt("EXPIRY_WARNING_DAYS", 30)
+		expirations, err := upcomingExpirations(ctx, client, employee.EmployeeID, time.Now().AddDate(0, 0, warningDays))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upcoming expirations"})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"employee_code":        employee.EmployeeCode,
 			"name":                 employee.Name,
 			"current_balance":      employee.CurrentBalance,
+			"held_balance":         employee.HeldBalance,
+			"paid_balance":         employee.PaidBalance,
+			"spendable_balance":    spendableCoupons(employee),
 			"monthly_allocation":   employee.MonthlyAllocation,
 			"last_allocation_date": employee.LastAllocationDate,
 			"status":               employee.Status,
+			"upcoming_expirations": expirations,
 		})
 	}
 }
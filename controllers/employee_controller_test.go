@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestUpdateEmployeeAccountAccess(t *testing.T) {
+	client := testClient(t)
+	now := time.Now()
+
+	tests := []struct {
+		name            string
+		status          string
+		newStatus       string
+		wantDeactivated bool
+		wantRevoked     bool
+	}{
+		{name: "suspended", status: "active", newStatus: "suspended", wantDeactivated: true, wantRevoked: true},
+		{name: "terminated", status: "active", newStatus: "terminated", wantDeactivated: true, wantRevoked: true},
+		{name: "on leave keeps the account", status: "active", newStatus: "on_leave"},
+		{name: "reinstated after suspension", status: "suspended", newStatus: "active"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := bson.NewObjectID().Hex()
+			employeeID := bson.NewObjectID().Hex()
+			user := models.User{UserID: userID, Email: userID + "@example.com", Role: "EMPLOYEE"}
+			if accountDisabledStatuses[tt.status] {
+				user.DeactivatedAt = &now
+			}
+			insertDocuments(t, client, "users", user)
+			insertDocuments(t, client, "employees", models.Employee{
+				EmployeeID:   employeeID,
+				UserID:       userID,
+				EmployeeCode: "E-" + employeeID,
+				Status:       tt.status,
+			})
+			insertDocuments(t, client, "sessions", models.Session{
+				SessionID: bson.NewObjectID().Hex(),
+				UserID:    userID,
+				AMR:       []string{"pwd"},
+				ExpiresAt: now.Add(time.Hour),
+			})
+
+			w := serve(t, UpdateEmployee(client), http.MethodPatch, "/employees/:id", "/employees/"+employeeID,
+				"admin", "ADMIN", gin.H{"status": tt.newStatus})
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var updated models.User
+			findDocument(t, client, "users", bson.D{{Key: "user_id", Value: userID}}, &updated)
+			if (updated.DeactivatedAt != nil) != tt.wantDeactivated {
+				t.Errorf("deactivated_at = %v, want deactivated %v", updated.DeactivatedAt, tt.wantDeactivated)
+			}
+			open := countDocuments(t, client, "sessions", bson.D{{Key: "user_id", Value: userID}, {Key: "revoked_at", Value: nil}})
+			if (open == 0) != tt.wantRevoked {
+				t.Errorf("%d open session(s), want revoked %v", open, tt.wantRevoked)
+			}
+		})
+	}
+}
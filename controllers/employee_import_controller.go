@@ -0,0 +1,655 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// employeeImportColumns are the columns an import file may have; the first five are required
+var employeeImportColumns = []string{
+	"employee_code", "first_name", "last_name", "email", "hire_date",
+	"phone", "department_code", "manager_employee_code", "job_grade", "shift_pattern", "employment_type", "notes", "password",
+}
+
+// employeeImportPlan is what importing one row would do
+type employeeImportPlan struct {
+	result       models.EmployeeImportRowResult
+	existing     *models.Employee
+	linkUser     *models.User // existing user account the new employee is linked to
+	hireDate     time.Time
+	departmentID string
+	updates      bson.M
+}
+
+// employeeImportLookup caches what every row of a file is checked against
+type employeeImportLookup struct {
+	departments map[string]string // department code to ID, active departments only
+	fileCodes   map[string]bool   // employee codes in the file, for managers imported together with their team
+}
+
+// DryRunEmployeeImport - Admin uploads a CSV or XLSX employee file and gets per-row validation errors
+// and a create/update/skip diff without changing anything
+func DryRunEmployeeImport(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the employee file as the file form field"})
+			return
+		}
+		rows, err := readEmployeeImportFile(fileHeader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		lookup, err := loadEmployeeImportLookup(ctx, client, rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load departments"})
+			return
+		}
+
+		summary := map[string]int{"create": 0, "update": 0, "skip": 0, "error": 0}
+		results := make([]models.EmployeeImportRowResult, 0, len(rows))
+		for _, plan := range planEmployeeImport(ctx, client, rows, lookup) {
+			summary[plan.result.Action]++
+			results = append(results, plan.result)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"total_rows": len(rows),
+			"summary":    summary,
+			"rows":       results,
+		})
+	}
+}
+
+// StartEmployeeImport - Admin uploads a CSV or XLSX employee file and imports it as a background job.
+// Rows that fail are skipped and listed in the job's error report.
+func StartEmployeeImport(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the employee file as the file form field"})
+			return
+		}
+		rows, err := readEmployeeImportFile(fileHeader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		job := models.EmployeeImportJob{
+			JobID:           bson.NewObjectID().Hex(),
+			FileName:        fileHeader.Filename,
+			Status:          "running",
+			TotalRows:       len(rows),
+			RowErrors:       []models.EmployeeImportRowError{},
+			CreatedByUserID: adminUserID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		jobCollection := database.OpenCollection("employee_import_jobs", client)
+		if _, err := jobCollection.InsertOne(ctx, job); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import job"})
+			return
+		}
+
+		go runEmployeeImport(client, job, rows, adminUserID)
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":    "Import started",
+			"job_id":     job.JobID,
+			"total_rows": job.TotalRows,
+		})
+	}
+}
+
+// GetEmployeeImports - Admin lists import jobs, newest first
+func GetEmployeeImports(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		jobCollection := database.OpenCollection("employee_import_jobs", client)
+		cursor, err := jobCollection.Find(
+			ctx,
+			bson.D{},
+			options.Find().
+				SetSort(bson.D{{Key: "created_at", Value: -1}}).
+				SetProjection(bson.D{{Key: "row_errors", Value: 0}}),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import jobs"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		jobs := []models.EmployeeImportJob{}
+		if err = cursor.All(ctx, &jobs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode import jobs"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"jobs":  jobs,
+			"total": len(jobs),
+		})
+	}
+}
+
+// GetEmployeeImport - Admin follows the progress of an import job
+func GetEmployeeImport(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		job, err := findEmployeeImportJob(ctx, client, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// GetEmployeeImportErrors - Admin downloads the rows an import job could not process as CSV
+func GetEmployeeImportErrors(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		job, err := findEmployeeImportJob(ctx, client, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+			return
+		}
+
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		writer.Write([]string{"line", "employee_code", "error"})
+		for _, rowError := range job.RowErrors {
+			writer.Write([]string{strconv.Itoa(rowError.Line), rowError.EmployeeCode, rowError.Message})
+		}
+		writer.Flush()
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=employee-import-%s-errors.csv", job.JobID))
+		c.Data(http.StatusOK, "text/csv", buf.Bytes())
+	}
+}
+
+// runEmployeeImport applies the rows one at a time, each in its own transaction, and records progress on the job.
+// Managers are linked after all rows so a team can be imported in the same file as its manager.
+func runEmployeeImport(client *mongo.Client, job models.EmployeeImportJob, rows []models.EmployeeImportRow, adminUserID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	jobCollection := database.OpenCollection("employee_import_jobs", client)
+	finish := func(status, failure string) {
+		now := time.Now()
+		jobCollection.UpdateOne(ctx, bson.D{{Key: "job_id", Value: job.JobID}}, bson.D{{Key: "$set", Value: bson.D{
+			{Key: "status", Value: status},
+			{Key: "failure", Value: failure},
+			{Key: "finished_at", Value: now},
+			{Key: "updated_at", Value: now},
+		}}})
+	}
+
+	settings, err := loadOrganizationSettings(ctx, client)
+	if err != nil {
+		finish("failed", "Failed to fetch allocation settings")
+		return
+	}
+	lookup, err := loadEmployeeImportLookup(ctx, client, rows)
+	if err != nil {
+		finish("failed", "Failed to load departments")
+		return
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		finish("failed", "Failed to start database session")
+		return
+	}
+	defer session.EndSession(ctx)
+
+	type managerLink struct {
+		line         int
+		employeeCode string
+		managerCode  string
+	}
+	var managerLinks []managerLink
+
+	for i, plan := range planEmployeeImport(ctx, client, rows, lookup) {
+		row := rows[i]
+		counter := plan.result.Action
+		var rowErr error
+		switch plan.result.Action {
+		case "create", "update":
+			_, rowErr = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+				return nil, applyEmployeeImportRow(sessCtx, client, row, plan, settings, adminUserID)
+			})
+		case "error":
+			rowErr = errors.New(strings.Join(plan.result.Errors, "; "))
+		}
+
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "updated_at", Value: time.Now()}}}}
+		if rowErr != nil {
+			counter = "error"
+			update = append(update, bson.E{Key: "$push", Value: bson.D{{Key: "row_errors", Value: models.EmployeeImportRowError{
+				Line:         row.Line,
+				EmployeeCode: row.EmployeeCode,
+				Message:      rowErr.Error(),
+			}}}})
+		} else if row.ManagerEmployeeCode != "" {
+			managerLinks = append(managerLinks, managerLink{row.Line, row.EmployeeCode, row.ManagerEmployeeCode})
+		}
+
+		field := map[string]string{"create": "created", "update": "updated", "skip": "skipped", "error": "failed"}[counter]
+		update = append(update, bson.E{Key: "$inc", Value: bson.D{
+			{Key: "processed", Value: 1},
+			{Key: field, Value: 1},
+		}})
+		if _, err := jobCollection.UpdateOne(ctx, bson.D{{Key: "job_id", Value: job.JobID}}, update); err != nil {
+			finish("failed", "Failed to record progress")
+			return
+		}
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	for _, link := range managerLinks {
+		var manager models.Employee
+		err := employeeCollection.FindOne(ctx, bson.D{{Key: "employee_code", Value: link.managerCode}}).Decode(&manager)
+		if err == nil {
+			_, err = employeeCollection.UpdateOne(
+				ctx,
+				bson.D{{Key: "employee_code", Value: link.employeeCode}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "manager_employee_id", Value: manager.EmployeeID}}}},
+			)
+		}
+		if err != nil {
+			jobCollection.UpdateOne(ctx, bson.D{{Key: "job_id", Value: job.JobID}}, bson.D{{Key: "$push", Value: bson.D{{Key: "row_errors", Value: models.EmployeeImportRowError{
+				Line:         link.line,
+				EmployeeCode: link.employeeCode,
+				Message:      "Manager " + link.managerCode + " could not be linked",
+			}}}}})
+		}
+	}
+
+	finish("completed", "")
+
+	final, err := findEmployeeImportJob(ctx, client, job.JobID)
+	if err == nil {
+		recordAudit(ctx, client, adminUserID, "employee_import.completed", "employee_import", job.JobID, bson.M{
+			"file_name": job.FileName,
+			"created":   final.Created,
+			"updated":   final.Updated,
+			"skipped":   final.Skipped,
+			"failed":    final.Failed,
+		})
+	}
+}
+
+// applyEmployeeImportRow creates or updates the employee of one planned row.
+// It must be called inside a session transaction.
+func applyEmployeeImportRow(ctx context.Context, client *mongo.Client, row models.EmployeeImportRow, plan employeeImportPlan, settings models.OrganizationSettings, adminUserID string) error {
+	now := time.Now()
+	employeeCollection := database.OpenCollection("employees", client)
+
+	if plan.result.Action == "update" {
+		plan.updates["updated_at"] = now
+		_, err := employeeCollection.UpdateOne(ctx, bson.D{{Key: "employee_id", Value: plan.existing.EmployeeID}}, bson.D{{Key: "$set", Value: plan.updates}})
+		if err != nil {
+			return err
+		}
+		if departmentID, changed := plan.updates["department_id"].(string); changed {
+			return assignEmployeeDepartment(ctx, client, plan.existing.EmployeeID, departmentID, adminUserID, now)
+		}
+		return nil
+	}
+
+	userID := ""
+	if plan.linkUser != nil {
+		userID = plan.linkUser.UserID
+	} else {
+		hashedPassword, err := HashPassword(row.Password)
+		if err != nil {
+			return err
+		}
+		user := models.User{
+			UserID:    bson.NewObjectID().Hex(),
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+			Email:     row.Email,
+			Password:  hashedPassword,
+			Role:      "EMPLOYEE",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		userCollection := database.OpenCollection("users", client)
+		if _, err := userCollection.InsertOne(ctx, user); err != nil {
+			return err
+		}
+		userID = user.UserID
+	}
+
+	employee := models.Employee{
+		EmployeeID:       bson.NewObjectID().Hex(),
+		UserID:           userID,
+		EmployeeCode:     row.EmployeeCode,
+		Name:             strings.TrimSpace(row.FirstName + " " + row.LastName),
+		Email:            row.Email,
+		Phone:            row.Phone,
+		Status:           "active",
+		DepartmentID:     plan.departmentID,
+		JobGrade:         row.JobGrade,
+		ShiftPattern:     row.ShiftPattern,
+		EmploymentType:   row.EmploymentType,
+		HireDate:         plan.hireDate,
+		CreatedByAdminID: adminUserID,
+		IsVerified:       true,
+		Notes:            row.Notes,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	_, _, err := insertNewEmployee(ctx, client, employee, settings, adminUserID, now)
+	return err
+}
+
+// planEmployeeImport validates every row against the database and the rest of the file
+func planEmployeeImport(ctx context.Context, client *mongo.Client, rows []models.EmployeeImportRow, lookup employeeImportLookup) []employeeImportPlan {
+	seenCodes := map[string]int{}
+	seenEmails := map[string]int{}
+	plans := make([]employeeImportPlan, 0, len(rows))
+	for _, row := range rows {
+		plan := planEmployeeImportRow(ctx, client, row, lookup)
+
+		email := strings.ToLower(row.Email)
+		if line, found := seenCodes[row.EmployeeCode]; found && row.EmployeeCode != "" {
+			plan.result.Errors = append(plan.result.Errors, fmt.Sprintf("employee_code is repeated from line %d", line))
+		}
+		if line, found := seenEmails[email]; found && email != "" {
+			plan.result.Errors = append(plan.result.Errors, fmt.Sprintf("email is repeated from line %d", line))
+		}
+		seenCodes[row.EmployeeCode] = row.Line
+		seenEmails[email] = row.Line
+
+		if len(plan.result.Errors) > 0 {
+			plan.result.Action = "error"
+			plan.result.Changes = nil
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+// planEmployeeImportRow works out whether a row creates, updates or leaves an employee unchanged
+func planEmployeeImportRow(ctx context.Context, client *mongo.Client, row models.EmployeeImportRow, lookup employeeImportLookup) employeeImportPlan {
+	plan := employeeImportPlan{result: models.EmployeeImportRowResult{Line: row.Line, EmployeeCode: row.EmployeeCode}}
+	fail := func(message string) {
+		plan.result.Errors = append(plan.result.Errors, message)
+	}
+
+	for column, value := range map[string]string{
+		"employee_code": row.EmployeeCode,
+		"first_name":    row.FirstName,
+		"last_name":     row.LastName,
+		"email":         row.Email,
+		"hire_date":     row.HireDate,
+	} {
+		if value == "" {
+			fail(column + " is required")
+		}
+	}
+	if row.Email != "" && validator.New().Var(row.Email, "email") != nil {
+		fail("email is not a valid email address")
+	}
+	if row.HireDate != "" {
+		hireDate, err := parseImportDate(row.HireDate)
+		if err != nil {
+			fail("hire_date is not a date. Use YYYY-MM-DD")
+		}
+		plan.hireDate = hireDate
+	}
+	if row.DepartmentCode != "" {
+		departmentID, found := lookup.departments[row.DepartmentCode]
+		if !found {
+			fail("department_code " + row.DepartmentCode + " is not an active department")
+		}
+		plan.departmentID = departmentID
+	}
+	if row.ManagerEmployeeCode != "" {
+		if row.ManagerEmployeeCode == row.EmployeeCode {
+			fail("an employee cannot be their own manager")
+		} else if !lookup.fileCodes[row.ManagerEmployeeCode] {
+			count, err := database.OpenCollection("employees", client).CountDocuments(ctx, bson.D{{Key: "employee_code", Value: row.ManagerEmployeeCode}})
+			if err != nil || count == 0 {
+				fail("manager_employee_code " + row.ManagerEmployeeCode + " does not exist")
+			}
+		}
+	}
+	if len(plan.result.Errors) > 0 {
+		plan.result.Action = "error"
+		return plan
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	var existing models.Employee
+	err := employeeCollection.FindOne(ctx, bson.D{{Key: "employee_code", Value: row.EmployeeCode}}).Decode(&existing)
+	switch {
+	case err == nil:
+		planEmployeeImportUpdate(&plan, row, existing)
+	case err == mongo.ErrNoDocuments:
+		planEmployeeImportCreate(ctx, client, &plan, row)
+	default:
+		fail("failed to look up the employee")
+	}
+	if len(plan.result.Errors) > 0 {
+		plan.result.Action = "error"
+	}
+	return plan
+}
+
+func planEmployeeImportUpdate(plan *employeeImportPlan, row models.EmployeeImportRow, existing models.Employee) {
+	if !strings.EqualFold(existing.Email, row.Email) {
+		plan.result.Errors = append(plan.result.Errors, "email does not match the existing employee "+row.EmployeeCode)
+		return
+	}
+
+	plan.existing = &existing
+	plan.updates = bson.M{}
+	change := func(field, from, to string) {
+		if to == "" || to == from {
+			return
+		}
+		plan.updates[field] = to
+		plan.result.Changes = append(plan.result.Changes, fmt.Sprintf("%s: %q -> %q", field, from, to))
+	}
+	change("name", existing.Name, strings.TrimSpace(row.FirstName+" "+row.LastName))
+	change("phone", existing.Phone, row.Phone)
+	change("department_id", existing.DepartmentID, plan.departmentID)
+	change("job_grade", existing.JobGrade, row.JobGrade)
+	change("shift_pattern", existing.ShiftPattern, row.ShiftPattern)
+	change("employment_type", existing.EmploymentType, row.EmploymentType)
+	change("notes", existing.Notes, row.Notes)
+
+	plan.result.Action = "update"
+	if len(plan.updates) == 0 {
+		plan.result.Action = "skip"
+	}
+}
+
+func planEmployeeImportCreate(ctx context.Context, client *mongo.Client, plan *employeeImportPlan, row models.EmployeeImportRow) {
+	plan.result.Action = "create"
+	plan.result.Changes = []string{"new employee"}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	count, err := employeeCollection.CountDocuments(ctx, bson.D{{Key: "email", Value: row.Email}})
+	if err != nil || count > 0 {
+		plan.result.Errors = append(plan.result.Errors, "email already belongs to another employee")
+		return
+	}
+
+	userCollection := database.OpenCollection("users", client)
+	var user models.User
+	err = userCollection.FindOne(ctx, bson.D{{Key: "email", Value: row.Email}}).Decode(&user)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		if len(row.Password) < 6 {
+			plan.result.Errors = append(plan.result.Errors, "password of at least 6 characters is required for a new user account")
+			return
+		}
+		plan.result.Changes = append(plan.result.Changes, "new user account")
+	case err != nil:
+		plan.result.Errors = append(plan.result.Errors, "failed to look up the user account")
+	case user.Role != "EMPLOYEE":
+		plan.result.Errors = append(plan.result.Errors, "email belongs to a "+strings.ToLower(user.Role)+" account")
+	default:
+		count, err := employeeCollection.CountDocuments(ctx, bson.D{{Key: "user_id", Value: user.UserID}})
+		if err != nil || count > 0 {
+			plan.result.Errors = append(plan.result.Errors, "user account is already linked to another employee")
+			return
+		}
+		plan.linkUser = &user
+		plan.result.Changes = append(plan.result.Changes, "linked to existing user account")
+	}
+}
+
+// loadEmployeeImportLookup loads the active departments by code and collects the file's employee codes
+func loadEmployeeImportLookup(ctx context.Context, client *mongo.Client, rows []models.EmployeeImportRow) (employeeImportLookup, error) {
+	lookup := employeeImportLookup{departments: map[string]string{}, fileCodes: map[string]bool{}}
+	for _, row := range rows {
+		lookup.fileCodes[row.EmployeeCode] = true
+	}
+
+	departmentCollection := database.OpenCollection("departments", client)
+	cursor, err := departmentCollection.Find(ctx, bson.D{{Key: "is_active", Value: true}})
+	if err != nil {
+		return lookup, err
+	}
+	defer cursor.Close(ctx)
+
+	var departments []models.Department
+	if err = cursor.All(ctx, &departments); err != nil {
+		return lookup, err
+	}
+	for _, department := range departments {
+		lookup.departments[department.Code] = department.DepartmentID
+	}
+	return lookup, nil
+}
+
+// readEmployeeImportFile reads the rows of a .csv or .xlsx file whose first row names the columns
+func readEmployeeImportFile(fileHeader *multipart.FileHeader) ([]models.EmployeeImportRow, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, errors.New("Failed to read uploaded file")
+	}
+	defer file.Close()
+
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".csv":
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err = reader.ReadAll()
+	case ".xlsx":
+		records, err = utils.ReadXLSXRows(file, fileHeader.Size)
+	default:
+		return nil, errors.New("Unsupported file type. Upload a .csv or .xlsx file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid file: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, errors.New("The file needs a header row and at least one employee")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range employeeImportColumns[:5] {
+		if _, found := columns[required]; !found {
+			return nil, errors.New("Missing column " + required)
+		}
+	}
+
+	rows := make([]models.EmployeeImportRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		value := func(column string) string {
+			index, found := columns[column]
+			if !found || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		rows = append(rows, models.EmployeeImportRow{
+			Line:                i + 2,
+			EmployeeCode:        value("employee_code"),
+			FirstName:           value("first_name"),
+			LastName:            value("last_name"),
+			Email:               utils.NormalizeEmail(value("email")),
+			Phone:               value("phone"),
+			HireDate:            value("hire_date"),
+			DepartmentCode:      value("department_code"),
+			ManagerEmployeeCode: value("manager_employee_code"),
+			JobGrade:            value("job_grade"),
+			ShiftPattern:        value("shift_pattern"),
+			EmploymentType:      value("employment_type"),
+			Notes:               value("notes"),
+			Password:            value("password"),
+		})
+	}
+	return rows, nil
+}
+
+// parseImportDate accepts YYYY-MM-DD, or the serial number spreadsheets store dates as
+func parseImportDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return utils.ExcelSerialDate(value)
+}
+
+func findEmployeeImportJob(ctx context.Context, client *mongo.Client, jobID string) (models.EmployeeImportJob, error) {
+	jobCollection := database.OpenCollection("employee_import_jobs", client)
+	var job models.EmployeeImportJob
+	err := jobCollection.FindOne(ctx, bson.D{{Key: "job_id", Value: jobID}}).Decode(&job)
+	return job, err
+}
@@ -0,0 +1,681 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var errGroupNotPending = errors.New("group transaction is no longer pending")
+
+// InitiateGroupTransaction - Supplier charges several employees under one checkout
+func InitiateGroupTransaction(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.InitiateGroupTransactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		supplierUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if !checkProgramOpen(c, ctx, client, time.Now()) {
+			return
+		}
+
+		// 1. Get and validate supplier
+		supplierCollection := database.OpenCollection("suppliers", client)
+		var supplier models.Supplier
+		err = supplierCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: supplierUserID}}).Decode(&supplier)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier profile not found. Please contact admin."})
+			return
+		}
+		if !supplier.IsActive || !supplier.IsVerified {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your supplier account is not active or not yet verified."})
+			return
+		}
+
+		// 2. Location validation (if coordinates provided)
+		if req.Latitude != 0 && req.Longitude != 0 &&
+			!utils.ValidateLocation(supplier.Latitude, supplier.Longitude, req.Latitude, req.Longitude, supplier.LocationRadius) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                 "Group is outside the allowed location radius",
+				"allowed_radius_meters": supplier.LocationRadius,
+			})
+			return
+		}
+
+		// 3. Validate every participant before creating anything
+		qrCollection := database.OpenCollection("qr_codes", client)
+		employeeCollection := database.OpenCollection("employees", client)
+
+		now := time.Now()
+		groupID := uuid.New().String()
+		seenEmployees := map[string]bool{}
+		var transactions []interface{}
+		var transactionIDs []string
+		var employees []models.Employee
+		totalCoupons := 0
+		totalAmount := 0.0
+		couponValue := 45.0
+
+		for i, participant := range req.Participants {
+			var qrCode models.QRCode
+			err = qrCollection.FindOne(ctx, bson.D{{Key: "code", Value: participant.QRCode}}).Decode(&qrCode)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid QR code", "participant": i})
+				return
+			}
+			if qrCode.IsUsed || now.After(qrCode.ExpiresAt) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "QR code has been used or has expired", "participant": i})
+				return
+			}
+
+			var employee models.Employee
+			err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: qrCode.EmployeeID}}).Decode(&employee)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found", "participant": i})
+				return
+			}
+			if employee.Status != "active" && employee.Status != "on_leave" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Employee account status does not allow transactions", "participant": i})
+				return
+			}
+			if seenEmployees[employee.EmployeeID] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Each employee can only appear once in a group", "participant": i})
+				return
+			}
+			seenEmployees[employee.EmployeeID] = true
+
+			if spendableCoupons(employee) < participant.CouponsUsed {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":             "Insufficient coupon balance",
+					"participant":       i,
+					"employee_code":     employee.EmployeeCode,
+					"employee_balance":  spendableCoupons(employee),
+					"requested_coupons": participant.CouponsUsed,
+				})
+				return
+			}
+
+			amount := float64(participant.CouponsUsed) * couponValue
+			transactionID := uuid.New().String()
+			transactions = append(transactions, models.Transaction{
+				TransactionID:      transactionID,
+				EmployeeID:         employee.EmployeeID,
+				SupplierID:         supplier.SupplierID,
+				QRCodeID:           qrCode.QRCodeID,
+				GroupTransactionID: groupID,
+				CouponsUsed:        participant.CouponsUsed,
+				TotalAmount:        amount,
+				EmployeeLatitude:   req.Latitude,
+				EmployeeLongitude:  req.Longitude,
+				Status:             "pending",
+				Notes:              req.Notes,
+				ProcessedAt:        now,
+				CreatedAt:          now,
+				UpdatedAt:          now,
+			})
+			transactionIDs = append(transactionIDs, transactionID)
+			employees = append(employees, employee)
+			totalCoupons += participant.CouponsUsed
+			totalAmount += amount
+		}
+
+		group := models.GroupTransaction{
+			GroupTransactionID: groupID,
+			SupplierID:         supplier.SupplierID,
+			TransactionIDs:     transactionIDs,
+			ParticipantCount:   len(transactionIDs),
+			ApprovedCount:      0,
+			TotalCoupons:       totalCoupons,
+			TotalAmount:        totalAmount,
+			Status:             "pending",
+			Notes:              req.Notes,
+			ExpiresAt:          now.Add(time.Duration(utils.GetEnvAsInt("GROUP_APPROVAL_MINUTES", 15)) * time.Minute),
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+
+		// 4. Create the group and all shares together
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			groupCollection := database.OpenCollection("group_transactions", client)
+			if _, err := groupCollection.InsertOne(sessCtx, group); err != nil {
+				return nil, err
+			}
+			transactionCollection := database.OpenCollection("transactions", client)
+			if _, err := transactionCollection.InsertMany(sessCtx, transactions); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group transaction"})
+			return
+		}
+
+		for i, employee := range employees {
+			share := transactions[i].(models.Transaction)
+			message := fmt.Sprintf("%s wants to charge %d coupon(s) for a group meal. Please approve or reject.", supplier.BusinessName, share.CouponsUsed)
+			notifyUser(ctx, client, employee.UserID, "approval_required", "Group meal approval required", message, share.TransactionID)
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success":              true,
+			"message":              "Group transaction initiated. Waiting for every participant to approve.",
+			"group_transaction_id": groupID,
+			"transaction_ids":      transactionIDs,
+			"participant_count":    group.ParticipantCount,
+			"total_coupons":        totalCoupons,
+			"total_amount":         totalAmount,
+			"status":               "pending",
+			"requires_approval":    true,
+			"expires_at":           group.ExpiresAt,
+		})
+	}
+}
+
+// GetGroupTransaction - Supplier views the aggregate status of a group checkout
+func GetGroupTransaction(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		supplierUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplierCollection := database.OpenCollection("suppliers", client)
+		var supplier models.Supplier
+		err = supplierCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: supplierUserID}}).Decode(&supplier)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+			return
+		}
+
+		groupCollection := database.OpenCollection("group_transactions", client)
+		var group models.GroupTransaction
+		err = groupCollection.FindOne(ctx, bson.D{
+			{Key: "group_transaction_id", Value: c.Param("id")},
+			{Key: "supplier_id", Value: supplier.SupplierID},
+		}).Decode(&group)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group transaction not found"})
+			return
+		}
+
+		transactionCollection := database.OpenCollection("transactions", client)
+		cursor, err := transactionCollection.Find(ctx, bson.D{{Key: "group_transaction_id", Value: group.GroupTransactionID}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var shares []models.Transaction
+		if err = cursor.All(ctx, &shares); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode transactions"})
+			return
+		}
+
+		employeeCollection := database.OpenCollection("employees", client)
+		statusCounts := map[string]int{}
+		var participants []models.GroupParticipantStatus
+		for _, share := range shares {
+			var employee models.Employee
+			employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: share.EmployeeID}}).Decode(&employee)
+
+			statusCounts[share.Status]++
+			participants = append(participants, models.GroupParticipantStatus{
+				TransactionID: share.TransactionID,
+				EmployeeID:    share.EmployeeID,
+				EmployeeName:  employee.Name,
+				EmployeeCode:  employee.EmployeeCode,
+				CouponsUsed:   share.CouponsUsed,
+				TotalAmount:   share.TotalAmount,
+				Status:        share.Status,
+			})
+		}
+
+		c.JSON(http.StatusOK, models.GroupTransactionResponse{
+			GroupTransaction: group,
+			Participants:     participants,
+			StatusCounts:     statusCounts,
+		})
+	}
+}
+
+// CancelGroupTransaction - Supplier cancels a group checkout that has not completed
+func CancelGroupTransaction(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CancelGroupTransactionRequest
+		c.ShouldBindJSON(&req)
+
+		supplierUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplierCollection := database.OpenCollection("suppliers", client)
+		var supplier models.Supplier
+		err = supplierCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: supplierUserID}}).Decode(&supplier)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+			return
+		}
+
+		groupCollection := database.OpenCollection("group_transactions", client)
+		var group models.GroupTransaction
+		err = groupCollection.FindOne(ctx, bson.D{
+			{Key: "group_transaction_id", Value: c.Param("id")},
+			{Key: "supplier_id", Value: supplier.SupplierID},
+		}).Decode(&group)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group transaction not found"})
+			return
+		}
+
+		reason := req.Reason
+		if reason == "" {
+			reason = "Cancelled by supplier"
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			return nil, cancelGroupTransaction(sessCtx, client, group.GroupTransactionID, "", reason)
+		})
+		if errors.Is(err, errGroupNotPending) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":          "Group transaction has already been processed",
+				"current_status": group.Status,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel group transaction"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":              true,
+			"message":              "Group transaction cancelled",
+			"group_transaction_id": group.GroupTransactionID,
+			"status":               "cancelled",
+			"reason":               reason,
+		})
+	}
+}
+
+// approveGroupShare records one participant's decision on their share.
+// Approving the last outstanding share settles every share atomically; rejecting any share cancels the group.
+func approveGroupShare(c *gin.Context, ctx context.Context, client *mongo.Client, transaction models.Transaction, employee models.Employee, req models.ApproveTransactionRequest) {
+	session, err := client.StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+		return
+	}
+	defer session.EndSession(ctx)
+
+	if !*req.Approved {
+		reason := req.Reason
+		if reason == "" {
+			reason = "Rejected by employee"
+		}
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			return nil, cancelGroupTransaction(sessCtx, client, transaction.GroupTransactionID, transaction.TransactionID, reason)
+		})
+		if errors.Is(err, errGroupNotPending) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Group transaction has already been processed"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject transaction"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":              true,
+			"message":              "Transaction rejected. The group transaction has been cancelled.",
+			"transaction_id":       transaction.TransactionID,
+			"group_transaction_id": transaction.GroupTransactionID,
+			"status":               "rejected",
+			"reason":               reason,
+		})
+		return
+	}
+
+	var failedShare models.Transaction
+	result, err := session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		failedShare = models.Transaction{}
+		now := time.Now()
+		transactionCollection := database.OpenCollection("transactions", client)
+		groupCollection := database.OpenCollection("group_transactions", client)
+
+		res, err := transactionCollection.UpdateOne(
+			sessCtx,
+			bson.D{
+				{Key: "transaction_id", Value: transaction.TransactionID},
+				{Key: "status", Value: "pending"},
+			},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "approved"},
+				{Key: "approval_method", Value: "app"},
+				{Key: "updated_at", Value: now},
+			}}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if res.MatchedCount == 0 {
+			return nil, errGroupNotPending
+		}
+
+		// Incrementing the counter on the group document makes concurrent approvals conflict,
+		// so exactly one of them sees the final count. An expired group is left for ExpireGroupTransactions.
+		var group models.GroupTransaction
+		err = groupCollection.FindOneAndUpdate(
+			sessCtx,
+			bson.D{
+				{Key: "group_transaction_id", Value: transaction.GroupTransactionID},
+				{Key: "status", Value: "pending"},
+				{Key: "expires_at", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$lte", Value: now}}}}},
+			},
+			bson.D{
+				{Key: "$inc", Value: bson.D{{Key: "approved_count", Value: 1}}},
+				{Key: "$set", Value: bson.D{{Key: "updated_at", Value: now}}},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&group)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errGroupNotPending
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if group.ApprovedCount < group.ParticipantCount {
+			return group, nil
+		}
+
+		// Every share approved: settle all of them
+		cursor, err := transactionCollection.Find(sessCtx, bson.D{{Key: "group_transaction_id", Value: group.GroupTransactionID}})
+		if err != nil {
+			return nil, err
+		}
+		var shares []models.Transaction
+		if err = cursor.All(sessCtx, &shares); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			if err := settleTransaction(sessCtx, client, &share, now); err != nil {
+				failedShare = share
+				return nil, err
+			}
+			_, err = transactionCollection.UpdateOne(
+				sessCtx,
+				bson.D{{Key: "transaction_id", Value: share.TransactionID}},
+				bson.D{{Key: "$set", Value: bson.D{
+					{Key: "status", Value: "completed"},
+					{Key: "paid_coupons", Value: share.PaidCoupons},
+					{Key: "employee_funded_amount", Value: share.EmployeeFundedAmount},
+					{Key: "department_id", Value: share.DepartmentID},
+					{Key: "cost_center", Value: share.CostCenter},
+					{Key: "updated_at", Value: now},
+				}}},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = groupCollection.UpdateOne(
+			sessCtx,
+			bson.D{{Key: "group_transaction_id", Value: group.GroupTransactionID}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "completed"},
+				{Key: "completed_at", Value: now},
+				{Key: "updated_at", Value: now},
+			}}},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		group.Status = "completed"
+		return group, nil
+	})
+
+	switch {
+	case errors.Is(err, errGroupNotPending):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group transaction has already been processed"})
+		return
+	case errors.Is(err, errInsufficientBalance), errors.Is(err, errQRCodeUsed):
+		// The settlement rolled back, and it would fail the same way on every retry, so the
+		// group is cancelled rather than left with approved shares that can never complete
+		reason := "A participant can no longer cover their share"
+		_, cancelErr := session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			return nil, cancelGroupTransaction(sessCtx, client, transaction.GroupTransactionID, "", reason)
+		})
+		if cancelErr != nil && !errors.Is(cancelErr, errGroupNotPending) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel group transaction"})
+			return
+		}
+		if cancelErr == nil {
+			notifyGroupCancelled(ctx, client, transaction.GroupTransactionID, reason)
+		}
+
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                "A participant can no longer cover their share. The group transaction has been cancelled.",
+			"details":              err.Error(),
+			"group_transaction_id": transaction.GroupTransactionID,
+			"failed_employee_id":   failedShare.EmployeeID,
+			"status":               "cancelled",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process transaction"})
+		return
+	}
+
+	group := result.(models.GroupTransaction)
+	message := "Share approved. Waiting for the other participants."
+	// Coupons only leave the balance once the whole group settles
+	employeeResult := gin.H{"name": employee.Name, "coupons_pending": transaction.CouponsUsed}
+	if group.Status == "completed" {
+		message = "All shares approved. Group transaction processed successfully."
+		employeeResult = gin.H{"name": employee.Name, "coupons_deducted": transaction.CouponsUsed}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":              true,
+		"message":              message,
+		"transaction_id":       transaction.TransactionID,
+		"group_transaction_id": group.GroupTransactionID,
+		"employee":             employeeResult,
+		"group": gin.H{
+			"status":            group.Status,
+			"approved_count":    group.ApprovedCount,
+			"participant_count": group.ParticipantCount,
+		},
+	})
+}
+
+// cancelGroupTransaction cancels a pending group and all of its unsettled shares.
+// When rejectedTransactionID is set that share is marked rejected instead of cancelled.
+// It must be called inside a session transaction.
+func cancelGroupTransaction(ctx context.Context, client *mongo.Client, groupID, rejectedTransactionID, reason string) error {
+	now := time.Now()
+	groupCollection := database.OpenCollection("group_transactions", client)
+	res, err := groupCollection.UpdateOne(
+		ctx,
+		bson.D{
+			{Key: "group_transaction_id", Value: groupID},
+			{Key: "status", Value: "pending"},
+		},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "status", Value: "cancelled"},
+			{Key: "cancel_reason", Value: reason},
+			{Key: "updated_at", Value: now},
+		}}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errGroupNotPending
+	}
+
+	transactionCollection := database.OpenCollection("transactions", client)
+	if rejectedTransactionID != "" {
+		_, err = transactionCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "transaction_id", Value: rejectedTransactionID}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "rejected"},
+				{Key: "notes", Value: reason},
+				{Key: "updated_at", Value: now},
+			}}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = transactionCollection.UpdateMany(
+		ctx,
+		bson.D{
+			{Key: "group_transaction_id", Value: groupID},
+			{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"pending", "approved"}}}},
+		},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "status", Value: "cancelled"},
+			{Key: "notes", Value: reason},
+			{Key: "updated_at", Value: now},
+		}}},
+	)
+	return err
+}
+
+// ExpireGroupTransactions cancels pending groups that not every participant approved in time, so one
+// participant who never answers cannot keep the other shares open. It returns how many it cancelled.
+func ExpireGroupTransactions(ctx context.Context, client *mongo.Client) (int, error) {
+	groupCollection := database.OpenCollection("group_transactions", client)
+	cursor, err := groupCollection.Find(ctx, bson.D{
+		{Key: "status", Value: "pending"},
+		{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: time.Now()}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var groups []models.GroupTransaction
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.EndSession(ctx)
+
+	reason := "Not every participant approved in time"
+	expired := 0
+	for _, group := range groups {
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			return nil, cancelGroupTransaction(sessCtx, client, group.GroupTransactionID, "", reason)
+		})
+		if errors.Is(err, errGroupNotPending) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		notifyGroupCancelled(ctx, client, group.GroupTransactionID, reason)
+		expired++
+	}
+	return expired, nil
+}
+
+// notifyGroupCancelled tells every participant and the supplier that a group was cancelled
+// without waiting for them to act on it
+func notifyGroupCancelled(ctx context.Context, client *mongo.Client, groupID, reason string) {
+	groupCollection := database.OpenCollection("group_transactions", client)
+	var group models.GroupTransaction
+	if err := groupCollection.FindOne(ctx, bson.D{{Key: "group_transaction_id", Value: groupID}}).Decode(&group); err != nil {
+		return
+	}
+
+	transactionCollection := database.OpenCollection("transactions", client)
+	cursor, err := transactionCollection.Find(ctx, bson.D{{Key: "group_transaction_id", Value: groupID}})
+	if err != nil {
+		return
+	}
+	var shares []models.Transaction
+	if err := cursor.All(ctx, &shares); err != nil {
+		return
+	}
+
+	employeeIDs := bson.A{}
+	for _, share := range shares {
+		employeeIDs = append(employeeIDs, share.EmployeeID)
+	}
+	employeeCollection := database.OpenCollection("employees", client)
+	cursor, err = employeeCollection.Find(ctx, bson.D{{Key: "employee_id", Value: bson.D{{Key: "$in", Value: employeeIDs}}}})
+	if err != nil {
+		return
+	}
+	var employees []models.Employee
+	if err := cursor.All(ctx, &employees); err != nil {
+		return
+	}
+
+	message := fmt.Sprintf("The group meal was cancelled and no coupons were charged: %s.", reason)
+	for _, employee := range employees {
+		notifyUser(ctx, client, employee.UserID, "group_transaction_cancelled", "Group meal cancelled", message, groupID)
+	}
+
+	supplierCollection := database.OpenCollection("suppliers", client)
+	var supplier models.Supplier
+	if err := supplierCollection.FindOne(ctx, bson.D{{Key: "supplier_id", Value: group.SupplierID}}).Decode(&supplier); err == nil {
+		notifyUser(ctx, client, supplier.UserID, "group_transaction_cancelled", "Group meal cancelled", message, groupID)
+	}
+}
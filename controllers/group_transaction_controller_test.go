@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// seedGroup stores a pending group of two employees' shares that expires at expiresAt.
+// It returns the group, its shares and the user IDs of the participants, in share order.
+func seedGroup(t *testing.T, client *mongo.Client, expiresAt time.Time) (models.GroupTransaction, []models.Transaction, []string) {
+	t.Helper()
+	now := time.Now()
+	group := models.GroupTransaction{
+		GroupTransactionID: bson.NewObjectID().Hex(),
+		SupplierID:         "supplier",
+		ParticipantCount:   2,
+		TotalCoupons:       3,
+		Status:             "pending",
+		ExpiresAt:          expiresAt,
+		CreatedAt:          now,
+	}
+
+	var shares []models.Transaction
+	var userIDs []string
+	for _, coupons := range []int{1, 2} {
+		userID := bson.NewObjectID().Hex()
+		employeeID := bson.NewObjectID().Hex()
+		insertDocuments(t, client, "employees", models.Employee{
+			EmployeeID:     employeeID,
+			UserID:         userID,
+			EmployeeCode:   "E-" + employeeID,
+			Status:         "active",
+			CurrentBalance: 10,
+		})
+		share := models.Transaction{
+			TransactionID:      bson.NewObjectID().Hex(),
+			EmployeeID:         employeeID,
+			SupplierID:         group.SupplierID,
+			GroupTransactionID: group.GroupTransactionID,
+			CouponsUsed:        coupons,
+			TotalAmount:        float64(coupons) * 45,
+			Status:             "pending",
+			CreatedAt:          now,
+		}
+		group.TransactionIDs = append(group.TransactionIDs, share.TransactionID)
+		shares = append(shares, share)
+		userIDs = append(userIDs, userID)
+		insertDocuments(t, client, "transactions", share)
+	}
+	insertDocuments(t, client, "group_transactions", group)
+	return group, shares, userIDs
+}
+
+// shareStatuses returns the status of every share of a group, keyed by transaction ID
+func shareStatuses(t *testing.T, client *mongo.Client, groupID string) map[string]string {
+	t.Helper()
+	statuses := map[string]string{}
+	var group models.GroupTransaction
+	findDocument(t, client, "group_transactions", bson.D{{Key: "group_transaction_id", Value: groupID}}, &group)
+	statuses[groupID] = group.Status
+	for _, transactionID := range group.TransactionIDs {
+		var share models.Transaction
+		findDocument(t, client, "transactions", bson.D{{Key: "transaction_id", Value: transactionID}}, &share)
+		statuses[transactionID] = share.Status
+	}
+	return statuses
+}
+
+func TestApproveTransactionRequestBinding(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "approved", body: `{"transaction_id": "t-1", "approved": true}`},
+		{name: "rejected", body: `{"transaction_id": "t-1", "approved": false, "reason": "Not my order"}`},
+		{name: "approved missing", body: `{"transaction_id": "t-1"}`, wantErr: true},
+		{name: "approved not a boolean", body: `{"transaction_id": "t-1", "approved": "no"}`, wantErr: true},
+		{name: "transaction missing", body: `{"approved": false}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.ApproveTransactionRequest
+			err := binding.JSON.BindBody([]byte(tt.body), &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				// The handler refuses it while binding, before the database is used
+				w := serve(t, ApproveTransaction(nil), http.MethodPost, "/transactions/approve", "/transactions/approve", "user", "EMPLOYEE", json.RawMessage(tt.body))
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRejectGroupShare(t *testing.T) {
+	client := testClient(t)
+	group, shares, userIDs := seedGroup(t, client, time.Now().Add(time.Hour))
+
+	w := serve(t, ApproveTransaction(client), http.MethodPost, "/transactions/approve", "/transactions/approve", userIDs[1], "EMPLOYEE",
+		gin.H{"transaction_id": shares[1].TransactionID, "approved": false, "reason": "Not my order"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	statuses := shareStatuses(t, client, group.GroupTransactionID)
+	want := map[string]string{
+		group.GroupTransactionID: "cancelled",
+		shares[0].TransactionID:  "cancelled",
+		shares[1].TransactionID:  "rejected",
+	}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("status of %s = %q, want %q", id, statuses[id], status)
+		}
+	}
+
+	// The other participant can no longer approve the cancelled group
+	w = serve(t, ApproveTransaction(client), http.MethodPost, "/transactions/approve", "/transactions/approve", userIDs[0], "EMPLOYEE",
+		gin.H{"transaction_id": shares[0].TransactionID, "approved": true})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("approving a share of a cancelled group: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestExpireGroupTransactions(t *testing.T) {
+	client := testClient(t)
+	expired, expiredShares, userIDs := seedGroup(t, client, time.Now().Add(-time.Minute))
+	open, openShares, _ := seedGroup(t, client, time.Now().Add(time.Hour))
+
+	// A participant approving after the deadline does not keep the group alive
+	w := serve(t, ApproveTransaction(client), http.MethodPost, "/transactions/approve", "/transactions/approve", userIDs[0], "EMPLOYEE",
+		gin.H{"transaction_id": expiredShares[0].TransactionID, "approved": true})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("approving a share of an expired group: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	count, err := ExpireGroupTransactions(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("ExpireGroupTransactions() = %d, want 1", count)
+	}
+
+	statuses := shareStatuses(t, client, expired.GroupTransactionID)
+	for _, id := range append([]string{expired.GroupTransactionID}, expired.TransactionIDs...) {
+		if statuses[id] != "cancelled" {
+			t.Errorf("status of expired %s = %q, want cancelled", id, statuses[id])
+		}
+	}
+	statuses = shareStatuses(t, client, open.GroupTransactionID)
+	for _, id := range []string{open.GroupTransactionID, openShares[0].TransactionID, openShares[1].TransactionID} {
+		if statuses[id] != "pending" {
+			t.Errorf("status of open %s = %q, want pending", id, statuses[id])
+		}
+	}
+	if n := countDocuments(t, client, "notifications", bson.D{{Key: "reference_id", Value: expired.GroupTransactionID}}); n != 2 {
+		t.Errorf("%d cancellation notification(s), want one per participant", n)
+	}
+}
@@ -0,0 +1,461 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	qrcode "github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var errVoucherUnavailable = errors.New("voucher is no longer valid")
+
+// CreateGuestVoucher - Admin issues a single-use or N-use meal voucher for a guest
+func CreateGuestVoucher(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateGuestVoucherRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		expiryDate, err := time.ParseInLocation("2006-01-02", req.ExpiresAt, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expiry date format. Use YYYY-MM-DD"})
+			return
+		}
+		expiresAt := expiryDate.AddDate(0, 0, 1).Add(-time.Second)
+		if expiresAt.Before(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry date must be in the future"})
+			return
+		}
+
+		maxUses := req.MaxUses
+		if maxUses == 0 {
+			maxUses = 1
+		}
+		couponsPerUse := req.CouponsPerUse
+		if couponsPerUse == 0 {
+			couponsPerUse = 1
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var host models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: req.HostEmployeeID}}).Decode(&host)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Host employee not found"})
+			return
+		}
+		if host.Status != "active" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Host employee must be active"})
+			return
+		}
+
+		now := time.Now()
+		voucher := models.GuestVoucher{
+			VoucherID:       bson.NewObjectID().Hex(),
+			Code:            uuid.New().String(),
+			GuestName:       req.GuestName,
+			GuestEmail:      req.GuestEmail,
+			GuestType:       req.GuestType,
+			HostEmployeeID:  host.EmployeeID,
+			CostCenter:      req.CostCenter,
+			MaxUses:         maxUses,
+			UsesRemaining:   maxUses,
+			CouponsPerUse:   couponsPerUse,
+			ExpiresAt:       expiresAt,
+			Status:          "active",
+			Notes:           req.Notes,
+			IssuedByAdminID: adminUserID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+
+		voucherCollection := database.OpenCollection("guest_vouchers", client)
+		if _, err = voucherCollection.InsertOne(ctx, voucher); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create voucher"})
+			return
+		}
+
+		qrImage, err := guestVoucherQRImage(voucher.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR image"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, models.GuestVoucherResponse{
+			GuestVoucher: voucher,
+			QRCodeImage:  qrImage,
+		})
+	}
+}
+
+// GetGuestVouchers - Admin lists guest vouchers
+func GetGuestVouchers(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		filter := bson.D{}
+		if status := c.Query("status"); status != "" {
+			filter = append(filter, bson.E{Key: "status", Value: status})
+		}
+		if hostID := c.Query("host_employee_id"); hostID != "" {
+			filter = append(filter, bson.E{Key: "host_employee_id", Value: hostID})
+		}
+
+		voucherCollection := database.OpenCollection("guest_vouchers", client)
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		cursor, err := voucherCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vouchers"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var vouchers []models.GuestVoucher
+		if err = cursor.All(ctx, &vouchers); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode vouchers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"vouchers": vouchers,
+			"total":    len(vouchers),
+		})
+	}
+}
+
+// GetGuestVoucherByID - Admin views a voucher with its QR code
+func GetGuestVoucherByID(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		voucherCollection := database.OpenCollection("guest_vouchers", client)
+		var voucher models.GuestVoucher
+		err := voucherCollection.FindOne(ctx, bson.D{{Key: "voucher_id", Value: c.Param("id")}}).Decode(&voucher)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Voucher not found"})
+			return
+		}
+
+		qrImage, err := guestVoucherQRImage(voucher.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR image"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.GuestVoucherResponse{
+			GuestVoucher: voucher,
+			QRCodeImage:  qrImage,
+		})
+	}
+}
+
+// RevokeGuestVoucher - Admin revokes a voucher before it is used up
+func RevokeGuestVoucher(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		voucherCollection := database.OpenCollection("guest_vouchers", client)
+		result, err := voucherCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "voucher_id", Value: c.Param("id")},
+				{Key: "status", Value: "active"},
+			},
+			bson.D{{Key: "$set", Value: bson.M{
+				"status":     "revoked",
+				"updated_at": time.Now(),
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke voucher"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Active voucher not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Voucher revoked successfully"})
+	}
+}
+
+// GetGuestUsageReport - Admin reports guest meals separately from employee usage
+func GetGuestUsageReport(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		match := bson.D{
+			{Key: "guest_voucher_id", Value: bson.D{{Key: "$exists", Value: true}}},
+			{Key: "status", Value: "completed"},
+		}
+
+		processedAt, err := dateRangeQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(processedAt) > 0 {
+			match = append(match, bson.E{Key: "processed_at", Value: processedAt})
+		}
+
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "guest_vouchers"},
+				{Key: "localField", Value: "guest_voucher_id"},
+				{Key: "foreignField", Value: "voucher_id"},
+				{Key: "as", Value: "voucher"},
+			}}},
+			{{Key: "$unwind", Value: "$voucher"}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{
+					{Key: "host_employee_id", Value: "$voucher.host_employee_id"},
+					{Key: "cost_center", Value: "$voucher.cost_center"},
+					{Key: "guest_type", Value: "$voucher.guest_type"},
+				}},
+				{Key: "transactions", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "total_coupons", Value: bson.D{{Key: "$sum", Value: "$coupons_used"}}},
+				{Key: "total_amount", Value: bson.D{{Key: "$sum", Value: "$total_amount"}}},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "host_employee_id", Value: "$_id.host_employee_id"},
+				{Key: "cost_center", Value: "$_id.cost_center"},
+				{Key: "guest_type", Value: "$_id.guest_type"},
+				{Key: "transactions", Value: 1},
+				{Key: "total_coupons", Value: 1},
+				{Key: "total_amount", Value: 1},
+			}}},
+			{{Key: "$sort", Value: bson.D{{Key: "total_amount", Value: -1}}}},
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		transactionCollection := database.OpenCollection("transactions", client)
+		cursor, err := transactionCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build guest report"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var rows []models.GuestUsageReportRow
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode guest report"})
+			return
+		}
+
+		totalCoupons := 0
+		totalAmount := 0.0
+		for _, row := range rows {
+			totalCoupons += row.TotalCoupons
+			totalAmount += row.TotalAmount
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rows":          rows,
+			"total_coupons": totalCoupons,
+			"total_amount":  totalAmount,
+		})
+	}
+}
+
+// findGuestVoucher looks up a voucher by the scanned code
+func findGuestVoucher(ctx context.Context, client *mongo.Client, code string) (models.GuestVoucher, error) {
+	var voucher models.GuestVoucher
+	voucherCollection := database.OpenCollection("guest_vouchers", client)
+	err := voucherCollection.FindOne(ctx, bson.D{{Key: "code", Value: strings.TrimPrefix(code, "VOUCHER-")}}).Decode(&voucher)
+	return voucher, err
+}
+
+// redeemGuestVoucher completes a supplier checkout paid with a guest voucher.
+// Guests have no app to approve in, so the transaction completes immediately.
+func redeemGuestVoucher(c *gin.Context, ctx context.Context, client *mongo.Client, supplier models.Supplier, voucher models.GuestVoucher, req models.InitiateTransactionRequest) {
+	now := time.Now()
+	if voucher.Status != "active" || voucher.UsesRemaining <= 0 || now.After(voucher.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "Guest voucher is no longer valid",
+			"status":     voucher.Status,
+			"expires_at": voucher.ExpiresAt,
+		})
+		return
+	}
+
+	var orderLines []models.OrderLine
+	if len(req.Items) > 0 {
+		lines, coupons, price, err := resolveOrderLines(ctx, client, supplier.SupplierID, req.Items, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order", "details": err.Error()})
+			return
+		}
+		orderLines = lines
+		req.CouponsUsed = coupons
+		if req.MealPrice == 0 {
+			req.MealPrice = price
+		}
+	}
+	if req.CouponsUsed == 0 {
+		req.CouponsUsed = voucher.CouponsPerUse
+	}
+	if req.CouponsUsed < 1 || req.CouponsUsed > voucher.CouponsPerUse {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           "Invalid coupon amount",
+			"coupons_per_use": voucher.CouponsPerUse,
+		})
+		return
+	}
+
+	couponValue := 45.0
+	totalAmount := float64(req.CouponsUsed) * couponValue
+	mealPrice, otherTenderAmount, otherTenderMethod, err := splitMealPrice(totalAmount, req.MealPrice, req.OtherTenderMethod)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction := models.Transaction{
+		TransactionID:     uuid.New().String(),
+		SupplierID:        supplier.SupplierID,
+		GuestVoucherID:    voucher.VoucherID,
+		CouponsUsed:       req.CouponsUsed,
+		Items:             orderLines,
+		TotalAmount:       totalAmount,
+		MealPrice:         mealPrice,
+		OtherTenderAmount: otherTenderAmount,
+		OtherTenderMethod: otherTenderMethod,
+		Status:            "completed",
+		ApprovalMethod:    "voucher",
+		Notes:             req.Notes,
+		ProcessedAt:       now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+		return
+	}
+	defer session.EndSession(ctx)
+
+	result, err := session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		voucherCollection := database.OpenCollection("guest_vouchers", client)
+		var updated models.GuestVoucher
+		err := voucherCollection.FindOneAndUpdate(
+			sessCtx,
+			bson.D{
+				{Key: "voucher_id", Value: voucher.VoucherID},
+				{Key: "status", Value: "active"},
+				{Key: "uses_remaining", Value: bson.D{{Key: "$gt", Value: 0}}},
+				{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+			},
+			bson.D{
+				{Key: "$inc", Value: bson.D{{Key: "uses_remaining", Value: -1}}},
+				{Key: "$set", Value: bson.D{
+					{Key: "last_used_at", Value: now},
+					{Key: "updated_at", Value: now},
+				}},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errVoucherUnavailable
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if updated.UsesRemaining == 0 {
+			_, err = voucherCollection.UpdateOne(
+				sessCtx,
+				bson.D{{Key: "voucher_id", Value: voucher.VoucherID}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: "exhausted"}}}},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		transactionCollection := database.OpenCollection("transactions", client)
+		if _, err = transactionCollection.InsertOne(sessCtx, transaction); err != nil {
+			return nil, err
+		}
+		return updated, nil
+	})
+	if errors.Is(err, errVoucherUnavailable) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Guest voucher is no longer valid"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem voucher"})
+		return
+	}
+	updated := result.(models.GuestVoucher)
+
+	// Let the host know their guest has eaten
+	employeeCollection := database.OpenCollection("employees", client)
+	var host models.Employee
+	if err := employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: voucher.HostEmployeeID}}).Decode(&host); err == nil {
+		message := fmt.Sprintf("Your guest %s used a meal voucher at %s.", voucher.GuestName, supplier.BusinessName)
+		notifyUser(ctx, client, host.UserID, "guest_voucher_redeemed", "Guest voucher used", message, transaction.TransactionID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":        true,
+		"message":        "Guest voucher redeemed successfully",
+		"transaction_id": transaction.TransactionID,
+		"guest": gin.H{
+			"name":           voucher.GuestName,
+			"type":           voucher.GuestType,
+			"uses_remaining": updated.UsesRemaining,
+		},
+		"supplier": gin.H{
+			"name":    supplier.BusinessName,
+			"address": supplier.Address,
+		},
+		"transaction": gin.H{
+			"coupons_used":        transaction.CouponsUsed,
+			"items":               orderLines,
+			"total_amount":        totalAmount,
+			"meal_price":          mealPrice,
+			"other_tender_amount": otherTenderAmount,
+			"other_tender_method": otherTenderMethod,
+			"status":              "completed",
+			"approval_method":     "voucher",
+		},
+		"requires_approval": false,
+	})
+}
+
+func guestVoucherQRImage(code string) (string, error) {
+	qrImage, err := qrcode.Encode("VOUCHER-"+code, qrcode.Medium, 256)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrImage), nil
+}
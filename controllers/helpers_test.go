@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// testClient connects to the MongoDB named by TEST_MONGO_URI and points DATABASE_NAME at a fresh
+// database that is dropped when the test ends. The handlers use transactions, so it must be a
+// replica set, e.g. a single node started with mongod --replSet rs0. Tests that need it are
+// skipped when TEST_MONGO_URI is unset.
+func testClient(t *testing.T) *mongo.Client {
+	t.Helper()
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("coupon_meal_test_%d", time.Now().UnixNano())
+	t.Setenv("DATABASE_NAME", name)
+	t.Cleanup(func() {
+		client.Database(name).Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return client
+}
+
+// insertDocuments seeds a collection of the test database
+func insertDocuments(t *testing.T, client *mongo.Client, collection string, documents ...any) {
+	t.Helper()
+	if _, err := database.OpenCollection(collection, client).InsertMany(context.Background(), documents); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// findDocument decodes the one document of a collection matching the filter
+func findDocument(t *testing.T, client *mongo.Client, collection string, filter bson.D, out any) {
+	t.Helper()
+	if err := database.OpenCollection(collection, client).FindOne(context.Background(), filter).Decode(out); err != nil {
+		t.Fatalf("find in %s %v: %v", collection, filter, err)
+	}
+}
+
+// countDocuments counts the documents of a collection matching the filter
+func countDocuments(t *testing.T, client *mongo.Client, collection string, filter bson.D) int64 {
+	t.Helper()
+	count, err := database.OpenCollection(collection, client).CountDocuments(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// serve runs a handler on route for one request, signed in as userID with role the way
+// AuthMiddleware would, and returns the recorded response
+func serve(t *testing.T, handler gin.HandlerFunc, method, route, target, userID, role string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		c.Set("userId", userID)
+		c.Set("role", role)
+		c.Next()
+	}, handler)
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
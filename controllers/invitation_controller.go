@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var errInvitationInvalid = errors.New("invitation is invalid or has expired")
+
+// newInvitedUser builds a user account whose password is random and unknown to anyone,
+// so it cannot be signed in to until the invitation is accepted
+func newInvitedUser(firstName, lastName, email, role string, now time.Time) (models.User, error) {
+	hashedPassword, err := HashPassword(uuid.New().String())
+	if err != nil {
+		return models.User{}, err
+	}
+	return models.User{
+		UserID:    bson.NewObjectID().Hex(),
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+		Password:  hashedPassword,
+		Role:      role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// issueInvitation replaces any open invitation of the user with a new one and returns its token.
+// Only the token's hash is stored, so the token has to be handed to the user from the response.
+func issueInvitation(ctx context.Context, client *mongo.Client, user models.User, createdByUserID string, now time.Time) (string, models.Invitation, error) {
+	invitationCollection := database.OpenCollection("invitations", client)
+	_, err := invitationCollection.UpdateMany(
+		ctx,
+		bson.D{
+			{Key: "user_id", Value: user.UserID},
+			{Key: "accepted_at", Value: nil},
+			{Key: "revoked_at", Value: nil},
+		},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: now}}}},
+	)
+	if err != nil {
+		return "", models.Invitation{}, err
+	}
+
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", models.Invitation{}, err
+	}
+	invitation := models.Invitation{
+		InvitationID:    bson.NewObjectID().Hex(),
+		UserID:          user.UserID,
+		Email:           user.Email,
+		TokenHash:       utils.HashSecureToken(token),
+		ExpiresAt:       now.Add(time.Duration(utils.GetEnvAsInt("INVITATION_EXPIRY_HOURS", 72)) * time.Hour),
+		CreatedByUserID: createdByUserID,
+		CreatedAt:       now,
+	}
+	if _, err := invitationCollection.InsertOne(ctx, invitation); err != nil {
+		return "", models.Invitation{}, err
+	}
+	return token, invitation, nil
+}
+
+// findOpenInvitation looks up an invitation by its token, returning errInvitationInvalid
+// unless it is neither accepted, replaced nor expired
+func findOpenInvitation(ctx context.Context, client *mongo.Client, token string, now time.Time) (models.Invitation, error) {
+	invitationCollection := database.OpenCollection("invitations", client)
+	var invitation models.Invitation
+	err := invitationCollection.FindOne(ctx, bson.D{{Key: "token_hash", Value: utils.HashSecureToken(token)}}).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		return invitation, errInvitationInvalid
+	}
+	if err != nil {
+		return invitation, err
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || !now.Before(invitation.ExpiresAt) {
+		return invitation, errInvitationInvalid
+	}
+	return invitation, nil
+}
+
+// ResendInvitation - Admin issues a new invitation for a user, e.g. after the first one expired.
+// Any earlier open invitation stops working.
+func ResendInvitation(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		userCollection := database.OpenCollection("users", client)
+		var user models.User
+		err = userCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: c.Param("id")}}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if user.DeactivatedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User account is deactivated"})
+			return
+		}
+
+		token, invitation, err := issueInvitation(ctx, client, user, adminUserID, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":               "Invitation created successfully",
+			"user_id":               user.UserID,
+			"invitation_token":      token,
+			"invitation_expires_at": invitation.ExpiresAt,
+		})
+	}
+}
+
+// GetInvitation - Anyone holding an invitation token checks it before choosing a password
+func GetInvitation(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		invitation, err := findOpenInvitation(ctx, client, c.Param("token"), time.Now())
+		if errors.Is(err, errInvitationInvalid) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation is invalid or has expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+			return
+		}
+
+		userCollection := database.OpenCollection("users", client)
+		var user models.User
+		err = userCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: invitation.UserID}}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation is invalid or has expired"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"email":      user.Email,
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"role":       user.Role,
+			"expires_at": invitation.ExpiresAt,
+		})
+	}
+}
+
+// AcceptInvitation - The invited user sets their password with the invitation token
+func AcceptInvitation(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AcceptInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		hashedPassword, err := HashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to hash password"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		invitationCollection := database.OpenCollection("invitations", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			now := time.Now()
+			invitation, err := findOpenInvitation(sessCtx, client, req.Token, now)
+			if err != nil {
+				return nil, err
+			}
+
+			// Claim the invitation so the token works once, even for concurrent requests
+			result, err := invitationCollection.UpdateOne(
+				sessCtx,
+				bson.D{
+					{Key: "invitation_id", Value: invitation.InvitationID},
+					{Key: "accepted_at", Value: nil},
+				},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "accepted_at", Value: now}}}},
+			)
+			if err != nil {
+				return nil, err
+			}
+			if result.MatchedCount == 0 {
+				return nil, errInvitationInvalid
+			}
+
+			if err := setUserPassword(sessCtx, client, invitation.UserID, hashedPassword, now); err != nil {
+				if err == mongo.ErrNoDocuments {
+					return nil, errInvitationInvalid
+				}
+				return nil, err
+			}
+			return nil, recordAudit(sessCtx, client, invitation.UserID, "invitation.accepted", "user", invitation.UserID, bson.M{
+				"invitation_id": invitation.InvitationID,
+			})
+		})
+		if errors.Is(err, errInvitationInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is invalid or has expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password set successfully. You can now log in"})
+	}
+}
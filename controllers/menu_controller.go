@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CreateMenuItem - Supplier adds an item to their menu
+func CreateMenuItem(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateMenuItemRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if err := validateMealWindows(req.MealWindows); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validateMenuItemPricing(req.Price, req.CouponCost); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		now := time.Now()
+		item := models.MenuItem{
+			MenuItemID:  bson.NewObjectID().Hex(),
+			SupplierID:  supplier.SupplierID,
+			Name:        req.Name,
+			Description: req.Description,
+			Category:    req.Category,
+			Price:       req.Price,
+			CouponCost:  req.CouponCost,
+			IsAvailable: true,
+			MealWindows: req.MealWindows,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		menuCollection := database.OpenCollection("menu_items", client)
+		if _, err = menuCollection.InsertOne(ctx, item); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create menu item"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":   "Menu item created successfully",
+			"menu_item": item,
+		})
+	}
+}
+
+// GetMyMenu - Supplier lists their own menu, including unavailable items
+func GetMyMenu(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		items, err := findMenuItems(ctx, client, bson.D{{Key: "supplier_id", Value: supplier.SupplierID}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch menu"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"menu_items": items,
+			"total":      len(items),
+		})
+	}
+}
+
+// GetSupplierMenu - Employee browses the items a supplier currently serves
+func GetSupplierMenu(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		items, err := findMenuItems(ctx, client, bson.D{
+			{Key: "supplier_id", Value: c.Param("id")},
+			{Key: "is_available", Value: true},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch menu"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"menu_items": items,
+			"total":      len(items),
+		})
+	}
+}
+
+// UpdateMenuItem - Supplier edits an item or toggles availability
+func UpdateMenuItem(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateMenuItemRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		updateData := bson.M{"updated_at": time.Now()}
+		if req.Name != "" {
+			updateData["name"] = req.Name
+		}
+		if req.Description != "" {
+			updateData["description"] = req.Description
+		}
+		if req.Category != "" {
+			updateData["category"] = req.Category
+		}
+		if req.Price > 0 {
+			updateData["price"] = req.Price
+		}
+		if req.CouponCost != nil {
+			updateData["coupon_cost"] = *req.CouponCost
+		}
+		if req.IsAvailable != nil {
+			updateData["is_available"] = *req.IsAvailable
+		}
+		if req.MealWindows != nil {
+			if err := validateMealWindows(*req.MealWindows); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateData["meal_windows"] = *req.MealWindows
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		menuCollection := database.OpenCollection("menu_items", client)
+		filter := bson.D{
+			{Key: "menu_item_id", Value: c.Param("id")},
+			{Key: "supplier_id", Value: supplier.SupplierID},
+		}
+
+		// A price or coupon cost change is checked against the other value as it is stored
+		if req.Price > 0 || req.CouponCost != nil {
+			var item models.MenuItem
+			if err := menuCollection.FindOne(ctx, filter).Decode(&item); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+			if req.Price > 0 {
+				item.Price = req.Price
+			}
+			if req.CouponCost != nil {
+				item.CouponCost = *req.CouponCost
+			}
+			if err := validateMenuItemPricing(item.Price, item.CouponCost); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		result, err := menuCollection.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: updateData}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update menu item"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Menu item updated successfully",
+			"updated": result.ModifiedCount,
+		})
+	}
+}
+
+// DeleteMenuItem - Supplier removes an item from their menu
+func DeleteMenuItem(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		menuCollection := database.OpenCollection("menu_items", client)
+		result, err := menuCollection.DeleteOne(ctx, bson.D{
+			{Key: "menu_item_id", Value: c.Param("id")},
+			{Key: "supplier_id", Value: supplier.SupplierID},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete menu item"})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Menu item deleted successfully"})
+	}
+}
+
+// GetMyTopMenuItems - Supplier sees their most-ordered items
+func GetMyTopMenuItems(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		respondTopMenuItems(c, ctx, client, supplier.SupplierID)
+	}
+}
+
+// GetSupplierTopMenuItems - Admin sees the most-ordered items of any supplier
+func GetSupplierTopMenuItems(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		respondTopMenuItems(c, ctx, client, c.Param("id"))
+	}
+}
+
+func respondTopMenuItems(c *gin.Context, ctx context.Context, client *mongo.Client, supplierID string) {
+	match := bson.D{
+		{Key: "supplier_id", Value: supplierID},
+		{Key: "status", Value: "completed"},
+	}
+
+	processedAt := bson.D{}
+	if from := c.Query("from"); from != "" {
+		fromDate, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+			return
+		}
+		processedAt = append(processedAt, bson.E{Key: "$gte", Value: fromDate})
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+			return
+		}
+		processedAt = append(processedAt, bson.E{Key: "$lt", Value: toDate.AddDate(0, 0, 1)})
+	}
+	if len(processedAt) > 0 {
+		match = append(match, bson.E{Key: "processed_at", Value: processedAt})
+	}
+
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if n, err := strconv.Atoi(limitStr); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$items.menu_item_id"},
+			{Key: "name", Value: bson.D{{Key: "$last", Value: "$items.name"}}},
+			{Key: "quantity_sold", Value: bson.D{{Key: "$sum", Value: "$items.quantity"}}},
+			{Key: "order_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "revenue", Value: bson.D{{Key: "$sum", Value: "$items.line_total"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "quantity_sold", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	transactionCollection := database.OpenCollection("transactions", client)
+	cursor, err := transactionCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate menu items"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var topItems []models.TopMenuItem
+	if err = cursor.All(ctx, &topItems); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode menu items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"supplier_id": supplierID,
+		"top_items":   topItems,
+		"total":       len(topItems),
+	})
+}
+
+// resolveOrderLines prices the requested items from the supplier's menu.
+// It returns the order lines with the coupon charge and meal price derived from them.
+func resolveOrderLines(ctx context.Context, client *mongo.Client, supplierID string, requested []models.OrderLineRequest, at time.Time) ([]models.OrderLine, int, float64, error) {
+	menuCollection := database.OpenCollection("menu_items", client)
+
+	var lines []models.OrderLine
+	coupons := 0
+	price := 0.0
+	for _, line := range requested {
+		var item models.MenuItem
+		err := menuCollection.FindOne(ctx, bson.D{
+			{Key: "menu_item_id", Value: line.MenuItemID},
+			{Key: "supplier_id", Value: supplierID},
+		}).Decode(&item)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("menu item %s not found", line.MenuItemID)
+		}
+		if !item.IsAvailable {
+			return nil, 0, 0, fmt.Errorf("%s is not available", item.Name)
+		}
+		if len(item.MealWindows) > 0 {
+			served := false
+			for _, window := range item.MealWindows {
+				if utils.WithinTimeWindow(window.Start, window.End, at) {
+					served = true
+					break
+				}
+			}
+			if !served {
+				return nil, 0, 0, fmt.Errorf("%s is not served at this time", item.Name)
+			}
+		}
+
+		lineTotal := item.Price * float64(line.Quantity)
+		lines = append(lines, models.OrderLine{
+			MenuItemID: item.MenuItemID,
+			Name:       item.Name,
+			Quantity:   line.Quantity,
+			UnitPrice:  item.Price,
+			CouponCost: item.CouponCost,
+			LineTotal:  lineTotal,
+		})
+		coupons += item.CouponCost * line.Quantity
+		price += lineTotal
+	}
+	return lines, coupons, price, nil
+}
+
+// validateMenuItemPricing keeps an item's price at or above the value of the coupons it costs.
+// Order totals are the sum of item prices, and coupons are charged at their full value, so a
+// cheaper item would make every order containing it fail at the terminal.
+func validateMenuItemPricing(price float64, couponCost int) error {
+	couponValue := 45.0
+	if minimum := float64(couponCost) * couponValue; price < minimum {
+		return fmt.Errorf("price must be at least %.2f for an item costing %d coupon(s)", minimum, couponCost)
+	}
+	return nil
+}
+
+func findMenuItems(ctx context.Context, client *mongo.Client, filter bson.D) ([]models.MenuItem, error) {
+	menuCollection := database.OpenCollection("menu_items", client)
+	cursor, err := menuCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.MenuItem
+	if err = cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// findSupplierForUser loads the supplier profile of the calling user.
+// It writes the error response itself, so callers just return on error.
+func findSupplierForUser(c *gin.Context, ctx context.Context, client *mongo.Client) (models.Supplier, error) {
+	var supplier models.Supplier
+
+	userID, err := utils.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return supplier, err
+	}
+
+	supplierCollection := database.OpenCollection("suppliers", client)
+	err = supplierCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&supplier)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Supplier profile not found"})
+		return supplier, err
+	}
+	return supplier, nil
+}
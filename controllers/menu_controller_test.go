@@ -0,0 +1,28 @@
+package controllers
+
+import "testing"
+
+func TestValidateMenuItemPricing(t *testing.T) {
+	tests := []struct {
+		name       string
+		price      float64
+		couponCost int
+		wantErr    bool
+	}{
+		{name: "cash only item", price: 12.5, couponCost: 0},
+		{name: "priced at the coupon value", price: 45, couponCost: 1},
+		{name: "priced above the coupon value", price: 60, couponCost: 1},
+		{name: "cheaper than one coupon", price: 30, couponCost: 1, wantErr: true},
+		{name: "cheaper than two coupons", price: 89.99, couponCost: 2, wantErr: true},
+		{name: "three coupons", price: 135, couponCost: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMenuItemPricing(tt.price, tt.couponCost)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMenuItemPricing(%v, %d) error = %v, wantErr %v", tt.price, tt.couponCost, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,757 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	mfaMaxAttempts       = 5
+	mfaRecoveryCodeCount = 10
+)
+
+var (
+	errMFACodeInvalid          = errors.New("verification code is invalid")
+	errMFALocked               = errors.New("mfa is locked after too many wrong codes")
+	errMFAEnrollmentChanged    = errors.New("mfa enrollment changed")
+	mfaAuthenticationMethods   = []string{"pwd", "otp", "mfa"}
+	passwordOnlyAuthentication = []string{"pwd"}
+	mfaLockedResponse          = gin.H{"error": "Too many wrong verification codes. Ask an administrator to unlock your account"}
+)
+
+// createMFAChallenge hands out the token that lets a user finish logging in with a second factor.
+// Only the token's hash is stored.
+func createMFAChallenge(ctx context.Context, client *mongo.Client, c *gin.Context, userID string, now time.Time) (string, models.MFAChallenge, error) {
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", models.MFAChallenge{}, err
+	}
+	challenge := models.MFAChallenge{
+		ChallengeID: bson.NewObjectID().Hex(),
+		UserID:      userID,
+		TokenHash:   utils.HashSecureToken(token),
+		IPAddress:   c.ClientIP(),
+		ExpiresAt:   now.Add(time.Duration(utils.GetEnvAsInt("MFA_CHALLENGE_MINUTES", 5)) * time.Minute),
+		CreatedAt:   now,
+	}
+	challengeCollection := database.OpenCollection("mfa_challenges", client)
+	if _, err := challengeCollection.InsertOne(ctx, challenge); err != nil {
+		return "", models.MFAChallenge{}, err
+	}
+	return token, challenge, nil
+}
+
+// verifyMFACode accepts either a TOTP code or a recovery code of a user with MFA enabled and
+// returns which one was used. Both work only once. Wrong codes count against the user across
+// challenges and endpoints; once MFA_MAX_FAILURES is reached the second factor stays locked until
+// an admin unlocks it. With errMFACodeInvalid it returns how many wrong codes are left.
+func verifyMFACode(ctx context.Context, client *mongo.Client, user models.User, code, recoveryCode string, now time.Time) (string, int, error) {
+	if user.MFA.LockedAt != nil {
+		return "", 0, errMFALocked
+	}
+
+	// Accepting a code resets the failure count, and the lock filter stops a code from being
+	// accepted after a concurrent wrong one locked the user
+	userCollection := database.OpenCollection("users", client)
+	filter := bson.D{
+		{Key: "user_id", Value: user.UserID},
+		{Key: "mfa.locked_at", Value: nil},
+	}
+	var method string
+	var result *mongo.UpdateResult
+	var err error
+	switch {
+	case code != "":
+		secret, err := utils.OpenSecret(user.MFA.Secret, user.MFA.SecretEncrypted)
+		if err != nil {
+			return "", 0, err
+		}
+		step, ok := utils.ValidateTOTP(string(secret), code, now)
+		if !ok {
+			return recordMFAFailure(ctx, client, user.UserID, now)
+		}
+
+		// Recording the time step stops the same code from being replayed, even concurrently
+		method = "otp"
+		result, err = userCollection.UpdateOne(
+			ctx,
+			append(filter, bson.E{Key: "mfa.last_used_step", Value: bson.D{{Key: "$lt", Value: step}}}),
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "mfa.last_used_step", Value: step},
+				{Key: "mfa.failed_attempts", Value: 0},
+			}}},
+		)
+		if err != nil {
+			return "", 0, err
+		}
+
+	case recoveryCode != "":
+		hash := utils.HashSecureToken(utils.NormalizeRecoveryCode(recoveryCode))
+		method = "recovery"
+		result, err = userCollection.UpdateOne(
+			ctx,
+			append(filter, bson.E{Key: "mfa.recovery_code_hashes", Value: hash}),
+			bson.D{
+				{Key: "$pull", Value: bson.D{{Key: "mfa.recovery_code_hashes", Value: hash}}},
+				{Key: "$set", Value: bson.D{{Key: "mfa.failed_attempts", Value: 0}}},
+			},
+		)
+		if err != nil {
+			return "", 0, err
+		}
+
+	default:
+		return "", 0, errMFACodeInvalid
+	}
+
+	if result.MatchedCount == 0 {
+		return recordMFAFailure(ctx, client, user.UserID, now)
+	}
+	return method, 0, nil
+}
+
+// recordMFAFailure counts a wrong code with an atomic increment, so concurrent guesses cannot
+// slip past the limit, and locks the second factor when the limit is reached
+func recordMFAFailure(ctx context.Context, client *mongo.Client, userID string, now time.Time) (string, int, error) {
+	userCollection := database.OpenCollection("users", client)
+	var user models.User
+	err := userCollection.FindOneAndUpdate(
+		ctx,
+		bson.D{
+			{Key: "user_id", Value: userID},
+			{Key: "mfa.locked_at", Value: nil},
+		},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "mfa.failed_attempts", Value: 1}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return "", 0, errMFALocked
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	attemptsLeft, locked := mfaLockout(user.MFA.FailedAttempts, utils.GetEnvAsInt("MFA_MAX_FAILURES", 10))
+	if !locked {
+		return "", attemptsLeft, errMFACodeInvalid
+	}
+
+	_, err = userCollection.UpdateOne(
+		ctx,
+		bson.D{{Key: "user_id", Value: userID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "mfa.locked_at", Value: now}}}},
+	)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := revokeUserSessions(ctx, client, userID, "mfa_locked", now); err != nil {
+		return "", 0, err
+	}
+	recordAudit(ctx, client, userID, "mfa.locked", "user", userID, bson.M{"failed_attempts": user.MFA.FailedAttempts})
+	notifyAdmins(ctx, client, "mfa_locked", "MFA locked", user.FirstName+" "+user.LastName+" entered too many wrong verification codes. Their second factor is locked until an admin unlocks it.", userID)
+	return "", 0, errMFALocked
+}
+
+// mfaLockout reports how many wrong codes a user has left, and whether failedAttempts reached the limit
+func mfaLockout(failedAttempts, maxFailures int) (int, bool) {
+	if maxFailures < 1 {
+		maxFailures = 1
+	}
+	if failedAttempts >= maxFailures {
+		return 0, true
+	}
+	return maxFailures - failedAttempts, false
+}
+
+// newRecoveryCodes generates a fresh set of recovery codes and the hashes to store for them
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(mfaRecoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashSecureToken(utils.NormalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// loadMFAUser fetches the signed-in user for the MFA endpoints, writing the error response on failure
+func loadMFAUser(c *gin.Context, ctx context.Context, client *mongo.Client) (models.User, bool) {
+	userID, err := utils.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return models.User{}, false
+	}
+	userCollection := database.OpenCollection("users", client)
+	var user models.User
+	err = userCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func mfaEnabled(user models.User) bool {
+	return user.MFA != nil && user.MFA.EnabledAt != nil
+}
+
+// VerifyMFALogin - Second login step: exchanges the mfa_token from the password step and a TOTP
+// or recovery code for a session
+func VerifyMFALogin(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.VerifyMFALoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if (req.Code == "") == (req.RecoveryCode == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either code or recovery_code"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		invalidChallenge := gin.H{"error": "MFA challenge is invalid or has expired. Please log in again"}
+
+		// Each attempt is counted before the code is checked, so guessing is capped per challenge
+		challengeCollection := database.OpenCollection("mfa_challenges", client)
+		var challenge models.MFAChallenge
+		err := challengeCollection.FindOneAndUpdate(
+			ctx,
+			bson.D{
+				{Key: "token_hash", Value: utils.HashSecureToken(req.MFAToken)},
+				{Key: "used_at", Value: nil},
+				{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+				{Key: "attempts", Value: bson.D{{Key: "$lt", Value: mfaMaxAttempts}}},
+			},
+			bson.D{{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&challenge)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusUnauthorized, invalidChallenge)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+
+		userCollection := database.OpenCollection("users", client)
+		var user models.User
+		err = userCollection.FindOne(ctx, bson.D{
+			{Key: "user_id", Value: challenge.UserID},
+			{Key: "deactivated_at", Value: nil},
+		}).Decode(&user)
+		if err != nil || !mfaEnabled(user) {
+			c.JSON(http.StatusUnauthorized, invalidChallenge)
+			return
+		}
+
+		method, attemptsLeft, err := verifyMFACode(ctx, client, user, req.Code, req.RecoveryCode, now)
+		if errors.Is(err, errMFACodeInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":              "Invalid verification code",
+				"attempts_remaining": min(mfaMaxAttempts-challenge.Attempts, attemptsLeft),
+			})
+			return
+		}
+		if errors.Is(err, errMFALocked) {
+			c.JSON(http.StatusLocked, mfaLockedResponse)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+
+		result, err := challengeCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "challenge_id", Value: challenge.ChallengeID},
+				{Key: "used_at", Value: nil},
+			},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: now}}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusUnauthorized, invalidChallenge)
+			return
+		}
+
+		response, ok := startLoginSession(c, ctx, client, user, mfaAuthenticationMethods)
+		if !ok {
+			return
+		}
+		if method == "recovery" {
+			recordAudit(ctx, client, user.UserID, "mfa.recovery_code_used", "user", user.UserID, bson.M{
+				"recovery_codes_remaining": len(user.MFA.RecoveryCodeHashes) - 1,
+			})
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetMyMFA - A signed-in user checks whether MFA is set up and whether their role requires it
+func GetMyMFA(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := loadMFAUser(c, ctx, client)
+		if !ok {
+			return
+		}
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		status := gin.H{
+			"enabled":            mfaEnabled(user),
+			"required":           slices.Contains(settings.MFA.RequiredRoles, user.Role),
+			"pending_enrollment": user.MFA != nil && user.MFA.PendingSecret != "",
+		}
+		if mfaEnabled(user) {
+			status["enabled_at"] = user.MFA.EnabledAt
+			status["recovery_codes_remaining"] = len(user.MFA.RecoveryCodeHashes)
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// StartMFAEnrollment - A signed-in user gets a new TOTP secret and its QR code for an authenticator
+// app. MFA is only turned on once a code from the app is confirmed.
+func StartMFAEnrollment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := loadMFAUser(c, ctx, client)
+		if !ok {
+			return
+		}
+		if mfaEnabled(user) {
+			c.JSON(http.StatusConflict, gin.H{"error": "MFA is already enabled"})
+			return
+		}
+
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate MFA secret"})
+			return
+		}
+		sealed, err := utils.SealSecret([]byte(secret))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate MFA secret"})
+			return
+		}
+
+		issuer := os.Getenv("MFA_ISSUER")
+		if issuer == "" {
+			issuer = "CouponMeal"
+		}
+		uri := utils.TOTPProvisioningURI(issuer, user.Email, secret)
+		qrImage, err := qrcode.Encode(uri, qrcode.Medium, 256)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+			return
+		}
+
+		userCollection := database.OpenCollection("users", client)
+		_, err = userCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "user_id", Value: user.UserID}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "mfa.pending_secret", Value: sealed},
+				{Key: "mfa.pending_secret_encrypted", Value: true},
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start MFA enrollment"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":     "Scan the QR code with an authenticator app, then confirm with a code from the app",
+			"secret":      secret,
+			"otpauth_uri": uri,
+			"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrImage),
+		})
+	}
+}
+
+// ConfirmMFAEnrollment - A signed-in user turns MFA on with a code from the authenticator app and
+// receives their recovery codes, which are shown only this once. Every session is signed out, so
+// the next login uses the second factor.
+func ConfirmMFAEnrollment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := loadMFAUser(c, ctx, client)
+		if !ok {
+			return
+		}
+		if mfaEnabled(user) {
+			c.JSON(http.StatusConflict, gin.H{"error": "MFA is already enabled"})
+			return
+		}
+		if user.MFA == nil || user.MFA.PendingSecret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Start MFA enrollment first"})
+			return
+		}
+
+		now := time.Now()
+		secret, err := utils.OpenSecret(user.MFA.PendingSecret, user.MFA.PendingSecretEncrypted)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read MFA secret"})
+			return
+		}
+		step, valid := utils.ValidateTOTP(string(secret), req.Code, now)
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+			return
+		}
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+			return
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		userCollection := database.OpenCollection("users", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			// Matching on the pending secret fails if enrollment was restarted in the meantime
+			result, err := userCollection.UpdateOne(
+				sessCtx,
+				bson.D{
+					{Key: "user_id", Value: user.UserID},
+					{Key: "mfa.enabled_at", Value: nil},
+					{Key: "mfa.pending_secret", Value: user.MFA.PendingSecret},
+				},
+				bson.D{
+					{Key: "$set", Value: bson.D{
+						{Key: "mfa.secret", Value: user.MFA.PendingSecret},
+						{Key: "mfa.secret_encrypted", Value: user.MFA.PendingSecretEncrypted},
+						{Key: "mfa.enabled_at", Value: now},
+						{Key: "mfa.recovery_code_hashes", Value: hashes},
+						{Key: "mfa.last_used_step", Value: step},
+					}},
+					{Key: "$unset", Value: bson.D{
+						{Key: "mfa.pending_secret", Value: ""},
+						{Key: "mfa.pending_secret_encrypted", Value: ""},
+					}},
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+			if result.MatchedCount == 0 {
+				return nil, errMFAEnrollmentChanged
+			}
+			if err := revokeUserSessions(sessCtx, client, user.UserID, "mfa_enabled", now); err != nil {
+				return nil, err
+			}
+			return nil, recordAudit(sessCtx, client, user.UserID, "mfa.enabled", "user", user.UserID, bson.M{})
+		})
+		if errors.Is(err, errMFAEnrollmentChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": "MFA enrollment changed. Please start again"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable MFA"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "MFA enabled. Keep the recovery codes somewhere safe, they are shown only once. Please log in again",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// RegenerateRecoveryCodes - A signed-in user replaces their recovery codes after confirming a
+// code from the authenticator app. The old codes stop working.
+func RegenerateRecoveryCodes(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := loadMFAUser(c, ctx, client)
+		if !ok {
+			return
+		}
+		if !mfaEnabled(user) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "MFA is not enabled"})
+			return
+		}
+
+		_, attemptsLeft, err := verifyMFACode(ctx, client, user, req.Code, "", time.Now())
+		if errors.Is(err, errMFACodeInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code", "attempts_remaining": attemptsLeft})
+			return
+		}
+		if errors.Is(err, errMFALocked) {
+			c.JSON(http.StatusLocked, mfaLockedResponse)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+			return
+		}
+		userCollection := database.OpenCollection("users", client)
+		_, err = userCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "user_id", Value: user.UserID}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "mfa.recovery_code_hashes", Value: hashes}}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recovery codes"})
+			return
+		}
+
+		recordAudit(ctx, client, user.UserID, "mfa.recovery_codes_regenerated", "user", user.UserID, bson.M{})
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "Recovery codes regenerated. Keep them somewhere safe, they are shown only once",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// DisableMyMFA - A signed-in user turns MFA off with their password and a code, unless their role requires it
+func DisableMyMFA(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.DisableMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if (req.Code == "") == (req.RecoveryCode == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either code or recovery_code"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := loadMFAUser(c, ctx, client)
+		if !ok {
+			return
+		}
+		if !mfaEnabled(user) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "MFA is not enabled"})
+			return
+		}
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+		if slices.Contains(settings.MFA.RequiredRoles, user.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "MFA is required for your role and cannot be turned off"})
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+			return
+		}
+
+		_, attemptsLeft, err := verifyMFACode(ctx, client, user, req.Code, req.RecoveryCode, time.Now())
+		if errors.Is(err, errMFACodeInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code", "attempts_remaining": attemptsLeft})
+			return
+		}
+		if errors.Is(err, errMFALocked) {
+			c.JSON(http.StatusLocked, mfaLockedResponse)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+
+		userCollection := database.OpenCollection("users", client)
+		_, err = userCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "user_id", Value: user.UserID}},
+			bson.D{{Key: "$unset", Value: bson.D{{Key: "mfa", Value: ""}}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable MFA"})
+			return
+		}
+
+		recordAudit(ctx, client, user.UserID, "mfa.disabled", "user", user.UserID, bson.M{})
+		c.JSON(http.StatusOK, gin.H{"message": "MFA disabled successfully"})
+	}
+}
+
+// ResetUserMFA - Admin removes a user's MFA, e.g. after a lost phone, and signs them out everywhere.
+// The user sets MFA up again on their next login if their role requires it.
+func ResetUserMFA(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		userCollection := database.OpenCollection("users", client)
+		result, err := userCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "user_id", Value: c.Param("id")}},
+			bson.D{{Key: "$unset", Value: bson.D{{Key: "mfa", Value: ""}}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset MFA"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err := revokeUserSessions(ctx, client, c.Param("id"), "mfa_reset", now); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign the user out"})
+			return
+		}
+
+		recordAudit(ctx, client, adminUserID, "mfa.reset", "user", c.Param("id"), bson.M{})
+		c.JSON(http.StatusOK, gin.H{"message": "MFA reset successfully", "user_id": c.Param("id")})
+	}
+}
+
+// UnlockUserMFA - Admin lifts an MFA lock after too many wrong codes. The user keeps their enrollment.
+func UnlockUserMFA(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		userCollection := database.OpenCollection("users", client)
+		result, err := userCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "user_id", Value: c.Param("id")},
+				{Key: "mfa", Value: bson.D{{Key: "$exists", Value: true}}},
+			},
+			bson.D{
+				{Key: "$set", Value: bson.D{{Key: "mfa.failed_attempts", Value: 0}}},
+				{Key: "$unset", Value: bson.D{{Key: "mfa.locked_at", Value: ""}}},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock MFA"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User with MFA not found"})
+			return
+		}
+
+		recordAudit(ctx, client, adminUserID, "mfa.unlocked", "user", c.Param("id"), bson.M{})
+		c.JSON(http.StatusOK, gin.H{"message": "MFA unlocked successfully", "user_id": c.Param("id")})
+	}
+}
+
+// UpdateMFAPolicy - Admin chooses which roles must sign in with MFA. Users of those roles who
+// have not set it up can only reach the MFA setup endpoints until they do.
+func UpdateMFAPolicy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateMFAPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		requiredRoles := []string{}
+		for _, role := range req.RequiredRoles {
+			if !slices.Contains(requiredRoles, role) {
+				requiredRoles = append(requiredRoles, role)
+			}
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		settings.MFA.RequiredRoles = requiredRoles
+		settings.UpdatedByUserID = adminUserID
+		settings.UpdatedAt = time.Now()
+
+		if err := saveOrganizationSettings(ctx, client, settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update MFA policy"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "MFA policy updated successfully",
+			"mfa":     settings.MFA,
+		})
+	}
+}
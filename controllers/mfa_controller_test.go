@@ -0,0 +1,29 @@
+package controllers
+
+import "testing"
+
+func TestMFALockout(t *testing.T) {
+	tests := []struct {
+		name             string
+		failedAttempts   int
+		maxFailures      int
+		wantAttemptsLeft int
+		wantLocked       bool
+	}{
+		{name: "first wrong code", failedAttempts: 1, maxFailures: 10, wantAttemptsLeft: 9},
+		{name: "one before the lock", failedAttempts: 9, maxFailures: 10, wantAttemptsLeft: 1},
+		{name: "limit reached", failedAttempts: 10, maxFailures: 10, wantLocked: true},
+		{name: "past the limit", failedAttempts: 12, maxFailures: 10, wantLocked: true},
+		{name: "zero limit locks on the first wrong code", failedAttempts: 1, maxFailures: 0, wantLocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attemptsLeft, locked := mfaLockout(tt.failedAttempts, tt.maxFailures)
+			if attemptsLeft != tt.wantAttemptsLeft || locked != tt.wantLocked {
+				t.Errorf("mfaLockout(%d, %d) = %d, %v; want %d, %v",
+					tt.failedAttempts, tt.maxFailures, attemptsLeft, locked, tt.wantAttemptsLeft, tt.wantLocked)
+			}
+		})
+	}
+}
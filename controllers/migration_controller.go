@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// RunMigrationOnce runs a one-off data migration unless it has already completed, and records it
+// with the details run returns once it succeeds. A failed migration is not recorded, so it is tried
+// again on the next start. It reports whether run was called.
+func RunMigrationOnce(ctx context.Context, client *mongo.Client, migrationID string, run func(ctx context.Context) (bson.M, error)) (bool, error) {
+	migrationCollection := database.OpenCollection("migrations", client)
+	count, err := migrationCollection.CountDocuments(ctx, bson.D{{Key: "migration_id", Value: migrationID}})
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	details, err := run(ctx)
+	if err != nil {
+		return true, err
+	}
+	_, err = migrationCollection.UpdateOne(
+		ctx,
+		bson.D{{Key: "migration_id", Value: migrationID}},
+		bson.D{{Key: "$setOnInsert", Value: models.Migration{
+			MigrationID: migrationID,
+			Details:     details,
+			CompletedAt: time.Now(),
+		}}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return true, err
+}
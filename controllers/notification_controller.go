@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// notifyUser stores an in-app notification for the user.
+// Pass a session context to make the notification part of a larger transaction.
+func notifyUser(ctx context.Context, client *mongo.Client, userID, notificationType, title, message, referenceID string) error {
+	notification := models.Notification{
+		NotificationID: bson.NewObjectID().Hex(),
+		UserID:         userID,
+		Type:           notificationType,
+		Title:          title,
+		Message:        message,
+		ReferenceID:    referenceID,
+		IsRead:         false,
+		CreatedAt:      time.Now(),
+	}
+
+	notificationCollection := database.OpenCollection("notifications", client)
+	_, err := notificationCollection.InsertOne(ctx, notification)
+	return err
+}
+
+// notifyAdmins stores the same in-app notification for every admin user
+func notifyAdmins(ctx context.Context, client *mongo.Client, notificationType, title, message, referenceID string) error {
+	userCollection := database.OpenCollection("users", client)
+	cursor, err := userCollection.Find(ctx, bson.D{{Key: "role", Value: "ADMIN"}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var admins []models.User
+	if err = cursor.All(ctx, &admins); err != nil {
+		return err
+	}
+	for _, admin := range admins {
+		if err := notifyUser(ctx, client, admin.UserID, notificationType, title, message, referenceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMyNotifications - User lists their notifications, newest first
+func GetMyNotifications(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		filter := bson.D{{Key: "user_id", Value: userID}}
+		if c.Query("unread") == "true" {
+			filter = append(filter, bson.E{Key: "is_read", Value: false})
+		}
+
+		notificationCollection := database.OpenCollection("notifications", client)
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(100)
+		cursor, err := notificationCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var notifications []models.Notification
+		if err = cursor.All(ctx, &notifications); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"notifications": notifications,
+			"total":         len(notifications),
+		})
+	}
+}
+
+// MarkNotificationRead - User marks one of their notifications as read
+func MarkNotificationRead(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		notificationCollection := database.OpenCollection("notifications", client)
+		result, err := notificationCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "notification_id", Value: c.Param("id")},
+				{Key: "user_id", Value: userID},
+			},
+			bson.D{{Key: "$set", Value: bson.M{
+				"is_read": true,
+				"read_at": time.Now(),
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+	}
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// OnboardEmployee - Admin creates an EMPLOYEE user and its employee record together and gets an
+// invitation token for the employee to set their own password
+func OnboardEmployee(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.OnboardEmployeeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		req.Email = utils.NormalizeEmail(req.Email)
+
+		hireDate, err := time.Parse("2006-01-02", req.HireDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hire date format. Use YYYY-MM-DD"})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if !checkOnboardingEmailFree(c, ctx, client, req.Email) {
+			return
+		}
+		employeeCollection := database.OpenCollection("employees", client)
+		count, err := employeeCollection.CountDocuments(ctx, bson.D{{Key: "employee_code", Value: req.EmployeeCode}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check employee code"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Employee code already exists"})
+			return
+		}
+		if req.DepartmentID != "" && !checkDepartmentAssignable(c, ctx, client, req.DepartmentID) {
+			return
+		}
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allocation settings"})
+			return
+		}
+
+		now := time.Now()
+		user, err := newInvitedUser(req.FirstName, req.LastName, req.Email, "EMPLOYEE", now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to hash password"})
+			return
+		}
+		employee := models.Employee{
+			EmployeeID:        bson.NewObjectID().Hex(),
+			UserID:            user.UserID,
+			EmployeeCode:      req.EmployeeCode,
+			Name:              strings.TrimSpace(req.FirstName + " " + req.LastName),
+			Email:             req.Email,
+			Phone:             req.Phone,
+			Status:            "active",
+			DepartmentID:      req.DepartmentID,
+			ManagerEmployeeID: req.ManagerEmployeeID,
+			JobGrade:          req.JobGrade,
+			ShiftPattern:      req.ShiftPattern,
+			EmploymentType:    req.EmploymentType,
+			HireDate:          hireDate,
+			CreatedByAdminID:  adminUserID,
+			IsVerified:        true,
+			Notes:             req.Notes,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		var token string
+		var invitation models.Invitation
+		allocation := 0
+		userCollection := database.OpenCollection("users", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			if _, err := userCollection.InsertOne(sessCtx, user); err != nil {
+				return nil, err
+			}
+			_, issued, err := insertNewEmployee(sessCtx, client, employee, settings, adminUserID, now)
+			if err != nil {
+				return nil, err
+			}
+			allocation = issued
+			token, invitation, err = issueInvitation(sessCtx, client, user, adminUserID, now)
+			if err != nil {
+				return nil, err
+			}
+			return nil, recordAudit(sessCtx, client, adminUserID, "user.onboarded", "employee", employee.EmployeeID, bson.M{
+				"user_id": user.UserID,
+				"role":    user.Role,
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to onboard employee"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":               "Employee onboarded successfully",
+			"user_id":               user.UserID,
+			"employee_id":           employee.EmployeeID,
+			"initial_allocation":    allocation,
+			"invitation_token":      token,
+			"invitation_expires_at": invitation.ExpiresAt,
+		})
+	}
+}
+
+// OnboardSupplier - Admin creates a SUPPLIER user and its supplier profile together and gets an
+// invitation token for the supplier to set their own password
+func OnboardSupplier(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.OnboardSupplierRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		req.Email = utils.NormalizeEmail(req.Email)
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if !checkOnboardingEmailFree(c, ctx, client, req.Email) {
+			return
+		}
+
+		now := time.Now()
+		user, err := newInvitedUser(req.FirstName, req.LastName, req.Email, "SUPPLIER", now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to hash password"})
+			return
+		}
+		locationRadius := req.LocationRadius
+		if locationRadius == 0 {
+			locationRadius = 500
+		}
+		supplier := models.Supplier{
+			SupplierID:       bson.NewObjectID().Hex(),
+			UserID:           user.UserID,
+			BusinessName:     req.BusinessName,
+			BusinessLicense:  req.BusinessLicense,
+			ContactPerson:    req.ContactPerson,
+			Phone:            req.Phone,
+			Email:            req.Email,
+			Address:          req.Address,
+			Latitude:         req.Latitude,
+			Longitude:        req.Longitude,
+			LocationRadius:   locationRadius,
+			IsActive:         true,
+			IsVerified:       false,
+			BankAccount:      req.BankAccount,
+			TaxID:            req.TaxID,
+			Notes:            req.Notes,
+			CreatedByAdminID: adminUserID,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		var token string
+		var invitation models.Invitation
+		userCollection := database.OpenCollection("users", client)
+		supplierCollection := database.OpenCollection("suppliers", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			if _, err := userCollection.InsertOne(sessCtx, user); err != nil {
+				return nil, err
+			}
+			if _, err := supplierCollection.InsertOne(sessCtx, supplier); err != nil {
+				return nil, err
+			}
+			var err error
+			token, invitation, err = issueInvitation(sessCtx, client, user, adminUserID, now)
+			if err != nil {
+				return nil, err
+			}
+			return nil, recordAudit(sessCtx, client, adminUserID, "user.onboarded", "supplier", supplier.SupplierID, bson.M{
+				"user_id": user.UserID,
+				"role":    user.Role,
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to onboard supplier"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":               "Supplier onboarded successfully",
+			"user_id":               user.UserID,
+			"supplier_id":           supplier.SupplierID,
+			"business_name":         supplier.BusinessName,
+			"invitation_token":      token,
+			"invitation_expires_at": invitation.ExpiresAt,
+		})
+	}
+}
+
+// checkOnboardingEmailFree writes a 409 response when a user with the email already exists
+func checkOnboardingEmailFree(c *gin.Context, ctx context.Context, client *mongo.Client, email string) bool {
+	userCollection := database.OpenCollection("users", client)
+	count, err := userCollection.CountDocuments(ctx, bson.D{{Key: "email", Value: email}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing user"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+		return false
+	}
+	return true
+}
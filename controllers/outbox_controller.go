@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// outboxLockTimeout is how long a message may stay claimed before another delivery run retries it
+const outboxLockTimeout = 10 * time.Minute
+
+// enqueueOutboxMessage queues a message for the notifier.
+// Pass a session context so the message is only sent if the change it announces commits.
+func enqueueOutboxMessage(ctx context.Context, client *mongo.Client, kind string, message utils.OutboundMessage, now time.Time) error {
+	outboxCollection := database.OpenCollection("outbox", client)
+	_, err := outboxCollection.InsertOne(ctx, models.OutboxMessage{
+		MessageID:     bson.NewObjectID().Hex(),
+		To:            message.To,
+		Subject:       message.Subject,
+		Body:          message.Body,
+		Kind:          kind,
+		Status:        "pending",
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+	return err
+}
+
+// DeliverOutbox sends the queued messages that are due through the notifier and returns how many
+// were sent. Failed sends are retried with a growing delay, up to OUTBOX_MAX_ATTEMPTS times.
+func DeliverOutbox(ctx context.Context, client *mongo.Client, notifier utils.Notifier) (int, error) {
+	outboxCollection := database.OpenCollection("outbox", client)
+	maxAttempts := utils.GetEnvAsInt("OUTBOX_MAX_ATTEMPTS", 5)
+
+	sent := 0
+	for {
+		now := time.Now()
+		var message models.OutboxMessage
+		err := outboxCollection.FindOneAndUpdate(
+			ctx,
+			bson.D{{Key: "$or", Value: bson.A{
+				bson.D{
+					{Key: "status", Value: "pending"},
+					{Key: "next_attempt_at", Value: bson.D{{Key: "$lte", Value: now}}},
+				},
+				bson.D{
+					{Key: "status", Value: "sending"},
+					{Key: "locked_at", Value: bson.D{{Key: "$lt", Value: now.Add(-outboxLockTimeout)}}},
+				},
+			}}},
+			bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "status", Value: "sending"},
+					{Key: "locked_at", Value: now},
+				}},
+				{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+			},
+			options.FindOneAndUpdate().
+				SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+				SetReturnDocument(options.After),
+		).Decode(&message)
+		if err == mongo.ErrNoDocuments {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		sendErr := notifier.Send(ctx, utils.OutboundMessage{To: message.To, Subject: message.Subject, Body: message.Body})
+		// A sent message has no further use for its body, which may carry a one-time token
+		update := bson.D{{Key: "status", Value: "sent"}, {Key: "sent_at", Value: time.Now()}, {Key: "body", Value: ""}}
+		if sendErr != nil {
+			status := "pending"
+			if message.Attempts >= maxAttempts {
+				status = "failed"
+			}
+			retryIn := time.Duration(message.Attempts*message.Attempts) * time.Minute
+			update = bson.D{
+				{Key: "status", Value: status},
+				{Key: "last_error", Value: sendErr.Error()},
+				{Key: "next_attempt_at", Value: time.Now().Add(retryIn)},
+			}
+		}
+		_, err = outboxCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "message_id", Value: message.MessageID}},
+			bson.D{
+				{Key: "$set", Value: update},
+				{Key: "$unset", Value: bson.D{{Key: "locked_at", Value: ""}}},
+			},
+		)
+		if err != nil {
+			return sent, err
+		}
+		if sendErr == nil {
+			sent++
+		}
+	}
+}
+
+// GetOutboxMessages - Admin checks delivery of outbound messages, newest first (?status=, ?kind=).
+// Message bodies are not returned because they can carry one-time tokens.
+func GetOutboxMessages(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.D{}
+		for _, field := range []string{"status", "kind"} {
+			if value := c.Query(field); value != "" {
+				filter = append(filter, bson.E{Key: field, Value: value})
+			}
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		outboxCollection := database.OpenCollection("outbox", client)
+		cursor, err := outboxCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(200))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outbox"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		messages := []models.OutboxMessage{}
+		if err = cursor.All(ctx, &messages); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode outbox"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"messages": messages,
+			"total":    len(messages),
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var errResetTokenInvalid = errors.New("reset token is invalid or has expired")
+
+// ForgotPassword - Sends a password reset link to the email if it belongs to an active account.
+// The response is the same either way so it cannot be used to find out which emails have accounts.
+func ForgotPassword(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		response := gin.H{"message": "If the email belongs to an account, a password reset link has been sent"}
+
+		userCollection := database.OpenCollection("users", client)
+		var user models.User
+		err := userCollection.FindOne(ctx, bson.D{
+			{Key: "email", Value: utils.NormalizeEmail(req.Email)},
+			{Key: "deactivated_at", Value: nil},
+		}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+
+		token, err := utils.GenerateSecureToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		resetCollection := database.OpenCollection("password_resets", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			now := time.Now()
+			expiryMinutes := utils.GetEnvAsInt("PASSWORD_RESET_EXPIRY_MINUTES", 30)
+
+			// Only the newest link works
+			_, err := resetCollection.UpdateMany(
+				sessCtx,
+				bson.D{
+					{Key: "user_id", Value: user.UserID},
+					{Key: "used_at", Value: nil},
+					{Key: "revoked_at", Value: nil},
+				},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: now}}}},
+			)
+			if err != nil {
+				return nil, err
+			}
+			_, err = resetCollection.InsertOne(sessCtx, models.PasswordReset{
+				ResetID:   bson.NewObjectID().Hex(),
+				UserID:    user.UserID,
+				TokenHash: utils.HashSecureToken(token),
+				ExpiresAt: now.Add(time.Duration(expiryMinutes) * time.Minute),
+				CreatedAt: now,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			link := os.Getenv("PASSWORD_RESET_URL") + "?token=" + token
+			return nil, enqueueOutboxMessage(sessCtx, client, "password_reset", utils.OutboundMessage{
+				To:      user.Email,
+				Subject: "Reset your password",
+				Body: fmt.Sprintf("Hello %s,\n\nUse this link within %d minutes to choose a new password:\n%s\n\nIf you did not ask for this, you can ignore this message.",
+					user.FirstName, expiryMinutes, link),
+			}, now)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// ResetPassword - Sets a new password with a reset token. The token works once, and every
+// existing session of the user is signed out.
+func ResetPassword(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		hashedPassword, err := HashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to hash password"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		resetCollection := database.OpenCollection("password_resets", client)
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			now := time.Now()
+
+			// Claim the token so it works once, even for concurrent requests
+			var reset models.PasswordReset
+			err := resetCollection.FindOneAndUpdate(
+				sessCtx,
+				bson.D{
+					{Key: "token_hash", Value: utils.HashSecureToken(req.Token)},
+					{Key: "used_at", Value: nil},
+					{Key: "revoked_at", Value: nil},
+					{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+				},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: now}}}},
+			).Decode(&reset)
+			if err == mongo.ErrNoDocuments {
+				return nil, errResetTokenInvalid
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if err := setUserPassword(sessCtx, client, reset.UserID, hashedPassword, now); err != nil {
+				return nil, err
+			}
+			return nil, recordAudit(sessCtx, client, reset.UserID, "password.reset", "user", reset.UserID, bson.M{"reset_id": reset.ResetID})
+		})
+		if errors.Is(err, errResetTokenInvalid) || err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reset token is invalid or has expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully. You can now log in"})
+	}
+}
+
+// ChangePassword - A signed-in user changes their password by confirming the current one.
+// Every session, including the current one, is signed out.
+func ChangePassword(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if req.NewPassword == req.CurrentPassword {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be different from the current one"})
+			return
+		}
+
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		userCollection := database.OpenCollection("users", client)
+		var user models.User
+		err = userCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+
+		hashedPassword, err := HashPassword(req.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to hash password"})
+			return
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			now := time.Now()
+			if err := setUserPassword(sessCtx, client, userID, hashedPassword, now); err != nil {
+				return nil, err
+			}
+			return nil, recordAudit(sessCtx, client, userID, "password.changed", "user", userID, bson.M{})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully. Please log in again"})
+	}
+}
+
+// setUserPassword stores a new password hash and signs the user out everywhere
+func setUserPassword(ctx context.Context, client *mongo.Client, userID, hashedPassword string, now time.Time) error {
+	userCollection := database.OpenCollection("users", client)
+	result, err := userCollection.UpdateOne(
+		ctx,
+		bson.D{
+			{Key: "user_id", Value: userID},
+			{Key: "deactivated_at", Value: nil},
+		},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "password", Value: hashedPassword},
+			{Key: "update_at", Value: now},
+		}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return revokeUserSessions(ctx, client, userID, "password_changed", now)
+}
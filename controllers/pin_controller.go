@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errPinNotSet = errors.New("employee has not set a PIN")
+	errPinLocked = errors.New("PIN entry is locked")
+	errPinWrong  = errors.New("incorrect PIN")
+)
+
+// maxPinLockout caps the doubling PIN lock, so a forgotten PIN never stays locked for more than a day
+const maxPinLockout = 24 * time.Hour
+
+// SetMyPin - Employee sets or changes their terminal PIN
+func SetMyPin(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.SetPinRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "PIN must be 4 to 6 digits and current password is required"})
+			return
+		}
+
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		// Confirm the current password before accepting a new PIN
+		userCollection := database.OpenCollection("users", client)
+		var user models.User
+		err = userCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+			return
+		}
+
+		pinHash, err := HashPassword(req.Pin)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to hash PIN"})
+			return
+		}
+
+		now := time.Now()
+		employeeCollection := database.OpenCollection("employees", client)
+		result, err := employeeCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "user_id", Value: userID}},
+			bson.D{
+				{Key: "$set", Value: bson.M{
+					"pin_hash":            pinHash,
+					"pin_failed_attempts": 0,
+					"pin_updated_at":      now,
+					"updated_at":          now,
+				}},
+				{Key: "$unset", Value: bson.M{"pin_locked_until": ""}},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set PIN"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "PIN set successfully"})
+	}
+}
+
+// UnlockEmployeePin - Admin clears a PIN lockout
+func UnlockEmployeePin(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		employeeID := c.Param("id")
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		result, err := employeeCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "employee_id", Value: employeeID}},
+			bson.D{
+				{Key: "$set", Value: bson.M{
+					"pin_failed_attempts": 0,
+					"updated_at":          time.Now(),
+				}},
+				{Key: "$unset", Value: bson.M{"pin_locked_until": ""}},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock PIN"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "PIN unlocked successfully"})
+	}
+}
+
+// verifyEmployeePin checks the PIN against the stored hash and records the attempt.
+// Every PIN_MAX_ATTEMPTS consecutive failures lock the PIN, for PIN_LOCKOUT_MINUTES the first
+// time and twice as long on each lock after that. Failures only reset on a correct PIN or when an
+// admin resets the PIN. It returns the number of attempts left when the PIN is wrong.
+func verifyEmployeePin(ctx context.Context, client *mongo.Client, employee models.Employee, pin string) (int, error) {
+	if employee.PinHash == "" {
+		return 0, errPinNotSet
+	}
+
+	now := time.Now()
+	if employee.PinLockedUntil != nil && now.Before(*employee.PinLockedUntil) {
+		return 0, errPinLocked
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	filter := bson.D{{Key: "employee_id", Value: employee.EmployeeID}}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(employee.PinHash), []byte(pin)); err == nil {
+		_, err = employeeCollection.UpdateOne(ctx, filter, bson.D{
+			{Key: "$set", Value: bson.M{"pin_failed_attempts": 0}},
+			{Key: "$unset", Value: bson.M{"pin_locked_until": ""}},
+		})
+		return 0, err
+	}
+
+	maxAttempts := utils.GetEnvAsInt("PIN_MAX_ATTEMPTS", 5)
+	lockoutMinutes := utils.GetEnvAsInt("PIN_LOCKOUT_MINUTES", 15)
+
+	// Counting with $inc keeps concurrent wrong PINs from overwriting each other's attempt, and
+	// the lock filter stops guesses that race with a lock being set
+	var updated models.Employee
+	err := employeeCollection.FindOneAndUpdate(
+		ctx,
+		append(filter, bson.E{Key: "pin_locked_until", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: now}}}}}),
+		bson.D{{Key: "$inc", Value: bson.M{"pin_failed_attempts": 1}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return 0, errPinLocked
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	lockout, attemptsLeft := pinLockout(updated.PinFailedAttempts, maxAttempts, time.Duration(lockoutMinutes)*time.Minute)
+	if lockout == 0 {
+		return attemptsLeft, errPinWrong
+	}
+
+	_, err = employeeCollection.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: bson.M{
+		"pin_locked_until": now.Add(lockout),
+	}}})
+	if err != nil {
+		return 0, err
+	}
+	return 0, errPinLocked
+}
+
+// pinLockout decides what a failed PIN attempt leads to. Every maxAttempts-th consecutive failure
+// locks the PIN, doubling the lock each time up to maxPinLockout; otherwise it returns the
+// attempts left before the next lock.
+func pinLockout(failedAttempts, maxAttempts int, baseLockout time.Duration) (time.Duration, int) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if failedAttempts%maxAttempts != 0 {
+		return 0, maxAttempts - failedAttempts%maxAttempts
+	}
+
+	lockout := baseLockout
+	for locks := failedAttempts / maxAttempts; locks > 1 && lockout < maxPinLockout; locks-- {
+		lockout *= 2
+	}
+	if lockout > maxPinLockout {
+		lockout = maxPinLockout
+	}
+	return lockout, 0
+}
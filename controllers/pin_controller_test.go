@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPinLockout(t *testing.T) {
+	tests := []struct {
+		name             string
+		failedAttempts   int
+		maxAttempts      int
+		wantLockout      time.Duration
+		wantAttemptsLeft int
+	}{
+		{name: "first failure", failedAttempts: 1, maxAttempts: 5, wantAttemptsLeft: 4},
+		{name: "one before the lock", failedAttempts: 4, maxAttempts: 5, wantAttemptsLeft: 1},
+		{name: "first lock", failedAttempts: 5, maxAttempts: 5, wantLockout: 15 * time.Minute},
+		{name: "failure after the first lock", failedAttempts: 6, maxAttempts: 5, wantAttemptsLeft: 4},
+		{name: "second lock doubles", failedAttempts: 10, maxAttempts: 5, wantLockout: 30 * time.Minute},
+		{name: "third lock doubles again", failedAttempts: 15, maxAttempts: 5, wantLockout: time.Hour},
+		{name: "lock is capped", failedAttempts: 100, maxAttempts: 5, wantLockout: maxPinLockout},
+		{name: "zero max attempts locks every failure", failedAttempts: 1, maxAttempts: 0, wantLockout: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockout, attemptsLeft := pinLockout(tt.failedAttempts, tt.maxAttempts, 15*time.Minute)
+			if lockout != tt.wantLockout || attemptsLeft != tt.wantAttemptsLeft {
+				t.Errorf("pinLockout(%d, %d) = %v, %d; want %v, %d",
+					tt.failedAttempts, tt.maxAttempts, lockout, attemptsLeft, tt.wantLockout, tt.wantAttemptsLeft)
+			}
+		})
+	}
+}
@@ -0,0 +1,687 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	qrcode "github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	errSlotFull           = errors.New("pickup slot is fully booked")
+	errPreOrderNotPending = errors.New("pre-order is no longer reserved")
+)
+
+// UpdatePreorderSettings - Supplier enables pre-ordering and configures pickup slots
+func UpdatePreorderSettings(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdatePreorderSettingsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if err := validateMealWindows(req.PickupWindows); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		slotMinutes := req.SlotMinutes
+		if slotMinutes == 0 {
+			slotMinutes = 15
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		settings := models.PreorderSettings{
+			Enabled:          req.Enabled,
+			SlotMinutes:      slotMinutes,
+			MaxOrdersPerSlot: req.MaxOrdersPerSlot,
+			PickupWindows:    req.PickupWindows,
+		}
+
+		supplierCollection := database.OpenCollection("suppliers", client)
+		_, err = supplierCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "supplier_id", Value: supplier.SupplierID}},
+			bson.D{{Key: "$set", Value: bson.M{
+				"preorder":   settings,
+				"updated_at": time.Now(),
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pre-order settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Pre-order settings updated successfully",
+			"preorder": settings,
+		})
+	}
+}
+
+// CreatePreOrder - Employee orders ahead for a pickup slot; the coupons are held, not spent
+func CreatePreOrder(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreatePreOrderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		// 1. Employee
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+		if employee.Status != "active" && employee.Status != "on_leave" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Employee account is not active"})
+			return
+		}
+
+		// 2. Supplier must accept pre-orders
+		supplierCollection := database.OpenCollection("suppliers", client)
+		var supplier models.Supplier
+		err = supplierCollection.FindOne(ctx, bson.D{{Key: "supplier_id", Value: req.SupplierID}}).Decode(&supplier)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+			return
+		}
+		if !supplier.IsActive || !supplier.IsVerified || supplier.Preorder == nil || !supplier.Preorder.Enabled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Supplier does not accept pre-orders"})
+			return
+		}
+
+		// 3. Pickup slot
+		settings := supplier.Preorder
+		slotLength := time.Duration(settings.SlotMinutes) * time.Minute
+		slotStart := req.PickupAt.Local().Truncate(slotLength)
+		slotEnd := slotStart.Add(slotLength)
+		if !slotEnd.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pickup slot has already ended"})
+			return
+		}
+		inWindow := false
+		for _, window := range settings.PickupWindows {
+			if utils.WithinTimeWindow(window.Start, window.End, slotStart) {
+				inWindow = true
+				break
+			}
+		}
+		if !inWindow {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":          "Pickup time is outside the supplier's pickup hours",
+				"pickup_windows": settings.PickupWindows,
+			})
+			return
+		}
+		if !checkProgramOpen(c, ctx, client, slotStart) {
+			return
+		}
+
+		// 4. Price the order from the menu at pickup time
+		lines, coupons, price, err := resolveOrderLines(ctx, client, supplier.SupplierID, req.Items, slotStart)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order", "details": err.Error()})
+			return
+		}
+		if coupons < 1 || coupons > 3 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid coupon amount",
+				"details": "A pre-order must cost between 1 and 3 coupons",
+			})
+			return
+		}
+		if employee.CurrentBalance < coupons {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "Insufficient coupon balance",
+				"employee_balance":  employee.CurrentBalance,
+				"requested_coupons": coupons,
+			})
+			return
+		}
+
+		now := time.Now()
+		preorder := models.PreOrder{
+			PreOrderID:  bson.NewObjectID().Hex(),
+			Code:        uuid.New().String(),
+			EmployeeID:  employee.EmployeeID,
+			SupplierID:  supplier.SupplierID,
+			Items:       lines,
+			CouponsHeld: coupons,
+			MealPrice:   price,
+			SlotStart:   slotStart,
+			SlotEnd:     slotEnd,
+			Status:      "reserved",
+			Notes:       req.Notes,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		// 5. Book the slot and hold the coupons together
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			if err := bookPickupSlot(sessCtx, client, supplier.SupplierID, slotStart, 1, settings.MaxOrdersPerSlot); err != nil {
+				return nil, err
+			}
+
+			description := fmt.Sprintf("Held for pre-order at %s", supplier.BusinessName)
+			if _, err := adjustCouponBalance(sessCtx, client, employee.EmployeeID, -coupons, coupons, "preorder_hold", preorder.PreOrderID, description); err != nil {
+				return nil, err
+			}
+
+			preorderCollection := database.OpenCollection("preorders", client)
+			_, err = preorderCollection.InsertOne(sessCtx, preorder)
+			return nil, err
+		})
+		switch {
+		case errors.Is(err, errSlotFull):
+			c.JSON(http.StatusConflict, gin.H{"error": "This pickup slot is fully booked. Please choose another."})
+			return
+		case errors.Is(err, errInsufficientBalance):
+			c.JSON(http.StatusConflict, gin.H{"error": "Insufficient coupon balance"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place pre-order"})
+			return
+		}
+
+		qrImage, err := preorderQRImage(preorder.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR image"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, models.PreOrderResponse{
+			PreOrder:    preorder,
+			QRCodeImage: qrImage,
+		})
+	}
+}
+
+// GetMyPreOrders - Employee lists their pre-orders
+func GetMyPreOrders(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		filter := bson.D{{Key: "employee_id", Value: employee.EmployeeID}}
+		if status := c.Query("status"); status != "" {
+			filter = append(filter, bson.E{Key: "status", Value: status})
+		}
+
+		preorders, err := findPreOrders(ctx, client, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pre-orders"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"preorders": preorders,
+			"total":     len(preorders),
+		})
+	}
+}
+
+// CancelMyPreOrder - Employee cancels a reserved pre-order before the slot starts
+func CancelMyPreOrder(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		preorderCollection := database.OpenCollection("preorders", client)
+		var preorder models.PreOrder
+		err = preorderCollection.FindOne(ctx, bson.D{
+			{Key: "preorder_id", Value: c.Param("id")},
+			{Key: "employee_id", Value: employee.EmployeeID},
+		}).Decode(&preorder)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pre-order not found"})
+			return
+		}
+		if !time.Now().Before(preorder.SlotStart) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pre-orders can only be cancelled before the pickup slot starts"})
+			return
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			return nil, releasePreOrder(sessCtx, client, preorder, "cancelled", "Cancelled by employee")
+		})
+		if errors.Is(err, errPreOrderNotPending) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":          "Pre-order has already been processed",
+				"current_status": preorder.Status,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel pre-order"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":          "Pre-order cancelled",
+			"preorder_id":      preorder.PreOrderID,
+			"coupons_released": preorder.CouponsHeld,
+			"status":           "cancelled",
+		})
+	}
+}
+
+// GetSupplierPreOrders - Supplier lists pre-orders for a day
+func GetSupplierPreOrders(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if dateStr := c.Query("date"); dateStr != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", dateStr, now.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+				return
+			}
+			day = parsed
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		filter := bson.D{
+			{Key: "supplier_id", Value: supplier.SupplierID},
+			{Key: "slot_start", Value: bson.D{
+				{Key: "$gte", Value: day},
+				{Key: "$lt", Value: day.AddDate(0, 0, 1)},
+			}},
+		}
+		if status := c.Query("status"); status != "" {
+			filter = append(filter, bson.E{Key: "status", Value: status})
+		}
+
+		preorders, err := findPreOrders(ctx, client, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pre-orders"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"date":      day.Format("2006-01-02"),
+			"preorders": preorders,
+			"total":     len(preorders),
+		})
+	}
+}
+
+// CollectPreOrder - Supplier scans the order code at pickup and captures the held coupons
+func CollectPreOrder(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CollectPreOrderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplier, err := findSupplierForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+		if !supplier.IsActive || !supplier.IsVerified {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your supplier account is not active or not yet verified."})
+			return
+		}
+
+		preorderCollection := database.OpenCollection("preorders", client)
+		var preorder models.PreOrder
+		err = preorderCollection.FindOne(ctx, bson.D{
+			{Key: "code", Value: strings.TrimPrefix(req.Code, "PREORDER-")},
+			{Key: "supplier_id", Value: supplier.SupplierID},
+		}).Decode(&preorder)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pre-order not found"})
+			return
+		}
+		if preorder.Status != "reserved" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":          "Pre-order cannot be collected",
+				"current_status": preorder.Status,
+			})
+			return
+		}
+
+		now := time.Now()
+		grace := time.Duration(utils.GetEnvAsInt("PREORDER_GRACE_MINUTES", 15)) * time.Minute
+		if now.After(preorder.SlotEnd.Add(grace)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pickup slot has ended", "slot_end": preorder.SlotEnd})
+			return
+		}
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var employee models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: preorder.EmployeeID}}).Decode(&employee)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+
+		couponValue := 45.0
+		totalAmount := float64(preorder.CouponsHeld) * couponValue
+		// Meals priced above the coupon value are split with another tender
+		mealPrice, otherTenderAmount, otherTenderMethod, err := splitMealPrice(totalAmount, preorder.MealPrice, req.OtherTenderMethod)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         err.Error(),
+				"meal_price":    preorder.MealPrice,
+				"coupon_amount": totalAmount,
+			})
+			return
+		}
+
+		transaction := models.Transaction{
+			TransactionID:     uuid.New().String(),
+			EmployeeID:        preorder.EmployeeID,
+			SupplierID:        supplier.SupplierID,
+			PreOrderID:        preorder.PreOrderID,
+			CouponsUsed:       preorder.CouponsHeld,
+			Items:             preorder.Items,
+			TotalAmount:       totalAmount,
+			MealPrice:         mealPrice,
+			OtherTenderAmount: otherTenderAmount,
+			OtherTenderMethod: otherTenderMethod,
+			Status:            "completed",
+			ApprovalMethod:    "preorder",
+			Notes:             preorder.Notes,
+			ProcessedAt:       now,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			result, err := preorderCollection.UpdateOne(
+				sessCtx,
+				bson.D{
+					{Key: "preorder_id", Value: preorder.PreOrderID},
+					{Key: "status", Value: "reserved"},
+				},
+				bson.D{{Key: "$set", Value: bson.D{
+					{Key: "status", Value: "collected"},
+					{Key: "transaction_id", Value: transaction.TransactionID},
+					{Key: "collected_at", Value: now},
+					{Key: "updated_at", Value: now},
+				}}},
+			)
+			if err != nil {
+				return nil, err
+			}
+			if result.MatchedCount == 0 {
+				return nil, errPreOrderNotPending
+			}
+
+			// The coupons already left the spendable balance when they were held
+			description := fmt.Sprintf("Pre-order collected at %s", supplier.BusinessName)
+			_, _, err = applyBalanceChange(sessCtx, client, preorder.EmployeeID, couponBalanceChange{Held: -preorder.CouponsHeld}, "preorder_capture", transaction.TransactionID, description)
+			if err != nil {
+				return nil, err
+			}
+
+			if err = snapshotCostCenter(sessCtx, client, &transaction); err != nil {
+				return nil, err
+			}
+			if err = recordBudgetSpend(sessCtx, client, transaction.CostCenter, transaction.TotalAmount-transaction.EmployeeFundedAmount, now); err != nil {
+				return nil, err
+			}
+			transactionCollection := database.OpenCollection("transactions", client)
+			if _, err = transactionCollection.InsertOne(sessCtx, transaction); err != nil {
+				return nil, err
+			}
+
+			message := fmt.Sprintf("Your pre-order at %s was collected. %d coupon(s) were charged.", supplier.BusinessName, preorder.CouponsHeld)
+			return nil, notifyUser(sessCtx, client, employee.UserID, "preorder_collected", "Pre-order collected", message, transaction.TransactionID)
+		})
+		if errors.Is(err, errPreOrderNotPending) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pre-order has already been processed"})
+			return
+		}
+		if errors.Is(err, errInsufficientBalance) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The coupons held for this pre-order are no longer available"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect pre-order"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":        true,
+			"message":        "Pre-order collected successfully",
+			"transaction_id": transaction.TransactionID,
+			"employee": gin.H{
+				"name": employee.Name,
+				"code": employee.EmployeeCode,
+			},
+			"items":               preorder.Items,
+			"coupons_used":        preorder.CouponsHeld,
+			"total_amount":        totalAmount,
+			"other_tender_amount": otherTenderAmount,
+			"other_tender_method": otherTenderMethod,
+		})
+	}
+}
+
+// ExpirePreOrders releases the held coupons of reserved pre-orders whose pickup slot
+// ended more than PREORDER_GRACE_MINUTES ago. It returns how many orders were expired.
+func ExpirePreOrders(ctx context.Context, client *mongo.Client) (int, error) {
+	grace := time.Duration(utils.GetEnvAsInt("PREORDER_GRACE_MINUTES", 15)) * time.Minute
+	preorders, err := findPreOrders(ctx, client, bson.D{
+		{Key: "status", Value: "reserved"},
+		{Key: "slot_end", Value: bson.D{{Key: "$lt", Value: time.Now().Add(-grace)}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.EndSession(ctx)
+
+	expired := 0
+	for _, preorder := range preorders {
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			return nil, releasePreOrder(sessCtx, client, preorder, "expired", "Not collected during the pickup slot")
+		})
+		if errors.Is(err, errPreOrderNotPending) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// releasePreOrder returns the held coupons to the spendable balance, gives the pickup slot back
+// and closes the order. It must be called inside a session transaction.
+func releasePreOrder(ctx context.Context, client *mongo.Client, preorder models.PreOrder, status, reason string) error {
+	now := time.Now()
+	preorderCollection := database.OpenCollection("preorders", client)
+	result, err := preorderCollection.UpdateOne(
+		ctx,
+		bson.D{
+			{Key: "preorder_id", Value: preorder.PreOrderID},
+			{Key: "status", Value: "reserved"},
+		},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "status", Value: status},
+			{Key: "notes", Value: reason},
+			{Key: "updated_at", Value: now},
+		}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errPreOrderNotPending
+	}
+
+	// Put the coupons back into the lots they were held from
+	historyCollection := database.OpenCollection("balance_history", client)
+	var hold models.BalanceHistoryEntry
+	err = historyCollection.FindOne(ctx, bson.D{
+		{Key: "employee_id", Value: preorder.EmployeeID},
+		{Key: "type", Value: "preorder_hold"},
+		{Key: "reference_id", Value: preorder.PreOrderID},
+	}).Decode(&hold)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	change := couponBalanceChange{Company: preorder.CouponsHeld, Held: -preorder.CouponsHeld, Lots: hold.Lots}
+	_, _, err = applyBalanceChange(ctx, client, preorder.EmployeeID, change, "preorder_release", preorder.PreOrderID, "Pre-order "+status+": "+reason)
+	if err != nil {
+		return err
+	}
+	return bookPickupSlot(ctx, client, preorder.SupplierID, preorder.SlotStart, -1, 0)
+}
+
+// bookPickupSlot adjusts the order count of a slot. With a positive delta and a cap above zero
+// it fails with errSlotFull once the cap is exceeded; inside a session transaction that rolls back the booking.
+// The slot document is keyed by supplier and start time so concurrent bookings conflict on it.
+func bookPickupSlot(ctx context.Context, client *mongo.Client, supplierID string, slotStart time.Time, delta, maxOrders int) error {
+	slotCollection := database.OpenCollection("pickup_slots", client)
+	slotKey := supplierID + "|" + slotStart.UTC().Format(time.RFC3339)
+
+	var slot struct {
+		OrderCount int `bson:"order_count"`
+	}
+	err := slotCollection.FindOneAndUpdate(
+		ctx,
+		bson.D{{Key: "_id", Value: slotKey}},
+		bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "order_count", Value: delta}}},
+			{Key: "$setOnInsert", Value: bson.D{
+				{Key: "supplier_id", Value: supplierID},
+				{Key: "slot_start", Value: slotStart},
+			}},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&slot)
+	if err != nil {
+		return err
+	}
+
+	if delta > 0 && maxOrders > 0 && slot.OrderCount > maxOrders {
+		return errSlotFull
+	}
+	return nil
+}
+
+func findPreOrders(ctx context.Context, client *mongo.Client, filter bson.D) ([]models.PreOrder, error) {
+	preorderCollection := database.OpenCollection("preorders", client)
+	opts := options.Find().SetSort(bson.D{{Key: "slot_start", Value: 1}})
+	cursor, err := preorderCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var preorders []models.PreOrder
+	if err = cursor.All(ctx, &preorders); err != nil {
+		return nil, err
+	}
+	return preorders, nil
+}
+
+func preorderQRImage(code string) (string, error) {
+	qrImage, err := qrcode.Encode("PREORDER-"+code, qrcode.Medium, 256)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrImage), nil
+}
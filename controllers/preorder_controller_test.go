@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestReleasePreOrderFreesPickupSlot(t *testing.T) {
+	client := testClient(t)
+	upcoming := time.Now().Add(2 * time.Hour).Truncate(time.Hour).UTC()
+	missed := time.Now().Add(-3 * time.Hour).Truncate(time.Hour).UTC()
+
+	tests := []struct {
+		name      string
+		slotStart time.Time
+		release   func(t *testing.T, userID, employeeID, preorderID string)
+		want      string
+	}{
+		{
+			name:      "cancelled by the employee",
+			slotStart: upcoming,
+			release: func(t *testing.T, userID, employeeID, preorderID string) {
+				w := serve(t, CancelMyPreOrder(client), http.MethodDelete, "/preorders/:id", "/preorders/"+preorderID, userID, "EMPLOYEE", nil)
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+				}
+			},
+			want: "cancelled",
+		},
+		{
+			name:      "employee terminated",
+			slotStart: upcoming,
+			release: func(t *testing.T, userID, employeeID, preorderID string) {
+				w := serve(t, UpdateEmployee(client), http.MethodPatch, "/employees/:id", "/employees/"+employeeID, "admin", "ADMIN", gin.H{"status": "terminated"})
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+				}
+			},
+			want: "cancelled",
+		},
+		{
+			name:      "not collected",
+			slotStart: missed,
+			release: func(t *testing.T, userID, employeeID, preorderID string) {
+				if _, err := ExpirePreOrders(context.Background(), client); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := bson.NewObjectID().Hex()
+			employeeID := bson.NewObjectID().Hex()
+			supplierID := bson.NewObjectID().Hex()
+			preorderID := bson.NewObjectID().Hex()
+			insertDocuments(t, client, "users", models.User{UserID: userID, Email: userID + "@example.com", Role: "EMPLOYEE"})
+			insertDocuments(t, client, "employees", models.Employee{
+				EmployeeID:     employeeID,
+				UserID:         userID,
+				EmployeeCode:   "E-" + employeeID,
+				Status:         "active",
+				CurrentBalance: 3,
+				HeldBalance:    2,
+			})
+			insertDocuments(t, client, "preorders", models.PreOrder{
+				PreOrderID:  preorderID,
+				EmployeeID:  employeeID,
+				SupplierID:  supplierID,
+				CouponsHeld: 2,
+				SlotStart:   tt.slotStart,
+				SlotEnd:     tt.slotStart.Add(30 * time.Minute),
+				Status:      "reserved",
+			})
+			// A second order keeps the slot from reaching zero by accident
+			if err := bookPickupSlot(context.Background(), client, supplierID, tt.slotStart, 2, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.release(t, userID, employeeID, preorderID)
+
+			var preorder models.PreOrder
+			findDocument(t, client, "preorders", bson.D{{Key: "preorder_id", Value: preorderID}}, &preorder)
+			if preorder.Status != tt.want {
+				t.Errorf("pre-order status = %q, want %q", preorder.Status, tt.want)
+			}
+			var slot struct {
+				OrderCount int `bson:"order_count"`
+			}
+			slotKey := supplierID + "|" + tt.slotStart.Format(time.RFC3339)
+			findDocument(t, client, "pickup_slots", bson.D{{Key: "_id", Value: slotKey}}, &slot)
+			if slot.OrderCount != 1 {
+				t.Errorf("slot order count = %d, want 1 after the release", slot.OrderCount)
+			}
+			var employee models.Employee
+			findDocument(t, client, "employees", bson.D{{Key: "employee_id", Value: employeeID}}, &employee)
+			if employee.HeldBalance != 0 || employee.CurrentBalance != 5 {
+				t.Errorf("balance = %d held %d, want 5 held 0", employee.CurrentBalance, employee.HeldBalance)
+			}
+		})
+	}
+}
+
+func TestCollectPreOrderOtherTender(t *testing.T) {
+	// An unknown tender is refused while binding, before the database is used
+	w := serve(t, CollectPreOrder(nil), http.MethodPost, "/preorders/collect", "/preorders/collect", "supplier", "SUPPLIER",
+		json.RawMessage(`{"code": "PREORDER-abc", "other_tender_method": "voucher"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown tender method: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	client := testClient(t)
+	supplierUserID := bson.NewObjectID().Hex()
+	supplierID := bson.NewObjectID().Hex()
+	employeeID := bson.NewObjectID().Hex()
+	preorderID := bson.NewObjectID().Hex()
+	code := bson.NewObjectID().Hex()
+	slotStart := time.Now().Truncate(time.Hour).UTC()
+	insertDocuments(t, client, "suppliers", models.Supplier{
+		SupplierID:   supplierID,
+		UserID:       supplierUserID,
+		BusinessName: "Canteen",
+		IsActive:     true,
+		IsVerified:   true,
+	})
+	insertDocuments(t, client, "employees", models.Employee{
+		EmployeeID:   employeeID,
+		UserID:       bson.NewObjectID().Hex(),
+		EmployeeCode: "E-" + employeeID,
+		Status:       "active",
+		HeldBalance:  2,
+	})
+	// Two coupons cover 90 of the 120 meal
+	insertDocuments(t, client, "preorders", models.PreOrder{
+		PreOrderID:  preorderID,
+		Code:        code,
+		EmployeeID:  employeeID,
+		SupplierID:  supplierID,
+		CouponsHeld: 2,
+		MealPrice:   120,
+		SlotStart:   slotStart,
+		SlotEnd:     slotStart.Add(2 * time.Hour),
+		Status:      "reserved",
+	})
+
+	collect := func(body gin.H) int {
+		w := serve(t, CollectPreOrder(client), http.MethodPost, "/preorders/collect", "/preorders/collect", supplierUserID, "SUPPLIER", body)
+		return w.Code
+	}
+	if status := collect(gin.H{"code": "PREORDER-" + code}); status != http.StatusBadRequest {
+		t.Fatalf("no tender method: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if status := collect(gin.H{"code": "PREORDER-" + code, "other_tender_method": "card"}); status != http.StatusOK {
+		t.Fatalf("paid by card: status = %d, want %d", status, http.StatusOK)
+	}
+
+	var transaction models.Transaction
+	findDocument(t, client, "transactions", bson.D{{Key: "preorder_id", Value: preorderID}}, &transaction)
+	if transaction.OtherTenderMethod != "card" || transaction.OtherTenderAmount != 30 {
+		t.Errorf("other tender = %v by %q, want 30 by card", transaction.OtherTenderAmount, transaction.OtherTenderMethod)
+	}
+}
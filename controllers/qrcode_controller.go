@@ -42,7 +42,7 @@ func GenerateQrCode(client *mongo.Client) gin.HandlerFunc {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Employee account is not active"})
 			return
 		}
-		if employee.CurrentBalance <= 0 {
+		if spendableCoupons(employee) <= 0 {
 			c.JSON(http.StatusForbidden, gin.H{"error": "No coupons available"})
 			return
 		}
@@ -93,7 +93,7 @@ func GenerateQrCode(client *mongo.Client) gin.HandlerFunc {
 			QRCodeImage:      "data:image/png;base64," + base64Image,
 			ExpiresAt:        expiresAt,
 			ExpiresInMinutes: expiryMinutes,
-			EmployeeBalance:  employee.CurrentBalance,
+			EmployeeBalance:  spendableCoupons(employee),
 		})
 	}
 }
@@ -112,6 +112,23 @@ func ValidateQRcode(client *mongo.Client) gin.HandlerFunc {
 		var qrCodeRecord models.QRCode
 		err := qrCollection.FindOne(ctx, bson.D{{Key: "code", Value: req.Code}}).Decode(&qrCodeRecord)
 		if err != nil {
+			if voucher, voucherErr := findGuestVoucher(ctx, client, req.Code); voucherErr == nil {
+				valid := voucher.Status == "active" && voucher.UsesRemaining > 0 && time.Now().Before(voucher.ExpiresAt)
+				message := "Guest voucher is valid"
+				if !valid {
+					message = "Guest voucher is no longer valid"
+				}
+				c.JSON(http.StatusOK, models.ValidateQRResponse{
+					Valid:          valid,
+					IsGuestVoucher: true,
+					GuestName:      voucher.GuestName,
+					UsesRemaining:  voucher.UsesRemaining,
+					CouponsPerUse:  voucher.CouponsPerUse,
+					ExpiresAt:      voucher.ExpiresAt,
+					Message:        message,
+				})
+				return
+			}
 			c.JSON(http.StatusOK, models.ValidateQRResponse{
 				Valid:   false,
 				Message: "Invaild QR code",
@@ -148,7 +165,7 @@ func ValidateQRcode(client *mongo.Client) gin.HandlerFunc {
 		}
 
 		// Check balance
-		if employee.CurrentBalance <= 0 {
+		if spendableCoupons(employee) <= 0 {
 			c.JSON(http.StatusOK, models.ValidateQRResponse{
 				Valid:   false,
 				Message: "Employee has no coupons available",
@@ -161,7 +178,7 @@ func ValidateQRcode(client *mongo.Client) gin.HandlerFunc {
 			EmployeeID:     employee.EmployeeID,
 			EmployeeName:   employee.Name,
 			EmployeeCode:   employee.EmployeeCode,
-			CurrentBalance: employee.CurrentBalance,
+			CurrentBalance: spendableCoupons(employee),
 			QRCodeID:       qrCodeRecord.QRCodeID,
 			ExpiresAt:      qrCodeRecord.ExpiresAt,
 			Message:        "QR code is valid",
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// dateRangeQuery turns the ?from= and ?to= dates (YYYY-MM-DD, both inclusive) into a range condition.
+// It returns an empty condition when neither is given.
+func dateRangeQuery(c *gin.Context) (bson.D, error) {
+	dateRange := bson.D{}
+	if from := c.Query("from"); from != "" {
+		fromDate, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, errors.New("Invalid from date. Use YYYY-MM-DD")
+		}
+		dateRange = append(dateRange, bson.E{Key: "$gte", Value: fromDate})
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, errors.New("Invalid to date. Use YYYY-MM-DD")
+		}
+		dateRange = append(dateRange, bson.E{Key: "$lt", Value: toDate.AddDate(0, 0, 1)})
+	}
+	return dateRange, nil
+}
+
+// GetMealFundingReport - Admin reports completed meals per month, split into company- and employee-funded coupons
+func GetMealFundingReport(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		match := bson.D{{Key: "status", Value: "completed"}}
+		processedAt, err := dateRangeQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(processedAt) > 0 {
+			match = append(match, bson.E{Key: "processed_at", Value: processedAt})
+		}
+		if supplierID := c.Query("supplier_id"); supplierID != "" {
+			match = append(match, bson.E{Key: "supplier_id", Value: supplierID})
+		}
+
+		// Meals settled before top-ups existed have no paid part and count as company funded
+		paidCoupons := bson.D{{Key: "$ifNull", Value: bson.A{"$paid_coupons", 0}}}
+		employeeFunded := bson.D{{Key: "$ifNull", Value: bson.A{"$employee_funded_amount", 0}}}
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+					{Key: "format", Value: "%Y-%m"},
+					{Key: "date", Value: "$processed_at"},
+				}}}},
+				{Key: "meals", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "company_coupons", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{"$coupons_used", paidCoupons}}}}}},
+				{Key: "paid_coupons", Value: bson.D{{Key: "$sum", Value: paidCoupons}}},
+				{Key: "company_funded_amount", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{"$total_amount", employeeFunded}}}}}},
+				{Key: "employee_funded_amount", Value: bson.D{{Key: "$sum", Value: employeeFunded}}},
+			}}},
+			{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		transactionCollection := database.OpenCollection("transactions", client)
+		cursor, err := transactionCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build funding report"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var rows []models.MealFundingReportRow
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode funding report"})
+			return
+		}
+
+		var totals models.MealFundingReportRow
+		totals.Period = "total"
+		for _, row := range rows {
+			totals.Meals += row.Meals
+			totals.CompanyCoupons += row.CompanyCoupons
+			totals.PaidCoupons += row.PaidCoupons
+			totals.CompanyFundedAmount += row.CompanyFundedAmount
+			totals.EmployeeFundedAmount += row.EmployeeFundedAmount
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rows":   rows,
+			"totals": totals,
+		})
+	}
+}
@@ -0,0 +1,1080 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	scimUserSchema       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema      = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimEnterpriseSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	scimEmployeeSchema   = "urn:ietf:params:scim:schemas:extension:couponmeal:2.0:User"
+	scimListSchema       = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema      = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	// scimActor is recorded as the actor of changes made by the identity provider
+	scimActor      = "scim"
+	scimMaxResults = 200
+)
+
+// scimEmployeeFields are the employee fields an identity provider attribute can be mapped to
+var scimEmployeeFields = []string{"employee_code", "phone", "hire_date", "job_grade", "shift_pattern", "employment_type", "manager_user_id"}
+
+// scimMultiValued are the SCIM attributes that hold a list of {value, primary} items
+var scimMultiValued = map[string]bool{"emails": true, "phonenumbers": true, "addresses": true, "members": true}
+
+var (
+	errSCIMUserNameTaken     = errors.New("userName is already used by another user")
+	errSCIMEmployeeCodeTaken = errors.New("employee code is already used by another employee")
+)
+
+// scimUserInput is a SCIM User resource reduced to what this service stores
+type scimUserInput struct {
+	Email      string
+	ExternalID string
+	FirstName  string
+	LastName   string
+	Active     bool
+	Fields     map[string]string // employee field to value, per the attribute mapping
+	HireDate   *time.Time
+}
+
+func defaultSCIMAttributeMapping() map[string]string {
+	return map[string]string{
+		"employee_code":   scimEnterpriseSchema + ":employeeNumber",
+		"phone":           "phoneNumbers",
+		"hire_date":       scimEmployeeSchema + ":hireDate",
+		"job_grade":       "title",
+		"shift_pattern":   scimEmployeeSchema + ":shiftPattern",
+		"employment_type": "userType",
+		"manager_user_id": scimEnterpriseSchema + ":manager.value",
+	}
+}
+
+// UpdateSCIMSettings - Admin sets which SCIM attributes fill the employee fields and which groups map to departments
+func UpdateSCIMSettings(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateSCIMSettingsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		for field, path := range req.AttributeMapping {
+			if !slices.Contains(scimEmployeeFields, field) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown employee field " + field, "fields": scimEmployeeFields})
+				return
+			}
+			if strings.TrimSpace(path) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A SCIM attribute path is required for " + field})
+				return
+			}
+		}
+		if req.AttributeMapping["employee_code"] == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "employee_code must be mapped to a SCIM attribute"})
+			return
+		}
+		if req.GroupDepartments == nil {
+			req.GroupDepartments = map[string]string{}
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		for _, departmentID := range req.GroupDepartments {
+			if !checkDepartmentAssignable(c, ctx, client, departmentID) {
+				return
+			}
+		}
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		// Existing group members are moved the next time the identity provider pushes the group
+		settings.SCIM.AttributeMapping = req.AttributeMapping
+		settings.SCIM.GroupDepartments = req.GroupDepartments
+		settings.UpdatedByUserID = adminUserID
+		settings.UpdatedAt = time.Now()
+
+		if err := saveOrganizationSettings(ctx, client, settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update SCIM settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "SCIM settings updated successfully",
+			"scim":    settings.SCIM,
+		})
+	}
+}
+
+// GetSCIMServiceProviderConfig - Tells SCIM clients which protocol features are supported
+func GetSCIMServiceProviderConfig() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scimJSON(c, http.StatusOK, gin.H{
+			"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+			"patch":          gin.H{"supported": true},
+			"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+			"filter":         gin.H{"supported": true, "maxResults": scimMaxResults},
+			"changePassword": gin.H{"supported": false},
+			"sort":           gin.H{"supported": false},
+			"etag":           gin.H{"supported": false},
+			"authenticationSchemes": []gin.H{{
+				"type":        "oauthbearertoken",
+				"name":        "Bearer token",
+				"description": "The token configured as SCIM_BEARER_TOKEN",
+				"primary":     true,
+			}},
+		})
+	}
+}
+
+// GetSCIMUsers - Lists employee users (?filter=userName eq "..." | externalId eq "...", ?startIndex=, ?count=)
+func GetSCIMUsers(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attribute, value, err := parseSCIMFilter(c.Query("filter"))
+		if err != nil {
+			scimError(c, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+		filter := bson.D{{Key: "role", Value: "EMPLOYEE"}}
+		switch strings.ToLower(attribute) {
+		case "":
+		case "username", "emails", "emails.value":
+			filter = append(filter, bson.E{Key: "email", Value: utils.NormalizeEmail(value)})
+		case "externalid":
+			filter = append(filter, bson.E{Key: "external_id", Value: value})
+		case "id":
+			filter = append(filter, bson.E{Key: "user_id", Value: value})
+		default:
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Filtering on "+attribute+" is not supported")
+			return
+		}
+		startIndex, count := scimPage(c)
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to fetch settings")
+			return
+		}
+
+		userCollection := database.OpenCollection("users", client)
+		total, err := userCollection.CountDocuments(ctx, filter)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to count users")
+			return
+		}
+		var users []models.User
+		if count > 0 {
+			cursor, err := userCollection.Find(
+				ctx,
+				filter,
+				options.Find().
+					SetSort(bson.D{{Key: "created_at", Value: 1}}).
+					SetSkip(int64(startIndex-1)).
+					SetLimit(int64(count)),
+			)
+			if err != nil {
+				scimError(c, http.StatusInternalServerError, "", "Failed to fetch users")
+				return
+			}
+			defer cursor.Close(ctx)
+
+			if err = cursor.All(ctx, &users); err != nil {
+				scimError(c, http.StatusInternalServerError, "", "Failed to decode users")
+				return
+			}
+		}
+
+		userIDs := make([]string, 0, len(users))
+		for _, user := range users {
+			userIDs = append(userIDs, user.UserID)
+		}
+		employees, managers, err := loadSCIMEmployees(ctx, client, userIDs)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to fetch employees")
+			return
+		}
+
+		resources := make([]interface{}, 0, len(users))
+		for _, user := range users {
+			employee, found := employees[user.UserID]
+			if !found {
+				resources = append(resources, scimUserResource(user, nil, "", settings.SCIM.AttributeMapping))
+				continue
+			}
+			resources = append(resources, scimUserResource(user, &employee, managers[employee.ManagerEmployeeID], settings.SCIM.AttributeMapping))
+		}
+
+		scimJSON(c, http.StatusOK, models.SCIMListResponse{
+			Schemas:      []string{scimListSchema},
+			TotalResults: int(total),
+			StartIndex:   startIndex,
+			ItemsPerPage: len(resources),
+			Resources:    resources,
+		})
+	}
+}
+
+// GetSCIMUser - Returns one employee user as a SCIM User
+func GetSCIMUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to fetch settings")
+			return
+		}
+		resource, ok := findSCIMUserResource(c, ctx, client, c.Param("id"), settings)
+		if !ok {
+			return
+		}
+		scimJSON(c, http.StatusOK, resource)
+	}
+}
+
+// CreateSCIMUser - Provisions a user account and its employee record in one step.
+// The account has no usable password until an admin issues an invitation for it.
+func CreateSCIMUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var resource map[string]interface{}
+		if err := c.ShouldBindJSON(&resource); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to fetch settings")
+			return
+		}
+		input, err := readSCIMUser(resource, settings.SCIM.AttributeMapping)
+		if err != nil {
+			scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+
+		userCollection := database.OpenCollection("users", client)
+		count, err := userCollection.CountDocuments(ctx, bson.D{{Key: "email", Value: input.Email}})
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to check existing user")
+			return
+		}
+		if count > 0 {
+			scimError(c, http.StatusConflict, "uniqueness", errSCIMUserNameTaken.Error())
+			return
+		}
+
+		now := time.Now()
+		user, err := newInvitedUser(input.FirstName, input.LastName, input.Email, "EMPLOYEE", now)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Unable to hash password")
+			return
+		}
+		user.ExternalID = input.ExternalID
+
+		session, err := client.StartSession()
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to start database session")
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			if _, err := userCollection.InsertOne(sessCtx, user); err != nil {
+				return nil, err
+			}
+			return nil, saveSCIMUser(sessCtx, client, user, nil, input, settings, now)
+		})
+		if errors.Is(err, errSCIMEmployeeCodeTaken) {
+			scimError(c, http.StatusConflict, "uniqueness", err.Error())
+			return
+		}
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to create user")
+			return
+		}
+
+		created, ok := findSCIMUserResource(c, ctx, client, user.UserID, settings)
+		if !ok {
+			return
+		}
+		c.Header("Location", scimLocation(c, "Users", user.UserID))
+		scimJSON(c, http.StatusCreated, created)
+	}
+}
+
+// ReplaceSCIMUser - Replaces a user's attributes. Mapped employee attributes that are left out keep their value.
+func ReplaceSCIMUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var resource map[string]interface{}
+		if err := c.ShouldBindJSON(&resource); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+		updateSCIMUser(c, client, func(map[string]interface{}) (map[string]interface{}, error) {
+			return resource, nil
+		})
+	}
+}
+
+// PatchSCIMUser - Applies SCIM PatchOp operations to a user, e.g. {"op": "replace", "path": "active", "value": false}
+func PatchSCIMUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.SCIMPatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+		updateSCIMUser(c, client, func(resource map[string]interface{}) (map[string]interface{}, error) {
+			for _, operation := range req.Operations {
+				if err := applySCIMPatch(resource, operation); err != nil {
+					return nil, err
+				}
+			}
+			return resource, nil
+		})
+	}
+}
+
+// DeleteSCIMUser - Deactivates the user and terminates the employee. Records are kept for reporting,
+// so the user can still be read and reactivated with active = true.
+func DeleteSCIMUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		updateSCIMUser(c, client, func(resource map[string]interface{}) (map[string]interface{}, error) {
+			resource["active"] = false
+			return resource, nil
+		})
+	}
+}
+
+// updateSCIMUser loads the user as a SCIM resource, lets change turn it into the new resource and
+// stores the result, writing the SCIM response
+func updateSCIMUser(c *gin.Context, client *mongo.Client, change func(map[string]interface{}) (map[string]interface{}, error)) {
+	userID := c.Param("id")
+
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+	defer cancel()
+
+	settings, err := loadOrganizationSettings(ctx, client)
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to fetch settings")
+		return
+	}
+
+	user, employee, managerUserID, err := loadSCIMUser(ctx, client, userID)
+	if err == mongo.ErrNoDocuments {
+		scimError(c, http.StatusNotFound, "", "User not found")
+		return
+	}
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to fetch user")
+		return
+	}
+
+	resource, err := change(scimUserResource(user, employee, managerUserID, settings.SCIM.AttributeMapping))
+	if err != nil {
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	input, err := readSCIMUser(resource, settings.SCIM.AttributeMapping)
+	if err != nil {
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to start database session")
+		return
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		return nil, saveSCIMUser(sessCtx, client, user, employee, input, settings, time.Now())
+	})
+	if errors.Is(err, errSCIMUserNameTaken) || errors.Is(err, errSCIMEmployeeCodeTaken) {
+		scimError(c, http.StatusConflict, "uniqueness", err.Error())
+		return
+	}
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to update user")
+		return
+	}
+
+	if c.Request.Method == http.MethodDelete {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	updated, ok := findSCIMUserResource(c, ctx, client, userID, settings)
+	if !ok {
+		return
+	}
+	scimJSON(c, http.StatusOK, updated)
+}
+
+// saveSCIMUser writes a SCIM user onto the user account and its employee record, creating the employee
+// if the account has none. Deactivation terminates the employee with the same balance rules as UpdateEmployee.
+// It must be called inside a session transaction.
+func saveSCIMUser(ctx context.Context, client *mongo.Client, user models.User, employee *models.Employee, input scimUserInput, settings models.OrganizationSettings, now time.Time) error {
+	userCollection := database.OpenCollection("users", client)
+	employeeCollection := database.OpenCollection("employees", client)
+
+	if input.Email != user.Email {
+		count, err := userCollection.CountDocuments(ctx, bson.D{{Key: "email", Value: input.Email}})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errSCIMUserNameTaken
+		}
+	}
+	_, err := userCollection.UpdateOne(ctx, bson.D{{Key: "user_id", Value: user.UserID}}, bson.D{{Key: "$set", Value: bson.M{
+		"first_name":  input.FirstName,
+		"last_name":   input.LastName,
+		"email":       input.Email,
+		"external_id": input.ExternalID,
+		"update_at":   now,
+	}}})
+	if err != nil {
+		return err
+	}
+
+	code := input.Fields["employee_code"]
+	if employee == nil || code != employee.EmployeeCode {
+		count, err := employeeCollection.CountDocuments(ctx, bson.D{{Key: "employee_code", Value: code}})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errSCIMEmployeeCodeTaken
+		}
+	}
+
+	managerEmployeeID := ""
+	if managerUserID := input.Fields["manager_user_id"]; managerUserID != "" && managerUserID != user.UserID {
+		// A manager the identity provider has not pushed yet is linked on a later update
+		var manager models.Employee
+		err := employeeCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: managerUserID}}).Decode(&manager)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		managerEmployeeID = manager.EmployeeID
+	}
+
+	name := strings.TrimSpace(input.FirstName + " " + input.LastName)
+	if employee == nil {
+		hireDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if input.HireDate != nil {
+			hireDate = *input.HireDate
+		}
+		created := models.Employee{
+			EmployeeID:        bson.NewObjectID().Hex(),
+			UserID:            user.UserID,
+			EmployeeCode:      code,
+			Name:              name,
+			Email:             input.Email,
+			Phone:             input.Fields["phone"],
+			Status:            "active",
+			ManagerEmployeeID: managerEmployeeID,
+			JobGrade:          input.Fields["job_grade"],
+			ShiftPattern:      input.Fields["shift_pattern"],
+			EmploymentType:    input.Fields["employment_type"],
+			HireDate:          hireDate,
+			CreatedByAdminID:  scimActor,
+			IsVerified:        true,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+		if _, _, err := insertNewEmployee(ctx, client, created, settings, scimActor, now); err != nil {
+			return err
+		}
+		err := recordAudit(ctx, client, scimActor, "scim.employee_created", "employee", created.EmployeeID, bson.M{
+			"user_id":     user.UserID,
+			"external_id": input.ExternalID,
+		})
+		if err != nil {
+			return err
+		}
+		employee = &created
+	} else {
+		update := bson.M{
+			"employee_code": code,
+			"name":          name,
+			"email":         input.Email,
+			"updated_at":    now,
+		}
+		for field, value := range map[string]string{
+			"phone":           input.Fields["phone"],
+			"job_grade":       input.Fields["job_grade"],
+			"shift_pattern":   input.Fields["shift_pattern"],
+			"employment_type": input.Fields["employment_type"],
+		} {
+			if value != "" {
+				update[field] = value
+			}
+		}
+		if managerEmployeeID != "" {
+			update["manager_employee_id"] = managerEmployeeID
+		}
+		if input.HireDate != nil {
+			update["hire_date"] = *input.HireDate
+		}
+		_, err := employeeCollection.UpdateOne(ctx, bson.D{{Key: "employee_id", Value: employee.EmployeeID}}, bson.D{{Key: "$set", Value: update}})
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case !input.Active && user.DeactivatedAt == nil:
+		_, err := userCollection.UpdateOne(ctx, bson.D{{Key: "user_id", Value: user.UserID}}, bson.D{{Key: "$set", Value: bson.M{"deactivated_at": now}}})
+		if err != nil {
+			return err
+		}
+		if err := revokeUserSessions(ctx, client, user.UserID, "deactivated", now); err != nil {
+			return err
+		}
+		if err := setEmployeeStatus(ctx, client, *employee, "terminated", settings, now); err != nil {
+			return err
+		}
+		return recordAudit(ctx, client, scimActor, "scim.user_deactivated", "employee", employee.EmployeeID, bson.M{"user_id": user.UserID})
+
+	case input.Active && user.DeactivatedAt != nil:
+		_, err := userCollection.UpdateOne(ctx, bson.D{{Key: "user_id", Value: user.UserID}}, bson.D{{Key: "$unset", Value: bson.M{"deactivated_at": ""}}})
+		if err != nil {
+			return err
+		}
+		if err := setEmployeeStatus(ctx, client, *employee, "active", settings, now); err != nil {
+			return err
+		}
+		return recordAudit(ctx, client, scimActor, "scim.user_reactivated", "employee", employee.EmployeeID, bson.M{"user_id": user.UserID})
+	}
+	return nil
+}
+
+// setEmployeeStatus changes an employee's status the way UpdateEmployee does, stamping the
+// termination date and adjusting the balance. It must be called inside a session transaction.
+func setEmployeeStatus(ctx context.Context, client *mongo.Client, employee models.Employee, status string, settings models.OrganizationSettings, now time.Time) error {
+	if employee.Status == status {
+		return nil
+	}
+	update := bson.M{"status": status, "updated_at": now}
+	if status == "terminated" {
+		update["termination_date"] = now
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	var previous models.Employee
+	err := employeeCollection.FindOneAndUpdate(
+		ctx,
+		bson.D{{Key: "employee_id", Value: employee.EmployeeID}},
+		bson.D{{Key: "$set", Value: update}},
+	).Decode(&previous)
+	if err != nil {
+		return err
+	}
+	return applyStatusChange(ctx, client, previous, status, settings, now)
+}
+
+// readSCIMUser pulls the stored fields out of a SCIM User resource. The email comes from the
+// primary email, or from userName when no email is sent.
+func readSCIMUser(resource map[string]interface{}, mapping map[string]string) (scimUserInput, error) {
+	input := scimUserInput{
+		Email:      utils.NormalizeEmail(scimAttribute(resource, "emails")),
+		ExternalID: scimAttribute(resource, "externalId"),
+		FirstName:  scimAttribute(resource, "name.givenName"),
+		LastName:   scimAttribute(resource, "name.familyName"),
+		Active:     scimBool(scimLookup(resource, "active"), true),
+		Fields:     map[string]string{},
+	}
+	userName := utils.NormalizeEmail(scimAttribute(resource, "userName"))
+	if userName == "" {
+		return input, errors.New("userName is required")
+	}
+	if input.Email == "" {
+		input.Email = userName
+	}
+	if validator.New().Var(input.Email, "email") != nil {
+		return input, errors.New("a valid email address is required in emails or userName")
+	}
+	if input.FirstName == "" || input.LastName == "" {
+		if parts := strings.Fields(scimAttribute(resource, "displayName")); len(parts) > 1 {
+			input.FirstName = parts[0]
+			input.LastName = strings.Join(parts[1:], " ")
+		}
+	}
+	if input.FirstName == "" || input.LastName == "" {
+		return input, errors.New("name.givenName and name.familyName are required")
+	}
+
+	for field, path := range mapping {
+		input.Fields[field] = scimAttribute(resource, path)
+	}
+	if input.Fields["employee_code"] == "" {
+		return input, fmt.Errorf("%s is required as the employee code", mapping["employee_code"])
+	}
+	if value := input.Fields["hire_date"]; value != "" {
+		hireDate, err := parseSCIMDate(value)
+		if err != nil {
+			return input, fmt.Errorf("%s is not a date", mapping["hire_date"])
+		}
+		input.HireDate = &hireDate
+	}
+	return input, nil
+}
+
+// scimUserResource renders a user and its employee record as a SCIM User,
+// placing the employee fields at their mapped attribute paths
+func scimUserResource(user models.User, employee *models.Employee, managerUserID string, mapping map[string]string) map[string]interface{} {
+	resource := map[string]interface{}{
+		"schemas":     []interface{}{scimUserSchema, scimEnterpriseSchema, scimEmployeeSchema},
+		"id":          user.UserID,
+		"userName":    user.Email,
+		"displayName": strings.TrimSpace(user.FirstName + " " + user.LastName),
+		"name": map[string]interface{}{
+			"givenName":  user.FirstName,
+			"familyName": user.LastName,
+			"formatted":  strings.TrimSpace(user.FirstName + " " + user.LastName),
+		},
+		"emails": []interface{}{
+			map[string]interface{}{"value": user.Email, "type": "work", "primary": true},
+		},
+		"active": user.DeactivatedAt == nil,
+		"meta": map[string]interface{}{
+			"resourceType": "User",
+			"created":      user.CreatedAt,
+			"lastModified": user.UpdatedAt,
+			"location":     "/scim/v2/Users/" + user.UserID,
+		},
+	}
+	if user.ExternalID != "" {
+		resource["externalId"] = user.ExternalID
+	}
+	if employee == nil {
+		return resource
+	}
+
+	values := map[string]string{
+		"employee_code":   employee.EmployeeCode,
+		"phone":           employee.Phone,
+		"hire_date":       employee.HireDate.Format("2006-01-02"),
+		"job_grade":       employee.JobGrade,
+		"shift_pattern":   employee.ShiftPattern,
+		"employment_type": employee.EmploymentType,
+		"manager_user_id": managerUserID,
+	}
+	for field, path := range mapping {
+		if values[field] != "" {
+			setSCIMAttribute(resource, path, values[field])
+		}
+	}
+	return resource
+}
+
+// findSCIMUserResource loads a user as a SCIM resource, writing the SCIM error response if it cannot
+func findSCIMUserResource(c *gin.Context, ctx context.Context, client *mongo.Client, userID string, settings models.OrganizationSettings) (map[string]interface{}, bool) {
+	user, employee, managerUserID, err := loadSCIMUser(ctx, client, userID)
+	if err == mongo.ErrNoDocuments {
+		scimError(c, http.StatusNotFound, "", "User not found")
+		return nil, false
+	}
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to fetch user")
+		return nil, false
+	}
+	return scimUserResource(user, employee, managerUserID, settings.SCIM.AttributeMapping), true
+}
+
+// loadSCIMUser loads an employee user with its employee record, if any, and its manager's user ID
+func loadSCIMUser(ctx context.Context, client *mongo.Client, userID string) (models.User, *models.Employee, string, error) {
+	userCollection := database.OpenCollection("users", client)
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.D{
+		{Key: "user_id", Value: userID},
+		{Key: "role", Value: "EMPLOYEE"},
+	}).Decode(&user)
+	if err != nil {
+		return user, nil, "", err
+	}
+
+	employees, managers, err := loadSCIMEmployees(ctx, client, []string{userID})
+	if err != nil {
+		return user, nil, "", err
+	}
+	employee, found := employees[userID]
+	if !found {
+		return user, nil, "", nil
+	}
+	return user, &employee, managers[employee.ManagerEmployeeID], nil
+}
+
+// loadSCIMEmployees loads the employee records of the given users by user ID, and the user IDs of
+// their managers by manager employee ID
+func loadSCIMEmployees(ctx context.Context, client *mongo.Client, userIDs []string) (map[string]models.Employee, map[string]string, error) {
+	employees := map[string]models.Employee{}
+	managers := map[string]string{}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	cursor, err := employeeCollection.Find(ctx, bson.D{{Key: "user_id", Value: bson.D{{Key: "$in", Value: userIDs}}}})
+	if err != nil {
+		return nil, nil, err
+	}
+	var found []models.Employee
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, nil, err
+	}
+
+	var managerIDs []string
+	for _, employee := range found {
+		employees[employee.UserID] = employee
+		if employee.ManagerEmployeeID != "" {
+			managerIDs = append(managerIDs, employee.ManagerEmployeeID)
+		}
+	}
+	if len(managerIDs) == 0 {
+		return employees, managers, nil
+	}
+
+	cursor, err = employeeCollection.Find(ctx, bson.D{{Key: "employee_id", Value: bson.D{{Key: "$in", Value: managerIDs}}}})
+	if err != nil {
+		return nil, nil, err
+	}
+	var managerRecords []models.Employee
+	if err = cursor.All(ctx, &managerRecords); err != nil {
+		return nil, nil, err
+	}
+	for _, manager := range managerRecords {
+		managers[manager.EmployeeID] = manager.UserID
+	}
+	return employees, managers, nil
+}
+
+// applySCIMPatch applies one PatchOp operation to a resource. Filters in a path, such as
+// emails[type eq "work"].value, address the primary item of the attribute.
+func applySCIMPatch(resource map[string]interface{}, operation models.SCIMPatchOperation) error {
+	switch strings.ToLower(operation.Op) {
+	case "add", "replace":
+		if operation.Path == "" {
+			values, ok := operation.Value.(map[string]interface{})
+			if !ok {
+				return errors.New("value must be an object when path is omitted")
+			}
+			for path, value := range values {
+				setSCIMAttribute(resource, path, value)
+			}
+			return nil
+		}
+		setSCIMAttribute(resource, operation.Path, operation.Value)
+		return nil
+	case "remove":
+		if operation.Path == "" {
+			return errors.New("path is required for remove")
+		}
+		setSCIMAttribute(resource, operation.Path, nil)
+		return nil
+	}
+	return errors.New("unsupported patch op " + operation.Op)
+}
+
+// scimAttribute reads an attribute path such as "name.givenName", "emails" or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value" as text.
+// Multi-valued attributes give the value of their primary item.
+func scimAttribute(resource map[string]interface{}, path string) string {
+	current := resource
+	schema, path := splitSCIMPath(path)
+	if schema != "" {
+		current, _ = scimLookup(resource, schema).(map[string]interface{})
+	}
+	if path == "" {
+		return ""
+	}
+
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		value := scimLookup(current, scimAttributeName(part))
+		if list, ok := value.([]interface{}); ok {
+			value = nil
+			if item := scimPrimary(list); item != nil {
+				value = item
+			}
+		}
+
+		next, isObject := value.(map[string]interface{})
+		if i == len(parts)-1 {
+			if isObject {
+				return scimString(scimLookup(next, "value"))
+			}
+			return scimString(value)
+		}
+		if !isObject {
+			// Some clients send a complex attribute such as manager as its bare value
+			if i == len(parts)-2 && strings.EqualFold(parts[i+1], "value") {
+				return scimString(value)
+			}
+			return ""
+		}
+		current = next
+	}
+	return ""
+}
+
+// setSCIMAttribute writes value at an attribute path, creating the objects along the way.
+// A nil value removes the attribute.
+func setSCIMAttribute(resource map[string]interface{}, path string, value interface{}) {
+	current := resource
+	schema, path := splitSCIMPath(path)
+	if schema != "" {
+		extension, ok := scimLookup(resource, schema).(map[string]interface{})
+		if path == "" {
+			if values, isObject := value.(map[string]interface{}); isObject && ok {
+				for key, item := range values {
+					scimSet(extension, key, item)
+				}
+				return
+			}
+			scimSet(resource, schema, value)
+			return
+		}
+		if !ok {
+			extension = map[string]interface{}{}
+			scimSet(resource, schema, extension)
+		}
+		current = extension
+	}
+
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		name := scimAttributeName(part)
+		switch next := scimLookup(current, name).(type) {
+		case map[string]interface{}:
+			current = next
+		case []interface{}:
+			item := scimPrimary(next)
+			if item == nil {
+				item = map[string]interface{}{"primary": true}
+				scimSet(current, name, append(next, item))
+			}
+			current = item
+		default:
+			item := map[string]interface{}{}
+			if scimMultiValued[strings.ToLower(name)] {
+				item["primary"] = true
+				scimSet(current, name, []interface{}{item})
+			} else {
+				scimSet(current, name, item)
+			}
+			current = item
+		}
+	}
+
+	name := scimAttributeName(parts[len(parts)-1])
+	if value != nil && scimMultiValued[strings.ToLower(name)] {
+		switch item := value.(type) {
+		case []interface{}:
+		case map[string]interface{}:
+			value = []interface{}{item}
+		default:
+			value = []interface{}{map[string]interface{}{"value": item, "primary": true}}
+		}
+	}
+	scimSet(current, name, value)
+}
+
+// splitSCIMPath separates an extension schema URN from the attribute path after it.
+// Core schema URNs are dropped, their attributes live at the top level.
+func splitSCIMPath(path string) (string, string) {
+	for _, schema := range []string{scimUserSchema, scimGroupSchema} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+			return "", path[len(schema)+1:]
+		}
+	}
+	for _, schema := range []string{scimEnterpriseSchema, scimEmployeeSchema} {
+		if strings.EqualFold(path, schema) {
+			return schema, ""
+		}
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+			return schema, path[len(schema)+1:]
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		i := strings.LastIndex(path, ":")
+		return path[:i], path[i+1:]
+	}
+	return "", path
+}
+
+// scimAttributeName drops a value filter such as [type eq "work"] from a path segment
+func scimAttributeName(part string) string {
+	if i := strings.Index(part, "["); i >= 0 {
+		return part[:i]
+	}
+	return part
+}
+
+// scimLookup reads a key the way SCIM compares attribute names, ignoring case
+func scimLookup(object map[string]interface{}, key string) interface{} {
+	if value, found := object[key]; found {
+		return value
+	}
+	for name, value := range object {
+		if strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return nil
+}
+
+// scimSet replaces a key ignoring case, or removes it when value is nil
+func scimSet(object map[string]interface{}, key string, value interface{}) {
+	for name := range object {
+		if strings.EqualFold(name, key) {
+			delete(object, name)
+		}
+	}
+	if value != nil {
+		object[key] = value
+	}
+}
+
+// scimPrimary returns the primary item of a multi-valued attribute, or its first item
+func scimPrimary(list []interface{}) map[string]interface{} {
+	var first map[string]interface{}
+	for _, value := range list {
+		item, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if scimBool(scimLookup(item, "primary"), false) {
+			return item
+		}
+		if first == nil {
+			first = item
+		}
+	}
+	return first
+}
+
+func scimString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// scimBool reads a boolean, accepting the "True"/"False" strings some identity providers send
+func scimBool(value interface{}, fallback bool) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		if parsed, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(v))); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// parseSCIMDate accepts a SCIM dateTime or a plain YYYY-MM-DD date
+func parseSCIMDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseSCIMFilter reads the only filter form supported: attribute eq "value"
+func parseSCIMFilter(filter string) (string, string, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return "", "", nil
+	}
+	parts := strings.SplitN(filter, " ", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[1], "eq") {
+		return "", "", errors.New(`Only filters of the form attribute eq "value" are supported`)
+	}
+	value := strings.TrimSpace(parts[2])
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	return parts[0], value, nil
+}
+
+// scimPage reads the 1-based startIndex and count query parameters, clamping them as SCIM requires
+func scimPage(c *gin.Context) (int, int) {
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", "100"))
+	if err != nil || count < 0 {
+		count = 0
+	}
+	if count > scimMaxResults {
+		count = scimMaxResults
+	}
+	return startIndex, count
+}
+
+func scimLocation(c *gin.Context, resourceType, id string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/scim/v2/%s/%s", scheme, c.Request.Host, resourceType, id)
+}
+
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", "application/scim+json; charset=utf-8")
+	c.JSON(status, body)
+}
+
+func scimError(c *gin.Context, status int, scimType, detail string) {
+	body := gin.H{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	scimJSON(c, status, body)
+}
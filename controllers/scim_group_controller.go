@@ -0,0 +1,495 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	errSCIMGroupNameTaken = errors.New("displayName is already used by another group")
+	errSCIMUnknownMember  = errors.New("members must be provisioned users")
+)
+
+// GetSCIMGroups - Lists groups (?filter=displayName eq "..." | externalId eq "...", ?startIndex=, ?count=)
+func GetSCIMGroups(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attribute, value, err := parseSCIMFilter(c.Query("filter"))
+		if err != nil {
+			scimError(c, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+		filter := bson.D{}
+		switch strings.ToLower(attribute) {
+		case "":
+		case "displayname":
+			filter = append(filter, bson.E{Key: "display_name", Value: value})
+		case "externalid":
+			filter = append(filter, bson.E{Key: "external_id", Value: value})
+		case "id":
+			filter = append(filter, bson.E{Key: "group_id", Value: value})
+		default:
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Filtering on "+attribute+" is not supported")
+			return
+		}
+		startIndex, count := scimPage(c)
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		groupCollection := database.OpenCollection("scim_groups", client)
+		total, err := groupCollection.CountDocuments(ctx, filter)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to count groups")
+			return
+		}
+		var groups []models.SCIMGroup
+		if count > 0 {
+			cursor, err := groupCollection.Find(
+				ctx,
+				filter,
+				options.Find().
+					SetSort(bson.D{{Key: "created_at", Value: 1}}).
+					SetSkip(int64(startIndex-1)).
+					SetLimit(int64(count)),
+			)
+			if err != nil {
+				scimError(c, http.StatusInternalServerError, "", "Failed to fetch groups")
+				return
+			}
+			defer cursor.Close(ctx)
+
+			if err = cursor.All(ctx, &groups); err != nil {
+				scimError(c, http.StatusInternalServerError, "", "Failed to decode groups")
+				return
+			}
+		}
+
+		resources := make([]interface{}, 0, len(groups))
+		for _, group := range groups {
+			resources = append(resources, scimGroupResource(group))
+		}
+
+		scimJSON(c, http.StatusOK, models.SCIMListResponse{
+			Schemas:      []string{scimListSchema},
+			TotalResults: int(total),
+			StartIndex:   startIndex,
+			ItemsPerPage: len(resources),
+			Resources:    resources,
+		})
+	}
+}
+
+// GetSCIMGroup - Returns one group with its members
+func GetSCIMGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		group, err := findSCIMGroup(ctx, client, c.Param("id"))
+		if err == mongo.ErrNoDocuments {
+			scimError(c, http.StatusNotFound, "", "Group not found")
+			return
+		}
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to fetch group")
+			return
+		}
+		scimJSON(c, http.StatusOK, scimGroupResource(group))
+	}
+}
+
+// CreateSCIMGroup - Stores a group pushed by the identity provider.
+// Members of a group mapped to a department are moved into that department.
+func CreateSCIMGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var resource map[string]interface{}
+		if err := c.ShouldBindJSON(&resource); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+
+		now := time.Now()
+		group := models.SCIMGroup{
+			GroupID:       bson.NewObjectID().Hex(),
+			DisplayName:   scimAttribute(resource, "displayName"),
+			ExternalID:    scimAttribute(resource, "externalId"),
+			MemberUserIDs: scimGroupMembers(scimLookup(resource, "members")),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if group.DisplayName == "" {
+			scimError(c, http.StatusBadRequest, "invalidValue", "displayName is required")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		saveSCIMGroup(c, ctx, client, nil, group)
+	}
+}
+
+// ReplaceSCIMGroup - Replaces a group's name and members
+func ReplaceSCIMGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var resource map[string]interface{}
+		if err := c.ShouldBindJSON(&resource); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		previous, ok := findSCIMGroupForUpdate(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		group := previous
+		group.DisplayName = scimAttribute(resource, "displayName")
+		group.ExternalID = scimAttribute(resource, "externalId")
+		group.MemberUserIDs = scimGroupMembers(scimLookup(resource, "members"))
+		group.UpdatedAt = time.Now()
+		if group.DisplayName == "" {
+			scimError(c, http.StatusBadRequest, "invalidValue", "displayName is required")
+			return
+		}
+
+		saveSCIMGroup(c, ctx, client, &previous, group)
+	}
+}
+
+// PatchSCIMGroup - Adds or removes members, or renames a group, with SCIM PatchOp operations
+func PatchSCIMGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.SCIMPatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		previous, ok := findSCIMGroupForUpdate(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		group := previous
+		group.MemberUserIDs = slices.Clone(previous.MemberUserIDs)
+		for _, operation := range req.Operations {
+			if err := applySCIMGroupPatch(&group, operation); err != nil {
+				scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+				return
+			}
+		}
+		if group.DisplayName == "" {
+			scimError(c, http.StatusBadRequest, "invalidValue", "displayName is required")
+			return
+		}
+		group.UpdatedAt = time.Now()
+
+		saveSCIMGroup(c, ctx, client, &previous, group)
+	}
+}
+
+// DeleteSCIMGroup - Removes a group. Its members keep their current department.
+func DeleteSCIMGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		groupCollection := database.OpenCollection("scim_groups", client)
+		result, err := groupCollection.DeleteOne(ctx, bson.D{{Key: "group_id", Value: c.Param("id")}})
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to delete group")
+			return
+		}
+		if result.DeletedCount == 0 {
+			scimError(c, http.StatusNotFound, "", "Group not found")
+			return
+		}
+
+		recordAudit(ctx, client, scimActor, "scim.group_deleted", "scim_group", c.Param("id"), bson.M{})
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// saveSCIMGroup inserts a new group, or replaces previous, and moves the members the change puts into
+// a mapped department. It writes the SCIM response.
+func saveSCIMGroup(c *gin.Context, ctx context.Context, client *mongo.Client, previous *models.SCIMGroup, group models.SCIMGroup) {
+	settings, err := loadOrganizationSettings(ctx, client)
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to fetch settings")
+		return
+	}
+
+	if group.MemberUserIDs == nil {
+		group.MemberUserIDs = []string{}
+	}
+
+	// Everyone is moved when the group is new or renamed, otherwise only members who just joined
+	moved := group.MemberUserIDs
+	if previous != nil && previous.DisplayName == group.DisplayName {
+		moved = nil
+		for _, userID := range group.MemberUserIDs {
+			if !slices.Contains(previous.MemberUserIDs, userID) {
+				moved = append(moved, userID)
+			}
+		}
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to start database session")
+		return
+	}
+	defer session.EndSession(ctx)
+
+	groupCollection := database.OpenCollection("scim_groups", client)
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		nameFilter := bson.D{{Key: "display_name", Value: group.DisplayName}}
+		if previous != nil {
+			nameFilter = append(nameFilter, bson.E{Key: "group_id", Value: bson.D{{Key: "$ne", Value: group.GroupID}}})
+		}
+		count, err := groupCollection.CountDocuments(sessCtx, nameFilter)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errSCIMGroupNameTaken
+		}
+
+		userCollection := database.OpenCollection("users", client)
+		count, err = userCollection.CountDocuments(sessCtx, bson.D{
+			{Key: "user_id", Value: bson.D{{Key: "$in", Value: group.MemberUserIDs}}},
+			{Key: "role", Value: "EMPLOYEE"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if int(count) != len(group.MemberUserIDs) {
+			return nil, errSCIMUnknownMember
+		}
+
+		if previous == nil {
+			_, err = groupCollection.InsertOne(sessCtx, group)
+		} else {
+			_, err = groupCollection.UpdateOne(sessCtx, bson.D{{Key: "group_id", Value: group.GroupID}}, bson.D{{Key: "$set", Value: bson.M{
+				"display_name":    group.DisplayName,
+				"external_id":     group.ExternalID,
+				"member_user_ids": group.MemberUserIDs,
+				"updated_at":      group.UpdatedAt,
+			}}})
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, moveSCIMGroupMembers(sessCtx, client, settings.SCIM.GroupDepartments[group.DisplayName], moved, group.UpdatedAt)
+	})
+	switch {
+	case errors.Is(err, errSCIMGroupNameTaken):
+		scimError(c, http.StatusConflict, "uniqueness", err.Error())
+		return
+	case errors.Is(err, errSCIMUnknownMember):
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	case err != nil:
+		scimError(c, http.StatusInternalServerError, "", "Failed to save group")
+		return
+	}
+
+	if previous == nil {
+		c.Header("Location", scimLocation(c, "Groups", group.GroupID))
+		scimJSON(c, http.StatusCreated, scimGroupResource(group))
+		return
+	}
+	scimJSON(c, http.StatusOK, scimGroupResource(group))
+}
+
+// moveSCIMGroupMembers moves the employees of the given users into departmentID, opening a new
+// department assignment for each one not already there. It must be called inside a session transaction.
+func moveSCIMGroupMembers(ctx context.Context, client *mongo.Client, departmentID string, userIDs []string, now time.Time) error {
+	if departmentID == "" || len(userIDs) == 0 {
+		return nil
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	cursor, err := employeeCollection.Find(ctx, bson.D{
+		{Key: "user_id", Value: bson.D{{Key: "$in", Value: userIDs}}},
+		{Key: "department_id", Value: bson.D{{Key: "$ne", Value: departmentID}}},
+	})
+	if err != nil {
+		return err
+	}
+	var employees []models.Employee
+	if err = cursor.All(ctx, &employees); err != nil {
+		return err
+	}
+
+	for _, employee := range employees {
+		_, err := employeeCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "employee_id", Value: employee.EmployeeID}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "department_id", Value: departmentID},
+				{Key: "updated_at", Value: now},
+			}}},
+		)
+		if err != nil {
+			return err
+		}
+		if err := assignEmployeeDepartment(ctx, client, employee.EmployeeID, departmentID, scimActor, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySCIMGroupPatch applies one PatchOp operation to a group: members (optionally filtered as
+// members[value eq "id"]), displayName and externalId can be changed
+func applySCIMGroupPatch(group *models.SCIMGroup, operation models.SCIMPatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return errors.New("unsupported patch op " + operation.Op)
+	}
+
+	if operation.Path == "" {
+		values, ok := operation.Value.(map[string]interface{})
+		if !ok || op == "remove" {
+			return errors.New("value must be an object when path is omitted")
+		}
+		for path, value := range values {
+			if err := applySCIMGroupPatch(group, models.SCIMPatchOperation{Op: op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, path := splitSCIMPath(operation.Path)
+	switch strings.ToLower(scimAttributeName(path)) {
+	case "displayname":
+		group.DisplayName = scimString(operation.Value)
+		if op == "remove" {
+			group.DisplayName = ""
+		}
+	case "externalid":
+		group.ExternalID = scimString(operation.Value)
+		if op == "remove" {
+			group.ExternalID = ""
+		}
+	case "members":
+		members := scimGroupMembers(operation.Value)
+		if _, filter, found := strings.Cut(path, "["); found {
+			_, value, err := parseSCIMFilter(strings.TrimSuffix(filter, "]"))
+			if err != nil {
+				return err
+			}
+			members = []string{value}
+		}
+
+		switch op {
+		case "replace":
+			group.MemberUserIDs = members
+		case "add":
+			for _, userID := range members {
+				if !slices.Contains(group.MemberUserIDs, userID) {
+					group.MemberUserIDs = append(group.MemberUserIDs, userID)
+				}
+			}
+		case "remove":
+			if len(members) == 0 {
+				group.MemberUserIDs = []string{}
+				return nil
+			}
+			group.MemberUserIDs = slices.DeleteFunc(group.MemberUserIDs, func(userID string) bool {
+				return slices.Contains(members, userID)
+			})
+		}
+	default:
+		return errors.New("unsupported path " + operation.Path)
+	}
+	return nil
+}
+
+// scimGroupMembers reads the user IDs from a members value: a list of {"value": id} items, or a single one
+func scimGroupMembers(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	members := []string{}
+	for _, item := range items {
+		member, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if userID := scimString(scimLookup(member, "value")); userID != "" && !slices.Contains(members, userID) {
+			members = append(members, userID)
+		}
+	}
+	return members
+}
+
+func scimGroupResource(group models.SCIMGroup) map[string]interface{} {
+	members := make([]interface{}, 0, len(group.MemberUserIDs))
+	for _, userID := range group.MemberUserIDs {
+		members = append(members, map[string]interface{}{
+			"value": userID,
+			"$ref":  "/scim/v2/Users/" + userID,
+		})
+	}
+
+	resource := map[string]interface{}{
+		"schemas":     []interface{}{scimGroupSchema},
+		"id":          group.GroupID,
+		"displayName": group.DisplayName,
+		"members":     members,
+		"meta": map[string]interface{}{
+			"resourceType": "Group",
+			"created":      group.CreatedAt,
+			"lastModified": group.UpdatedAt,
+			"location":     "/scim/v2/Groups/" + group.GroupID,
+		},
+	}
+	if group.ExternalID != "" {
+		resource["externalId"] = group.ExternalID
+	}
+	return resource
+}
+
+// findSCIMGroupForUpdate loads the group named in the path, writing the SCIM error response if it cannot
+func findSCIMGroupForUpdate(c *gin.Context, ctx context.Context, client *mongo.Client) (models.SCIMGroup, bool) {
+	group, err := findSCIMGroup(ctx, client, c.Param("id"))
+	if err == mongo.ErrNoDocuments {
+		scimError(c, http.StatusNotFound, "", "Group not found")
+		return group, false
+	}
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to fetch group")
+		return group, false
+	}
+	return group, true
+}
+
+func findSCIMGroup(ctx context.Context, client *mongo.Client, groupID string) (models.SCIMGroup, error) {
+	groupCollection := database.OpenCollection("scim_groups", client)
+	var group models.SCIMGroup
+	err := groupCollection.FindOne(ctx, bson.D{{Key: "group_id", Value: groupID}}).Decode(&group)
+	return group, err
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	errSessionInvalid     = errors.New("session is revoked or has expired")
+	errRefreshTokenReused = errors.New("refresh token has already been used")
+)
+
+// createSession starts a new refresh token family for a login
+func createSession(ctx context.Context, client *mongo.Client, c *gin.Context, userID, sessionID, tokenID string, amr []string, now time.Time) error {
+	sessionCollection := database.OpenCollection("sessions", client)
+	_, err := sessionCollection.InsertOne(ctx, models.Session{
+		SessionID:      sessionID,
+		UserID:         userID,
+		CurrentTokenID: tokenID,
+		AMR:            amr,
+		UserAgent:      c.Request.UserAgent(),
+		IPAddress:      c.ClientIP(),
+		CreatedAt:      now,
+		LastUsedAt:     now,
+		ExpiresAt:      now.Add(utils.RefreshTokenTTL),
+	})
+	return err
+}
+
+// rotateSession swaps the session's current refresh token for newTokenID. Presenting a refresh
+// token that is no longer the current one means it was copied, so the whole family is revoked.
+func rotateSession(ctx context.Context, client *mongo.Client, c *gin.Context, claims *utils.SignedDetails, newTokenID string, now time.Time) error {
+	sessionCollection := database.OpenCollection("sessions", client)
+	var session models.Session
+	err := sessionCollection.FindOne(ctx, bson.D{
+		{Key: "session_id", Value: claims.SessionID},
+		{Key: "user_id", Value: claims.UserId},
+	}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return errSessionInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return errSessionInvalid
+	}
+
+	// Matching on the current token makes the swap single-use, even for concurrent requests
+	result, err := sessionCollection.UpdateOne(
+		ctx,
+		bson.D{
+			{Key: "session_id", Value: session.SessionID},
+			{Key: "current_token_id", Value: claims.ID},
+			{Key: "revoked_at", Value: nil},
+		},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "current_token_id", Value: newTokenID},
+				{Key: "user_agent", Value: c.Request.UserAgent()},
+				{Key: "ip_address", Value: c.ClientIP()},
+				{Key: "last_used_at", Value: now},
+				{Key: "expires_at", Value: now.Add(utils.RefreshTokenTTL)},
+			}},
+			{Key: "$inc", Value: bson.D{{Key: "rotations", Value: 1}}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	if err := revokeSessions(ctx, client, bson.D{{Key: "session_id", Value: session.SessionID}}, "refresh_token_reused", now); err != nil {
+		return err
+	}
+	recordAudit(ctx, client, session.UserID, "session.refresh_token_reused", "session", session.SessionID, bson.M{
+		"ip_address": c.ClientIP(),
+		"user_agent": c.Request.UserAgent(),
+	})
+	return errRefreshTokenReused
+}
+
+// revokeSessions revokes every open session matching the filter
+func revokeSessions(ctx context.Context, client *mongo.Client, filter bson.D, reason string, now time.Time) error {
+	sessionCollection := database.OpenCollection("sessions", client)
+	_, err := sessionCollection.UpdateMany(
+		ctx,
+		append(filter, bson.E{Key: "revoked_at", Value: nil}),
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "revoked_at", Value: now},
+			{Key: "revoked_reason", Value: reason},
+		}}},
+	)
+	return err
+}
+
+// revokeUserSessions signs a user out everywhere, so none of their refresh tokens work anymore
+func revokeUserSessions(ctx context.Context, client *mongo.Client, userID, reason string, now time.Time) error {
+	return revokeSessions(ctx, client, bson.D{{Key: "user_id", Value: userID}}, reason, now)
+}
+
+// GetMySessions - A signed-in user lists the sessions that can still be refreshed, most recently used first
+func GetMySessions(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		sessionCollection := database.OpenCollection("sessions", client)
+		cursor, err := sessionCollection.Find(
+			ctx,
+			bson.D{
+				{Key: "user_id", Value: userID},
+				{Key: "revoked_at", Value: nil},
+				{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+			},
+			options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}}),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var sessions []models.Session
+		if err := cursor.All(ctx, &sessions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode sessions"})
+			return
+		}
+		currentSessionID := c.GetString("sessionId")
+		for i := range sessions {
+			sessions[i].Current = sessions[i].SessionID == currentSessionID
+		}
+
+		c.JSON(http.StatusOK, gin.H{"sessions": sessions, "count": len(sessions)})
+	}
+}
+
+// RevokeMySession - A signed-in user signs one of their own sessions out, e.g. a lost device
+func RevokeMySession(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		sessionCollection := database.OpenCollection("sessions", client)
+		result, err := sessionCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "session_id", Value: c.Param("id")},
+				{Key: "user_id", Value: userID},
+				{Key: "revoked_at", Value: nil},
+			},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "revoked_at", Value: now},
+				{Key: "revoked_reason", Value: "signed_out"},
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+
+		recordAudit(ctx, client, userID, "session.revoked", "session", c.Param("id"), bson.M{})
+		c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully", "session_id": c.Param("id")})
+	}
+}
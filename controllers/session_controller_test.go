@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// loadTestKeys installs a fresh HS256 signing key for access and refresh tokens
+func loadTestKeys(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", "test-encryption-key-0123456789abcdef")
+	now := time.Now()
+	for _, purpose := range []string{utils.AccessTokenKeys, utils.RefreshTokenKeys} {
+		key, err := utils.NewSigningKey(purpose, "HS256", now.Add(-time.Minute), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := utils.LoadSigningKeys(purpose, []models.SigningKey{key}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		utils.LoadSigningKeys(utils.AccessTokenKeys, nil, now)
+		utils.LoadSigningKeys(utils.RefreshTokenKeys, nil, now)
+	})
+}
+
+// refresh presents a refresh token to RefreshTokenHandler and returns the status and the rotated token
+func refresh(client *mongo.Client, refreshToken string) (int, string) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/refresh", RefreshTokenHandler(client))
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "refresh_token" {
+			return w.Code, cookie.Value
+		}
+	}
+	return w.Code, ""
+}
+
+// seedSession stores a user with one open session and returns a refresh token for it
+func seedSession(t *testing.T, client *mongo.Client) (models.User, models.Session, string) {
+	t.Helper()
+	user := models.User{UserID: bson.NewObjectID().Hex(), Email: "employee@example.com", Role: "EMPLOYEE"}
+	session := models.Session{
+		SessionID:      bson.NewObjectID().Hex(),
+		UserID:         user.UserID,
+		CurrentTokenID: bson.NewObjectID().Hex(),
+		AMR:            []string{"pwd"},
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	insertDocuments(t, client, "users", user)
+	insertDocuments(t, client, "sessions", session)
+
+	_, refreshToken, err := utils.GenerateAllTokens(user.Email, user.FirstName, user.LastName, user.Role, user.UserID, session.SessionID, session.CurrentTokenID, session.AMR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, session, refreshToken
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	client := testClient(t)
+	loadTestKeys(t)
+	_, session, first := seedSession(t, client)
+
+	status, second := refresh(client, first)
+	if status != http.StatusOK || second == "" {
+		t.Fatalf("first refresh: status = %d, want %d with a new token", status, http.StatusOK)
+	}
+
+	// Presenting the rotated-out token again signs the whole session out
+	if status, _ := refresh(client, first); status != http.StatusUnauthorized {
+		t.Errorf("reused token: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	var revoked models.Session
+	findDocument(t, client, "sessions", bson.D{{Key: "session_id", Value: session.SessionID}}, &revoked)
+	if revoked.RevokedAt == nil || revoked.RevokedReason != "refresh_token_reused" {
+		t.Errorf("session revoked at %v for %q, want revoked for refresh_token_reused", revoked.RevokedAt, revoked.RevokedReason)
+	}
+	if status, _ := refresh(client, second); status != http.StatusUnauthorized {
+		t.Errorf("current token of the revoked session: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if n := countDocuments(t, client, "audit_log", bson.D{{Key: "action", Value: "session.refresh_token_reused"}}); n != 1 {
+		t.Errorf("%d reuse audit entries, want 1", n)
+	}
+}
+
+func TestRevokedSessionCannotRefresh(t *testing.T) {
+	client := testClient(t)
+	loadTestKeys(t)
+	user, session, refreshToken := seedSession(t, client)
+
+	w := serve(t, RevokeMySession(client), http.MethodDelete, "/sessions/:id", "/sessions/"+session.SessionID, user.UserID, user.Role, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke: status = %d: %s", w.Code, w.Body.String())
+	}
+	if status, _ := refresh(client, refreshToken); status != http.StatusUnauthorized {
+		t.Errorf("refresh after revocation: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
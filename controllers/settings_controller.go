@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const organizationSettingsID = "default"
+
+// defaultOrganizationSettings are used until an admin saves the settings for the first time
+func defaultOrganizationSettings() models.OrganizationSettings {
+	return models.OrganizationSettings{
+		SettingsID: organizationSettingsID,
+		TransferPolicy: models.TransferPolicy{
+			Enabled:     true,
+			MaxPerMonth: 10,
+		},
+		CarryOverPolicy: models.CarryOverPolicy{
+			Mode: "full",
+		},
+		TerminationPolicy: models.TerminationPolicy{
+			UnusedCoupons: "freeze",
+		},
+		WeekendDays:          []int{int(time.Saturday), int(time.Sunday)},
+		CouponsPerWorkingDay: 1,
+		SCIM: models.SCIMSettings{
+			AttributeMapping: defaultSCIMAttributeMapping(),
+			GroupDepartments: map[string]string{},
+		},
+		MFA: models.MFAPolicy{
+			RequiredRoles: []string{},
+		},
+	}
+}
+
+// loadOrganizationSettings reads the org-wide settings, falling back to the defaults
+func loadOrganizationSettings(ctx context.Context, client *mongo.Client) (models.OrganizationSettings, error) {
+	settingsCollection := database.OpenCollection("settings", client)
+	var settings models.OrganizationSettings
+	err := settingsCollection.FindOne(ctx, bson.D{{Key: "settings_id", Value: organizationSettingsID}}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return defaultOrganizationSettings(), nil
+	}
+	if err != nil {
+		return settings, err
+	}
+
+	// Settings saved before the work week, SCIM mapping or MFA policy was configurable fall back to their defaults
+	defaults := defaultOrganizationSettings()
+	if settings.WeekendDays == nil {
+		settings.WeekendDays = defaults.WeekendDays
+	}
+	if settings.CouponsPerWorkingDay == 0 {
+		settings.CouponsPerWorkingDay = defaults.CouponsPerWorkingDay
+	}
+	if settings.SCIM.AttributeMapping == nil {
+		settings.SCIM.AttributeMapping = defaults.SCIM.AttributeMapping
+	}
+	if settings.SCIM.GroupDepartments == nil {
+		settings.SCIM.GroupDepartments = defaults.SCIM.GroupDepartments
+	}
+	if settings.MFA.RequiredRoles == nil {
+		settings.MFA.RequiredRoles = defaults.MFA.RequiredRoles
+	}
+	return settings, nil
+}
+
+// saveOrganizationSettings stores the settings document, creating it on first save
+func saveOrganizationSettings(ctx context.Context, client *mongo.Client, settings models.OrganizationSettings) error {
+	settingsCollection := database.OpenCollection("settings", client)
+	_, err := settingsCollection.ReplaceOne(
+		ctx,
+		bson.D{{Key: "settings_id", Value: organizationSettingsID}},
+		settings,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// GetOrganizationSettings - Admin views the org-wide policies
+func GetOrganizationSettings(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+// UpdateTransferPolicy - Admin sets the limits on coupon transfers between employees
+func UpdateTransferPolicy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateTransferPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		policy := &settings.TransferPolicy
+		if req.Enabled != nil {
+			policy.Enabled = *req.Enabled
+		}
+		if req.MaxPerMonth != nil {
+			policy.MaxPerMonth = *req.MaxPerMonth
+		}
+		if req.SameDepartmentOnly != nil {
+			policy.SameDepartmentOnly = *req.SameDepartmentOnly
+		}
+		if req.RequiresManagerApproval != nil {
+			policy.RequiresManagerApproval = *req.RequiresManagerApproval
+		}
+		settings.UpdatedByUserID = adminUserID
+		settings.UpdatedAt = time.Now()
+
+		if err := saveOrganizationSettings(ctx, client, settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transfer policy"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":         "Transfer policy updated successfully",
+			"transfer_policy": settings.TransferPolicy,
+		})
+	}
+}
+
+// UpdateCarryOverPolicy - Admin sets what happens to unused coupons at the next allocation
+func UpdateCarryOverPolicy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateCarryOverPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+		if req.Mode == "capped" && req.Cap == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Capped carry-over needs a cap above zero. Use mode none to keep nothing"})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		// Lots already issued keep the expiry they were issued with
+		settings.CarryOverPolicy = models.CarryOverPolicy{
+			Mode:         req.Mode,
+			Cap:          req.Cap,
+			ExpiryMonths: req.ExpiryMonths,
+		}
+		settings.UpdatedByUserID = adminUserID
+		settings.UpdatedAt = time.Now()
+
+		if err := saveOrganizationSettings(ctx, client, settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update carry-over policy"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":           "Carry-over policy updated successfully",
+			"carry_over_policy": settings.CarryOverPolicy,
+		})
+	}
+}
+
+// UpdateTerminationPolicy - Admin sets whether terminated employees forfeit or keep a frozen balance
+func UpdateTerminationPolicy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateTerminationPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+			return
+		}
+
+		settings.TerminationPolicy.UnusedCoupons = req.UnusedCoupons
+		settings.UpdatedByUserID = adminUserID
+		settings.UpdatedAt = time.Now()
+
+		if err := saveOrganizationSettings(ctx, client, settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update termination policy"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":            "Termination policy updated successfully",
+			"termination_policy": settings.TerminationPolicy,
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var signingKeyPurposes = []string{utils.AccessTokenKeys, utils.RefreshTokenKeys}
+
+// SyncSigningKeys loads the JWT keyring from the database, rotating the keys that are older than
+// JWT_KEY_ROTATION_DAYS or use another algorithm than configured, and returns how many were rotated.
+// Every instance runs it, so they all sign and verify with the same keys.
+func SyncSigningKeys(ctx context.Context, client *mongo.Client) (int, error) {
+	rotated := 0
+	for _, purpose := range signingKeyPurposes {
+		_, didRotate, err := syncSigningKeys(ctx, client, purpose, false, time.Now())
+		if err != nil {
+			return rotated, err
+		}
+		if didRotate {
+			rotated++
+		}
+	}
+	return rotated, nil
+}
+
+// syncSigningKeys brings the keys of one purpose up to date and loads them, returning the key that
+// signs new tokens once it is active. A new key is only used after it has been published for two
+// sync intervals, and the keys it replaces keep verifying for the grace window.
+func syncSigningKeys(ctx context.Context, client *mongo.Client, purpose string, force bool, now time.Time) (models.SigningKey, bool, error) {
+	keyCollection := database.OpenCollection("signing_keys", client)
+
+	// Expired keys verify nothing anymore, so their private parts are not kept around
+	_, err := keyCollection.DeleteMany(ctx, bson.D{
+		{Key: "purpose", Value: purpose},
+		{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}},
+	})
+	if err != nil {
+		return models.SigningKey{}, false, err
+	}
+
+	cursor, err := keyCollection.Find(ctx, bson.D{{Key: "purpose", Value: purpose}},
+		options.Find().SetSort(bson.D{{Key: "activates_at", Value: -1}, {Key: "kid", Value: -1}}))
+	if err != nil {
+		return models.SigningKey{}, false, err
+	}
+	var keys []models.SigningKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return models.SigningKey{}, false, err
+	}
+
+	newest := -1
+	for i := range keys {
+		if keys[i].RetiredAt == nil {
+			newest = i
+			break
+		}
+	}
+
+	algorithm := utils.SigningAlgorithm(purpose)
+	rotationPeriod := time.Duration(utils.GetEnvAsInt("JWT_KEY_ROTATION_DAYS", 30)) * 24 * time.Hour
+	rotate := force || newest < 0 || keys[newest].Algorithm != algorithm || now.Sub(keys[newest].ActivatesAt) >= rotationPeriod
+	if rotate {
+		// With no usable key at all there is nothing to wait for
+		activatesAt := now
+		if newest >= 0 {
+			syncInterval := time.Duration(utils.GetEnvAsInt("JWT_KEY_SYNC_MINUTES", 5)) * time.Minute
+			activatesAt = now.Add(2 * syncInterval)
+		}
+		key, err := utils.NewSigningKey(purpose, algorithm, activatesAt, now)
+		if err != nil {
+			return models.SigningKey{}, false, err
+		}
+		if _, err := keyCollection.InsertOne(ctx, key); err != nil {
+			return models.SigningKey{}, false, err
+		}
+		keys = append([]models.SigningKey{key}, keys...)
+		newest = 0
+	}
+
+	// Every other active key, including one another instance rotated in at the same time, stops
+	// signing when the newest one starts
+	retiredAt := keys[newest].ActivatesAt
+	expiresAt := retiredAt.Add(signingKeyGracePeriod(purpose))
+	for i := range keys {
+		if i == newest || keys[i].RetiredAt != nil {
+			continue
+		}
+		_, err := keyCollection.UpdateOne(
+			ctx,
+			bson.D{{Key: "kid", Value: keys[i].KID}, {Key: "retired_at", Value: nil}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "retired_at", Value: retiredAt},
+				{Key: "expires_at", Value: expiresAt},
+			}}},
+		)
+		if err != nil {
+			return models.SigningKey{}, false, err
+		}
+		keys[i].RetiredAt = &retiredAt
+		keys[i].ExpiresAt = &expiresAt
+	}
+
+	return keys[newest], rotate, utils.LoadSigningKeys(purpose, keys, now)
+}
+
+// signingKeyGracePeriod is how long a retired key keeps verifying: JWT_KEY_GRACE_HOURS, but never
+// less than the lifetime of the tokens it signed, so rotating does not sign anyone out
+func signingKeyGracePeriod(purpose string) time.Duration {
+	grace := time.Duration(utils.GetEnvAsInt("JWT_KEY_GRACE_HOURS", 0)) * time.Hour
+	tokenTTL := utils.AccessTokenTTL
+	if purpose == utils.RefreshTokenKeys {
+		tokenTTL = utils.RefreshTokenTTL
+	}
+	return max(grace, tokenTTL)
+}
+
+// GetSigningKeys - Admin lists the JWT signing keys without their private parts, newest first
+func GetSigningKeys(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		keyCollection := database.OpenCollection("signing_keys", client)
+		cursor, err := keyCollection.Find(ctx, bson.D{},
+			options.Find().SetSort(bson.D{{Key: "purpose", Value: 1}, {Key: "activates_at", Value: -1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch signing keys"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var keys []models.SigningKey
+		if err := cursor.All(ctx, &keys); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode signing keys"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"signing_keys": keys, "count": len(keys)})
+	}
+}
+
+// RotateSigningKeys - Admin rotates the signing keys ahead of schedule, for one purpose with
+// ?purpose=access|refresh or for both. Tokens signed with the old keys stay valid.
+func RotateSigningKeys(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUserID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		purposes := signingKeyPurposes
+		if purpose := c.Query("purpose"); purpose != "" {
+			if purpose != utils.AccessTokenKeys && purpose != utils.RefreshTokenKeys {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Purpose must be access or refresh"})
+				return
+			}
+			purposes = []string{purpose}
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var rotated []models.SigningKey
+		for _, purpose := range purposes {
+			key, _, err := syncSigningKeys(ctx, client, purpose, true, time.Now())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate signing keys"})
+				return
+			}
+			rotated = append(rotated, key)
+			recordAudit(ctx, client, adminUserID, "signing_key.rotated", "signing_key", key.KID, bson.M{
+				"purpose":      key.Purpose,
+				"algorithm":    key.Algorithm,
+				"activates_at": key.ActivatesAt,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Signing keys rotated successfully", "signing_keys": rotated})
+	}
+}
+
+// GetJWKS - Publishes the public keys that verify access tokens, for services that check them
+// without calling this API
+func GetJWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, gin.H{"keys": utils.PublicJWKS()})
+	}
+}
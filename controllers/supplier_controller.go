@@ -404,4 +404,77 @@ func GetMyTotals(client *mongo.Client) gin.HandlerFunc {
 			EarningsThisMonth:   earningsThisMonth,
 		})
 	}
-}
\ No newline at end of file
+}
+
+// GetMyDailyCloseOut - Supplier reconciles a day's full takings.
+// Only the coupon portion is payable by the company; other tenders were collected at the counter.
+func GetMyDailyCloseOut(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		now := time.Now()
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if dateStr := c.Query("date"); dateStr != "" {
+			day, err = time.ParseInLocation("2006-01-02", dateStr, now.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+				return
+			}
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		supplierCollection := database.OpenCollection("suppliers", client)
+		var supplier models.Supplier
+		err = supplierCollection.FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&supplier)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+			return
+		}
+
+		transactionCollection := database.OpenCollection("transactions", client)
+		cursor, err := transactionCollection.Find(ctx, bson.D{
+			{Key: "supplier_id", Value: supplier.SupplierID},
+			{Key: "status", Value: "completed"},
+			{Key: "processed_at", Value: bson.D{
+				{Key: "$gte", Value: day},
+				{Key: "$lt", Value: day.AddDate(0, 0, 1)},
+			}},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var transactions []models.Transaction
+		if err = cursor.All(ctx, &transactions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode transactions"})
+			return
+		}
+
+		closeOut := models.SupplierCloseOutResponse{
+			SupplierID:   supplier.SupplierID,
+			BusinessName: supplier.BusinessName,
+			Date:         day.Format("2006-01-02"),
+			Transactions: len(transactions),
+			OtherTenders: map[string]float64{},
+		}
+		for _, tx := range transactions {
+			closeOut.TotalCoupons += tx.CouponsUsed
+			closeOut.CouponAmount += tx.TotalAmount
+			closeOut.OtherTenderAmount += tx.OtherTenderAmount
+			if tx.OtherTenderAmount > 0 {
+				closeOut.OtherTenders[tx.OtherTenderMethod] += tx.OtherTenderAmount
+			}
+		}
+		closeOut.TotalTakings = closeOut.CouponAmount + closeOut.OtherTenderAmount
+
+		c.JSON(http.StatusOK, closeOut)
+	}
+}
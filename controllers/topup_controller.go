@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// PurchaseTopUp - Employee buys extra coupons, deducted from the next payroll
+func PurchaseTopUp(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateTopUpRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employee, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+		if employee.Status != "active" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only active employees can buy coupons", "status": employee.Status})
+			return
+		}
+
+		now := time.Now()
+		budget, err := blockingBudget(ctx, client, employee, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check meal budget"})
+			return
+		}
+		if budget != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Top-ups are blocked while your cost center is over its meal budget", "period": budget.Period})
+			return
+		}
+
+		period := now.Format("2006-01")
+		maxPerMonth := utils.GetEnvAsInt("TOPUP_MAX_PER_MONTH", 20)
+		bought, err := topUpQuantityInPeriod(ctx, client, employee.EmployeeID, period)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check monthly top-up limit"})
+			return
+		}
+		if maxPerMonth > 0 && bought+req.Quantity > maxPerMonth {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "Monthly top-up limit exceeded",
+				"max_per_month":     maxPerMonth,
+				"bought_this_month": bought,
+			})
+			return
+		}
+
+		unitPrice := float64(utils.GetEnvAsInt("TOPUP_COUPON_PRICE", 45))
+		topUp := models.CouponTopUp{
+			TopUpID:       bson.NewObjectID().Hex(),
+			EmployeeID:    employee.EmployeeID,
+			Quantity:      req.Quantity,
+			UnitPrice:     unitPrice,
+			TotalCost:     unitPrice * float64(req.Quantity),
+			PayrollPeriod: period,
+			CreatedAt:     now,
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		var updated models.Employee
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			topUpCollection := database.OpenCollection("coupon_top_ups", client)
+			if _, err := topUpCollection.InsertOne(sessCtx, topUp); err != nil {
+				return nil, err
+			}
+			description := fmt.Sprintf("Bought %d coupon(s), %.2f deducted in payroll %s", topUp.Quantity, topUp.TotalCost, period)
+			var err error
+			updated, _, err = applyBalanceChange(sessCtx, client, employee.EmployeeID, couponBalanceChange{Paid: topUp.Quantity}, "top_up", topUp.TopUpID, description)
+			return nil, err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to buy coupons"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":      "Coupons bought successfully",
+			"top_up":       topUp,
+			"paid_balance": updated.PaidBalance,
+		})
+	}
+}
+
+// GetMyTopUps - Employee lists their coupon purchases
+func GetMyTopUps(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employee, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		filter := bson.D{{Key: "employee_id", Value: employee.EmployeeID}}
+		if period := c.Query("period"); period != "" {
+			filter = append(filter, bson.E{Key: "payroll_period", Value: period})
+		}
+
+		topUpCollection := database.OpenCollection("coupon_top_ups", client)
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		cursor, err := topUpCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch top-ups"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var topUps []models.CouponTopUp
+		if err = cursor.All(ctx, &topUps); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode top-ups"})
+			return
+		}
+
+		totalCost := 0.0
+		for _, topUp := range topUps {
+			totalCost += topUp.TotalCost
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"top_ups":    topUps,
+			"total":      len(topUps),
+			"total_cost": totalCost,
+		})
+	}
+}
+
+// GetPayrollDeductions - Admin exports each employee's top-up cost for a payroll month (?format=csv for a file)
+func GetPayrollDeductions(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		period := c.DefaultQuery("period", time.Now().Format("2006-01"))
+		if _, err := time.Parse("2006-01", period); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period. Use YYYY-MM"})
+			return
+		}
+
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{{Key: "payroll_period", Value: period}}}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$employee_id"},
+				{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$quantity"}}},
+				{Key: "total_cost", Value: bson.D{{Key: "$sum", Value: "$total_cost"}}},
+				{Key: "top_up_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+			{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "employees"},
+				{Key: "localField", Value: "_id"},
+				{Key: "foreignField", Value: "employee_id"},
+				{Key: "as", Value: "employee"},
+			}}},
+			{{Key: "$unwind", Value: "$employee"}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "employee_code", Value: "$employee.employee_code"},
+				{Key: "name", Value: "$employee.name"},
+				{Key: "quantity", Value: 1},
+				{Key: "total_cost", Value: 1},
+				{Key: "top_up_count", Value: 1},
+			}}},
+			{{Key: "$sort", Value: bson.D{{Key: "employee_code", Value: 1}}}},
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		topUpCollection := database.OpenCollection("coupon_top_ups", client)
+		cursor, err := topUpCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build payroll export"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var rows []models.PayrollDeductionRow
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode payroll export"})
+			return
+		}
+
+		if c.Query("format") == "csv" {
+			var buf bytes.Buffer
+			writer := csv.NewWriter(&buf)
+			writer.Write([]string{"employee_code", "name", "payroll_period", "coupons", "deduction"})
+			for _, row := range rows {
+				writer.Write([]string{
+					row.EmployeeCode,
+					row.Name,
+					period,
+					strconv.Itoa(row.Quantity),
+					strconv.FormatFloat(row.TotalCost, 'f', 2, 64),
+				})
+			}
+			writer.Flush()
+
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=payroll-top-ups-%s.csv", period))
+			c.Data(http.StatusOK, "text/csv", buf.Bytes())
+			return
+		}
+
+		totalCost := 0.0
+		for _, row := range rows {
+			totalCost += row.TotalCost
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"payroll_period": period,
+			"rows":           rows,
+			"total_cost":     totalCost,
+		})
+	}
+}
+
+// topUpQuantityInPeriod sums the coupons the employee bought in a payroll period
+func topUpQuantityInPeriod(ctx context.Context, client *mongo.Client, employeeID, period string) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "employee_id", Value: employeeID},
+			{Key: "payroll_period", Value: period},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$quantity"}}},
+		}}},
+	}
+
+	topUpCollection := database.OpenCollection("coupon_top_ups", client)
+	cursor, err := topUpCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Total int `bson:"total"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Total, nil
+}
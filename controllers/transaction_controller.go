@@ -3,7 +3,10 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -32,6 +35,11 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 		defer cancel()
 
+		// No meals are served on weekends, public holidays or site closures
+		if !checkProgramOpen(c, ctx, client, time.Now()) {
+			return
+		}
+
 		// 1. Get Supplier Profile
 		supplierCollection := database.OpenCollection("suppliers", client)
 		var supplier models.Supplier
@@ -56,6 +64,13 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 		qrCollection := database.OpenCollection("qr_codes", client)
 		var qrCode models.QRCode
 		err = qrCollection.FindOne(ctx, bson.D{{Key: "code", Value: req.QRCode}}).Decode(&qrCode)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Guest vouchers are redeemed through the same checkout
+			if voucher, voucherErr := findGuestVoucher(ctx, client, req.QRCode); voucherErr == nil {
+				redeemGuestVoucher(c, ctx, client, supplier, voucher, req)
+				return
+			}
+		}
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid QR code"})
 			return
@@ -132,6 +147,28 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 			}
 		}
 
+		// Order lines from the menu determine the coupon charge and meal price
+		var orderLines []models.OrderLine
+		if len(req.Items) > 0 {
+			lines, coupons, price, err := resolveOrderLines(ctx, client, supplier.SupplierID, req.Items, time.Now())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order", "details": err.Error()})
+				return
+			}
+			if req.CouponsUsed != 0 && req.CouponsUsed != coupons {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "coupons_used does not match the order items",
+					"coupons_for_items": coupons,
+				})
+				return
+			}
+			orderLines = lines
+			req.CouponsUsed = coupons
+			if req.MealPrice == 0 {
+				req.MealPrice = price
+			}
+		}
+
 		// 7. Validate Coupons Range
 		if req.CouponsUsed < 1 || req.CouponsUsed > 3 {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -142,12 +179,12 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 		}
 
 		// 8. Check Employee Balance
-		if employee.CurrentBalance < req.CouponsUsed {
+		if spendableCoupons(employee) < req.CouponsUsed {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Insufficient coupon balance",
-				"employee_balance": employee.CurrentBalance,
+				"employee_balance": spendableCoupons(employee),
 				"requested_coupons": req.CouponsUsed,
-				"message": "Employee only has " + string(rune(employee.CurrentBalance)) + " coupons available",
+				"message": "Employee only has " + strconv.Itoa(spendableCoupons(employee)) + " coupons available",
 			})
 			return
 		}
@@ -156,7 +193,53 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 		couponValue := 45.0
 		totalAmount := float64(req.CouponsUsed) * couponValue
 
-		// 10. Create Transaction Record
+		// Meals priced above the coupon value are split with another tender
+		mealPrice, otherTenderAmount, otherTenderMethod, err := splitMealPrice(totalAmount, req.MealPrice, req.OtherTenderMethod)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"meal_price": req.MealPrice,
+				"coupon_amount": totalAmount,
+			})
+			return
+		}
+
+		// 10. PIN confirmation or a matching auto-approval rule completes the transaction in one step
+		approvalMethod := ""
+		autoApprovalRuleID := ""
+		if req.Pin != "" {
+			attemptsLeft, err := verifyEmployeePin(ctx, client, employee, req.Pin)
+			switch {
+			case errors.Is(err, errPinNotSet):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Employee has not set a PIN. Please approve in the app."})
+				return
+			case errors.Is(err, errPinLocked):
+				c.JSON(http.StatusLocked, gin.H{"error": "Too many failed PIN attempts. PIN entry is temporarily locked."})
+				return
+			case errors.Is(err, errPinWrong):
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "Incorrect PIN",
+					"attempts_remaining": attemptsLeft,
+				})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify PIN"})
+				return
+			}
+			approvalMethod = "pin"
+		} else {
+			rule, err := matchAutoApprovalRule(ctx, client, employee.EmployeeID, supplier.SupplierID, req.CouponsUsed, time.Now())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check auto-approval rules"})
+				return
+			}
+			if rule != nil {
+				approvalMethod = "auto"
+				autoApprovalRuleID = rule.RuleID
+			}
+		}
+
+		// 11. Create Transaction Record
 		transactionID := uuid.New().String()
 		transaction := models.Transaction{
 			TransactionID:     transactionID,
@@ -164,7 +247,11 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 			SupplierID:        supplier.SupplierID,
 			QRCodeID:          qrCode.QRCodeID,
 			CouponsUsed:       req.CouponsUsed,
+			Items:             orderLines,
 			TotalAmount:       totalAmount,
+			MealPrice:         mealPrice,
+			OtherTenderAmount: otherTenderAmount,
+			OtherTenderMethod: otherTenderMethod,
 			EmployeeLatitude:  req.Latitude,
 			EmployeeLongitude: req.Longitude,
 			Status:            "pending",
@@ -175,13 +262,87 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 		}
 
 		transactionCollection := database.OpenCollection("transactions", client)
+
+		if approvalMethod != "" {
+			transaction.Status = "completed"
+			transaction.ApprovalMethod = approvalMethod
+			transaction.AutoApprovalRuleID = autoApprovalRuleID
+
+			session, err := client.StartSession()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+				return
+			}
+			defer session.EndSession(ctx)
+
+			_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+				if err := settleTransaction(sessCtx, client, &transaction, transaction.ProcessedAt); err != nil {
+					return nil, err
+				}
+				if _, err := transactionCollection.InsertOne(sessCtx, transaction); err != nil {
+					return nil, err
+				}
+				message := fmt.Sprintf("%d coupon(s) were charged at %s.", transaction.CouponsUsed, supplier.BusinessName)
+				err := notifyUser(sessCtx, client, employee.UserID, "transaction_completed", "Meal charged", message, transactionID)
+				return nil, err
+			})
+			if errors.Is(err, errInsufficientBalance) || errors.Is(err, errQRCodeUsed) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process transaction"})
+				return
+			}
+
+			message := "Transaction confirmed with PIN and processed successfully"
+			if approvalMethod == "auto" {
+				message = "Transaction auto-approved and processed successfully"
+			}
+
+			c.JSON(http.StatusCreated, gin.H{
+				"success": true,
+				"message": message,
+				"transaction_id": transactionID,
+				"employee": gin.H{
+					"name": employee.Name,
+					"code": employee.EmployeeCode,
+					"previous_balance": spendableCoupons(employee),
+					"new_balance": spendableCoupons(employee) - req.CouponsUsed,
+				},
+				"supplier": gin.H{
+					"name": supplier.BusinessName,
+					"address": supplier.Address,
+				},
+				"transaction": gin.H{
+					"coupons_used": req.CouponsUsed,
+					"items": orderLines,
+					"total_amount": totalAmount,
+					"meal_price": mealPrice,
+					"other_tender_amount": otherTenderAmount,
+					"other_tender_method": otherTenderMethod,
+					"paid_coupons": transaction.PaidCoupons,
+					"company_funded_amount": transaction.TotalAmount - transaction.EmployeeFundedAmount,
+					"employee_funded_amount": transaction.EmployeeFundedAmount,
+					"status": "completed",
+					"approval_method": approvalMethod,
+					"auto_approval_rule_id": autoApprovalRuleID,
+				},
+				"requires_approval": false,
+			})
+			return
+		}
+
 		_, err = transactionCollection.InsertOne(ctx, transaction)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
 			return
 		}
 
-		// 11. Return Success Response
+		message := fmt.Sprintf("%s wants to charge %d coupon(s). Please approve or reject.", supplier.BusinessName, req.CouponsUsed)
+		notifyUser(ctx, client, employee.UserID, "approval_required", "Approval required", message, transactionID)
+
+		// 12. Return Success Response
 		c.JSON(http.StatusCreated, gin.H{
 			"success": true,
 			"message": "Transaction initiated successfully. Waiting for employee approval.",
@@ -189,8 +350,8 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 			"employee": gin.H{
 				"name": employee.Name,
 				"code": employee.EmployeeCode,
-				"current_balance": employee.CurrentBalance,
-				"new_balance": employee.CurrentBalance - req.CouponsUsed,
+				"current_balance": spendableCoupons(employee),
+				"new_balance": spendableCoupons(employee) - req.CouponsUsed,
 			},
 			"supplier": gin.H{
 				"name": supplier.BusinessName,
@@ -198,7 +359,11 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 			},
 			"transaction": gin.H{
 				"coupons_used": req.CouponsUsed,
+				"items": orderLines,
 				"total_amount": totalAmount,
+				"meal_price": mealPrice,
+				"other_tender_amount": otherTenderAmount,
+				"other_tender_method": otherTenderMethod,
 				"status": "pending",
 			},
 			"requires_approval": true,
@@ -206,6 +371,73 @@ func InitiateTransaction(client *mongo.Client) gin.HandlerFunc {
 	}
 }
 
+var (
+	errInsufficientBalance = errors.New("insufficient coupon balance")
+	errQRCodeUsed          = errors.New("QR code has already been used")
+)
+
+// settleTransaction deducts the coupons from the employee balance and consumes the QR code.
+// It fills in the funding split and the cost center snapshot on the transaction, which the caller persists.
+// It must be called inside a session transaction so both writes commit together.
+func settleTransaction(ctx context.Context, client *mongo.Client, transaction *models.Transaction, now time.Time) error {
+	description := fmt.Sprintf("Meal transaction for %d coupon(s)", transaction.CouponsUsed)
+	paidCoupons, err := spendCoupons(ctx, client, transaction.EmployeeID, transaction.CouponsUsed, transaction.TransactionID, description)
+	if err != nil {
+		return err
+	}
+	if err := snapshotCostCenter(ctx, client, transaction); err != nil {
+		return err
+	}
+
+	// Company coupons are spent first, so only the remainder is funded by the employee's top-ups
+	couponValue := transaction.TotalAmount / float64(transaction.CouponsUsed)
+	transaction.PaidCoupons = paidCoupons
+	transaction.EmployeeFundedAmount = float64(paidCoupons) * couponValue
+	if err := recordBudgetSpend(ctx, client, transaction.CostCenter, transaction.TotalAmount-transaction.EmployeeFundedAmount, now); err != nil {
+		return err
+	}
+
+	qrCollection := database.OpenCollection("qr_codes", client)
+	result, err := qrCollection.UpdateOne(
+		ctx,
+		bson.D{
+			{Key: "qr_code_id", Value: transaction.QRCodeID},
+			{Key: "is_used", Value: false},
+		},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "is_used", Value: true},
+			{Key: "used_at", Value: now},
+		}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errQRCodeUsed
+	}
+	return nil
+}
+
+// splitMealPrice works out the part of the meal paid outside the coupons.
+// A zero meal price means the meal costs exactly the coupon amount.
+func splitMealPrice(couponAmount, mealPrice float64, otherTenderMethod string) (float64, float64, string, error) {
+	if mealPrice == 0 {
+		mealPrice = couponAmount
+	}
+	if mealPrice < couponAmount {
+		return 0, 0, "", errors.New("meal price is lower than the value of the coupons charged")
+	}
+
+	otherTenderAmount := mealPrice - couponAmount
+	if otherTenderAmount == 0 {
+		return mealPrice, 0, "", nil
+	}
+	if otherTenderMethod == "" {
+		return 0, 0, "", errors.New("other_tender_method is required when the meal price exceeds the coupon value")
+	}
+	return mealPrice, otherTenderAmount, otherTenderMethod, nil
+}
+
 // ApproveTransaction - Employee approves or rejects transaction
 func ApproveTransaction(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -254,17 +486,23 @@ func ApproveTransaction(client *mongo.Client) gin.HandlerFunc {
 			return
 		}
 
+		// Shares of a group meal are settled together once every participant approves
+		if transaction.GroupTransactionID != "" {
+			approveGroupShare(c, ctx, client, transaction, employee, req)
+			return
+		}
+
 		// 4. Get Supplier Info (for response)
 		supplierCollection := database.OpenCollection("suppliers", client)
 		var supplier models.Supplier
 		supplierCollection.FindOne(ctx, bson.D{{Key: "supplier_id", Value: transaction.SupplierID}}).Decode(&supplier)
 
 		// 5. Process Based on Approval
-		if req.Approved {
-			if employee.CurrentBalance < transaction.CouponsUsed {
+		if *req.Approved {
+			if spendableCoupons(employee) < transaction.CouponsUsed {
 				c.JSON(http.StatusBadRequest, gin.H{
 					"error": "Insufficient balance",
-					"current_balance": employee.CurrentBalance,
+					"current_balance": spendableCoupons(employee),
 					"required": transaction.CouponsUsed,
 				})
 				return
@@ -279,37 +517,24 @@ func ApproveTransaction(client *mongo.Client) gin.HandlerFunc {
 			defer session.EndSession(ctx)
 
 			_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
-				// Deduct employee balance
-				_, err := employeeCollection.UpdateOne(
-					sessCtx,
-					bson.D{{Key: "employee_id", Value: employee.EmployeeID}},
-					bson.D{{Key: "$inc", Value: bson.D{{Key: "current_coupon_balance", Value: -transaction.CouponsUsed}}}},
-				)
-				if err != nil {
-					return nil, err
-				}
-
-				// Mark QR code as used
-				qrCollection := database.OpenCollection("qr_codes", client)
 				now := time.Now()
-				_, err = qrCollection.UpdateOne(
-					sessCtx,
-					bson.D{{Key: "qr_code_id", Value: transaction.QRCodeID}},
-					bson.D{{Key: "$set", Value: bson.D{
-						{Key: "is_used", Value: true},
-						{Key: "used_at", Value: now},
-					}}},
-				)
-				if err != nil {
+
+				// Deduct employee balance and mark QR code as used
+				if err := settleTransaction(sessCtx, client, &transaction, now); err != nil {
 					return nil, err
 				}
 
 				// Update transaction status
-				_, err = transactionCollection.UpdateOne(
+				_, err := transactionCollection.UpdateOne(
 					sessCtx,
 					bson.D{{Key: "transaction_id", Value: req.TransactionID}},
 					bson.D{{Key: "$set", Value: bson.D{
 						{Key: "status", Value: "completed"},
+						{Key: "approval_method", Value: "app"},
+						{Key: "paid_coupons", Value: transaction.PaidCoupons},
+						{Key: "employee_funded_amount", Value: transaction.EmployeeFundedAmount},
+						{Key: "department_id", Value: transaction.DepartmentID},
+						{Key: "cost_center", Value: transaction.CostCenter},
 						{Key: "updated_at", Value: now},
 					}}},
 				)
@@ -332,9 +557,10 @@ func ApproveTransaction(client *mongo.Client) gin.HandlerFunc {
 				"transaction_id": req.TransactionID,
 				"employee": gin.H{
 					"name": employee.Name,
-					"previous_balance": employee.CurrentBalance,
-					"new_balance": employee.CurrentBalance - transaction.CouponsUsed,
+					"previous_balance": spendableCoupons(employee),
+					"new_balance": spendableCoupons(employee) - transaction.CouponsUsed,
 					"coupons_deducted": transaction.CouponsUsed,
+					"paid_coupons_deducted": transaction.PaidCoupons,
 				},
 				"supplier": gin.H{
 					"name": supplier.BusinessName,
@@ -342,6 +568,10 @@ func ApproveTransaction(client *mongo.Client) gin.HandlerFunc {
 				},
 				"transaction": gin.H{
 					"amount": transaction.TotalAmount,
+					"meal_price": transaction.MealPrice,
+					"other_tender_amount": transaction.OtherTenderAmount,
+					"company_funded_amount": transaction.TotalAmount - transaction.EmployeeFundedAmount,
+					"employee_funded_amount": transaction.EmployeeFundedAmount,
 					"status": "completed",
 				},
 			})
@@ -479,6 +709,9 @@ func GetSupplierTransactions(client *mongo.Client) gin.HandlerFunc {
 		// Calculate statistics
 		totalCoupons := 0
 		totalAmount := 0.0
+		otherTenderAmount := 0.0
+		companyFundedAmount := 0.0
+		employeeFundedAmount := 0.0
 		completedCount := 0
 		pendingCount := 0
 
@@ -487,6 +720,9 @@ func GetSupplierTransactions(client *mongo.Client) gin.HandlerFunc {
 case "completed":
 				totalCoupons += tx.CouponsUsed
 				totalAmount += tx.TotalAmount
+				otherTenderAmount += tx.OtherTenderAmount
+				companyFundedAmount += tx.TotalAmount - tx.EmployeeFundedAmount
+				employeeFundedAmount += tx.EmployeeFundedAmount
 				completedCount++
 			case "pending":
 				pendingCount++
@@ -501,6 +737,10 @@ case "completed":
 				"pending": pendingCount,
 				"total_coupons": totalCoupons,
 				"total_amount": totalAmount,
+				"other_tender_amount": otherTenderAmount,
+				"company_funded_amount": companyFundedAmount,
+				"employee_funded_amount": employeeFundedAmount,
+				"total_takings": totalAmount + otherTenderAmount,
 			},
 		})
 	}
@@ -0,0 +1,39 @@
+package controllers
+
+import "testing"
+
+func TestSplitMealPrice(t *testing.T) {
+	tests := []struct {
+		name              string
+		couponAmount      float64
+		mealPrice         float64
+		otherTenderMethod string
+		wantMealPrice     float64
+		wantOtherTender   float64
+		wantMethod        string
+		wantErr           bool
+	}{
+		{name: "no meal price means coupons only", couponAmount: 90, wantMealPrice: 90},
+		{name: "meal priced at the coupon value", couponAmount: 45, mealPrice: 45, wantMealPrice: 45},
+		{name: "remainder paid by card", couponAmount: 45, mealPrice: 60, otherTenderMethod: "card", wantMealPrice: 60, wantOtherTender: 15, wantMethod: "card"},
+		{name: "method ignored without remainder", couponAmount: 45, mealPrice: 45, otherTenderMethod: "cash", wantMealPrice: 45},
+		{name: "remainder without a method", couponAmount: 45, mealPrice: 60, wantErr: true},
+		{name: "meal cheaper than the coupons", couponAmount: 90, mealPrice: 60, otherTenderMethod: "cash", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mealPrice, otherTender, method, err := splitMealPrice(tt.couponAmount, tt.mealPrice, tt.otherTenderMethod)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitMealPrice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if mealPrice != tt.wantMealPrice || otherTender != tt.wantOtherTender || method != tt.wantMethod {
+				t.Errorf("splitMealPrice() = %v, %v, %q; want %v, %v, %q",
+					mealPrice, otherTender, method, tt.wantMealPrice, tt.wantOtherTender, tt.wantMethod)
+			}
+		})
+	}
+}
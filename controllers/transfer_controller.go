@@ -0,0 +1,575 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	errTransferNotPending        = errors.New("transfer is not pending")
+	errTransfersDisabled         = errors.New("coupon transfers are disabled")
+	errTransferSenderInactive    = errors.New("sender cannot transfer coupons")
+	errTransferRecipientInactive = errors.New("recipient is not active")
+	errTransferOtherDepartment   = errors.New("coupons can only be transferred within the department")
+	errTransferLimitExceeded     = errors.New("monthly transfer limit exceeded")
+)
+
+// CreateTransfer - Employee gives coupons to a colleague
+func CreateTransfer(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateTransferRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		// 1. Policy
+		settings, err := loadOrganizationSettings(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfer policy"})
+			return
+		}
+		policy := settings.TransferPolicy
+		if !policy.Enabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Coupon transfers are disabled"})
+			return
+		}
+
+		// 2. Sender and recipient
+		sender, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+		if sender.Status != "active" && sender.Status != "on_leave" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your account cannot transfer coupons", "status": sender.Status})
+			return
+		}
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var recipient models.Employee
+		err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_code", Value: req.ToEmployeeCode}}).Decode(&recipient)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+			return
+		}
+		if recipient.EmployeeID == sender.EmployeeID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot transfer coupons to yourself"})
+			return
+		}
+		if recipient.Status != "active" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Recipient is not active"})
+			return
+		}
+		if policy.SameDepartmentOnly && (sender.DepartmentID == "" || sender.DepartmentID != recipient.DepartmentID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Coupons can only be transferred within your department"})
+			return
+		}
+
+		// 3. Balance. The policy and the monthly limit are enforced again inside the transaction.
+		if sender.CurrentBalance < req.Quantity {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "Insufficient coupon balance",
+				"employee_balance":  sender.CurrentBalance,
+				"requested_coupons": req.Quantity,
+			})
+			return
+		}
+
+		now := time.Now()
+		transfer := models.CouponTransfer{
+			TransferID:     bson.NewObjectID().Hex(),
+			FromEmployeeID: sender.EmployeeID,
+			ToEmployeeID:   recipient.EmployeeID,
+			Quantity:       req.Quantity,
+			Message:        req.Message,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		transferCollection := database.OpenCollection("coupon_transfers", client)
+
+		// 4a. Park the transfer with the sender's manager
+		if policy.RequiresManagerApproval {
+			if sender.ManagerEmployeeID == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Transfers need manager approval but you have no manager assigned"})
+				return
+			}
+			var manager models.Employee
+			err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: sender.ManagerEmployeeID}}).Decode(&manager)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Your manager could not be found"})
+				return
+			}
+
+			session, err := client.StartSession()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+				return
+			}
+			defer session.EndSession(ctx)
+
+			transfer.Status = "pending_approval"
+			transfer.ApproverEmployeeID = manager.EmployeeID
+			_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+				if _, _, err := enforceTransferPolicy(sessCtx, client, transfer); err != nil {
+					return nil, err
+				}
+				_, err := transferCollection.InsertOne(sessCtx, transfer)
+				return nil, err
+			})
+			if respondTransferPolicyError(c, err) {
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+				return
+			}
+
+			message := fmt.Sprintf("%s wants to give %d coupon(s) to %s.", sender.Name, transfer.Quantity, recipient.Name)
+			notifyUser(ctx, client, manager.UserID, "transfer_approval_required", "Coupon transfer awaiting approval", message, transfer.TransferID)
+
+			c.JSON(http.StatusCreated, gin.H{
+				"message":           "Transfer sent to your manager for approval",
+				"transfer":          transfer,
+				"requires_approval": true,
+			})
+			return
+		}
+
+		// 4b. Move the coupons straight away
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		transfer.Status = "completed"
+		transfer.CompletedAt = &now
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			sender, recipient, err := enforceTransferPolicy(sessCtx, client, transfer)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := transferCollection.InsertOne(sessCtx, transfer); err != nil {
+				return nil, err
+			}
+			return nil, moveTransferredCoupons(sessCtx, client, transfer, sender, recipient)
+		})
+		if respondTransferPolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, errInsufficientBalance) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Insufficient coupon balance"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer coupons"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":           "Coupons transferred successfully",
+			"transfer":          transfer,
+			"requires_approval": false,
+		})
+	}
+}
+
+// GetMyTransfers - Employee lists transfers they sent or received
+func GetMyTransfers(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employee, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		var filter bson.D
+		switch c.Query("direction") {
+		case "sent":
+			filter = bson.D{{Key: "from_employee_id", Value: employee.EmployeeID}}
+		case "received":
+			filter = bson.D{{Key: "to_employee_id", Value: employee.EmployeeID}}
+		default:
+			filter = bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "from_employee_id", Value: employee.EmployeeID}},
+				bson.D{{Key: "to_employee_id", Value: employee.EmployeeID}},
+			}}}
+		}
+		if status := c.Query("status"); status != "" {
+			filter = append(filter, bson.E{Key: "status", Value: status})
+		}
+
+		transfers, err := findTransfers(ctx, client, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"transfers": transfers,
+			"total":     len(transfers),
+		})
+	}
+}
+
+// GetTransferApprovals - Manager lists transfers waiting for their decision
+func GetTransferApprovals(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		manager, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		transfers, err := findTransfers(ctx, client, bson.D{
+			{Key: "approver_employee_id", Value: manager.EmployeeID},
+			{Key: "status", Value: "pending_approval"},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"transfers": transfers,
+			"total":     len(transfers),
+		})
+	}
+}
+
+// DecideTransfer - Manager approves or rejects a pending transfer
+func DecideTransfer(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transferID := c.Param("id")
+
+		var req models.TransferDecisionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		manager, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		transferCollection := database.OpenCollection("coupon_transfers", client)
+		var transfer models.CouponTransfer
+		err = transferCollection.FindOne(ctx, bson.D{{Key: "transfer_id", Value: transferID}}).Decode(&transfer)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+			return
+		}
+		if transfer.ApproverEmployeeID != manager.EmployeeID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not the approver of this transfer"})
+			return
+		}
+		if transfer.Status != "pending_approval" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer has already been processed", "status": transfer.Status})
+			return
+		}
+
+		employeeCollection := database.OpenCollection("employees", client)
+		var sender, recipient models.Employee
+		if err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: transfer.FromEmployeeID}}).Decode(&sender); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sender not found"})
+			return
+		}
+		if err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: transfer.ToEmployeeID}}).Decode(&recipient); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+			return
+		}
+
+		now := time.Now()
+		newStatus := "rejected"
+		if req.Approved {
+			newStatus = "completed"
+		}
+		update := bson.M{
+			"status":          newStatus,
+			"decision_reason": req.Reason,
+			"decided_at":      now,
+			"updated_at":      now,
+		}
+		if req.Approved {
+			update["completed_at"] = now
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start database session"})
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+			result, err := transferCollection.UpdateOne(
+				sessCtx,
+				bson.D{
+					{Key: "transfer_id", Value: transfer.TransferID},
+					{Key: "status", Value: "pending_approval"},
+				},
+				bson.D{{Key: "$set", Value: update}},
+			)
+			if err != nil {
+				return nil, err
+			}
+			if result.MatchedCount == 0 {
+				return nil, errTransferNotPending
+			}
+
+			if !req.Approved {
+				message := fmt.Sprintf("Your transfer of %d coupon(s) to %s was rejected.", transfer.Quantity, recipient.Name)
+				if req.Reason != "" {
+					message += " Reason: " + req.Reason
+				}
+				return nil, notifyUser(sessCtx, client, sender.UserID, "transfer_rejected", "Coupon transfer rejected", message, transfer.TransferID)
+			}
+
+			// The policy or either employee may have changed while the transfer waited
+			sender, recipient, err := enforceTransferPolicy(sessCtx, client, transfer)
+			if err != nil {
+				return nil, err
+			}
+			return nil, moveTransferredCoupons(sessCtx, client, transfer, sender, recipient)
+		})
+		if respondTransferPolicyError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, errTransferNotPending):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer has already been processed"})
+			return
+		case errors.Is(err, errInsufficientBalance):
+			c.JSON(http.StatusConflict, gin.H{"error": "Sender no longer has enough coupons"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process transfer"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "Transfer " + newStatus,
+			"transfer_id": transfer.TransferID,
+			"status":      newStatus,
+		})
+	}
+}
+
+// CancelMyTransfer - Sender withdraws a transfer that is still waiting for approval
+func CancelMyTransfer(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transferID := c.Param("id")
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		employee, err := findEmployeeForUser(c, ctx, client)
+		if err != nil {
+			return
+		}
+
+		now := time.Now()
+		transferCollection := database.OpenCollection("coupon_transfers", client)
+		result, err := transferCollection.UpdateOne(
+			ctx,
+			bson.D{
+				{Key: "transfer_id", Value: transferID},
+				{Key: "from_employee_id", Value: employee.EmployeeID},
+				{Key: "status", Value: "pending_approval"},
+			},
+			bson.D{{Key: "$set", Value: bson.M{
+				"status":     "cancelled",
+				"decided_at": now,
+				"updated_at": now,
+			}}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel transfer"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No pending transfer found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "Transfer cancelled",
+			"transfer_id": transferID,
+		})
+	}
+}
+
+// moveTransferredCoupons debits the sender and credits the recipient, recording both sides in the balance history.
+// It must be called inside a session transaction so neither side applies alone.
+func moveTransferredCoupons(ctx context.Context, client *mongo.Client, transfer models.CouponTransfer, sender, recipient models.Employee) error {
+	description := fmt.Sprintf("Transferred to %s (%s)", recipient.Name, recipient.EmployeeCode)
+	_, drawn, err := applyBalanceChange(ctx, client, sender.EmployeeID, couponBalanceChange{Company: -transfer.Quantity}, "transfer_out", transfer.TransferID, description)
+	if err != nil {
+		return err
+	}
+
+	// The coupons keep the expiry dates they had with the sender
+	issued := make([]models.LotDraw, 0, len(drawn))
+	for _, draw := range drawn {
+		issued = append(issued, models.LotDraw{Quantity: draw.Quantity, ExpiresAt: draw.ExpiresAt})
+	}
+	description = fmt.Sprintf("Received from %s (%s)", sender.Name, sender.EmployeeCode)
+	_, _, err = applyBalanceChange(ctx, client, recipient.EmployeeID, couponBalanceChange{Company: transfer.Quantity, Lots: issued}, "transfer_in", transfer.TransferID, description)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%s gave you %d coupon(s).", sender.Name, transfer.Quantity)
+	if transfer.Message != "" {
+		message += " \"" + transfer.Message + "\""
+	}
+	return notifyUser(ctx, client, recipient.UserID, "transfer_received", "Coupons received", message, transfer.TransferID)
+}
+
+// enforceTransferPolicy checks a transfer against the stored policy, the current state of both
+// employees and the sender's monthly limit, and returns both employees as they are now.
+// It must be called inside the session transaction that writes the transfer.
+func enforceTransferPolicy(ctx context.Context, client *mongo.Client, transfer models.CouponTransfer) (models.Employee, models.Employee, error) {
+	var sender, recipient models.Employee
+
+	settings, err := loadOrganizationSettings(ctx, client)
+	if err != nil {
+		return sender, recipient, err
+	}
+	policy := settings.TransferPolicy
+	if !policy.Enabled {
+		return sender, recipient, errTransfersDisabled
+	}
+
+	// Writing the sender's document makes concurrent transfers of the same sender conflict, so
+	// the retried one counts the transfer the other committed and the limit cannot be overrun
+	employeeCollection := database.OpenCollection("employees", client)
+	err = employeeCollection.FindOneAndUpdate(
+		ctx,
+		bson.D{{Key: "employee_id", Value: transfer.FromEmployeeID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "last_transfer_at", Value: time.Now()}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&sender)
+	if err != nil {
+		return sender, recipient, err
+	}
+	if err = employeeCollection.FindOne(ctx, bson.D{{Key: "employee_id", Value: transfer.ToEmployeeID}}).Decode(&recipient); err != nil {
+		return sender, recipient, err
+	}
+
+	if sender.Status != "active" && sender.Status != "on_leave" {
+		return sender, recipient, errTransferSenderInactive
+	}
+	if recipient.Status != "active" {
+		return sender, recipient, errTransferRecipientInactive
+	}
+	if policy.SameDepartmentOnly && (sender.DepartmentID == "" || sender.DepartmentID != recipient.DepartmentID) {
+		return sender, recipient, errTransferOtherDepartment
+	}
+
+	if policy.MaxPerMonth > 0 {
+		sent, err := couponsSentInMonth(ctx, client, sender.EmployeeID, transfer.TransferID, transfer.CreatedAt)
+		if err != nil {
+			return sender, recipient, err
+		}
+		if sent+transfer.Quantity > policy.MaxPerMonth {
+			return sender, recipient, fmt.Errorf("%w: %d of %d coupon(s) already sent this month", errTransferLimitExceeded, sent, policy.MaxPerMonth)
+		}
+	}
+	return sender, recipient, nil
+}
+
+// respondTransferPolicyError writes the response for an error of enforceTransferPolicy and
+// reports whether it did
+func respondTransferPolicyError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, errTransfersDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Coupon transfers are disabled"})
+	case errors.Is(err, errTransferSenderInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Sender account cannot transfer coupons"})
+	case errors.Is(err, errTransferRecipientInactive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Recipient is not active"})
+	case errors.Is(err, errTransferOtherDepartment):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Coupons can only be transferred within your department"})
+	case errors.Is(err, errTransferLimitExceeded):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Monthly transfer limit exceeded", "details": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+// couponsSentInMonth sums the coupons the employee sent or has pending in the calendar month of
+// at, leaving out the transfer being checked
+func couponsSentInMonth(ctx context.Context, client *mongo.Client, employeeID, excludeTransferID string, at time.Time) (int, error) {
+	monthStart := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "from_employee_id", Value: employeeID},
+			{Key: "transfer_id", Value: bson.D{{Key: "$ne", Value: excludeTransferID}}},
+			{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"pending_approval", "completed"}}}},
+			{Key: "created_at", Value: bson.D{{Key: "$gte", Value: monthStart}, {Key: "$lt", Value: monthEnd}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$quantity"}}},
+		}}},
+	}
+
+	transferCollection := database.OpenCollection("coupon_transfers", client)
+	cursor, err := transferCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Total int `bson:"total"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Total, nil
+}
+
+func findTransfers(ctx context.Context, client *mongo.Client, filter bson.D) ([]models.CouponTransfer, error) {
+	transferCollection := database.OpenCollection("coupon_transfers", client)
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := transferCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var transfers []models.CouponTransfer
+	if err = cursor.All(ctx, &transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
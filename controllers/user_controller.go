@@ -2,12 +2,15 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/muhaba7me/coupon-meal-system/database"
 	"github.com/muhaba7me/coupon-meal-system/models"
 	"github.com/muhaba7me/coupon-meal-system/utils"
@@ -42,6 +45,7 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc {
 
 		// Normalize role (optional)
 		userRequest.Role = strings.ToUpper(strings.TrimSpace(userRequest.Role))
+		userRequest.Email = utils.NormalizeEmail(userRequest.Email)
 
 		hashedPassword, err := HashPassword(userRequest.Password)
 		if err != nil {
@@ -109,7 +113,7 @@ func LoginUser(client *mongo.Client) gin.HandlerFunc {
 		var userCollection *mongo.Collection = database.OpenCollection("users", client)
 
 		var foundUser models.User
-		err := userCollection.FindOne(ctx, bson.D{{Key: "email", Value: userLogin.Email}}).Decode(&foundUser)
+		err := userCollection.FindOne(ctx, bson.D{{Key: "email", Value: utils.NormalizeEmail(userLogin.Email)}}).Decode(&foundUser)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
@@ -120,49 +124,90 @@ func LoginUser(client *mongo.Client) gin.HandlerFunc {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
 		}
-		token, refreshToken, err := utils.GenerateAllTokens(foundUser.Email, foundUser.FirstName, foundUser.LastName, foundUser.Role, foundUser.UserID)
+		if foundUser.DeactivatedAt != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account has been deactivated"})
+			return
+		}
+		if foundUser.MFA != nil && foundUser.MFA.EnabledAt != nil {
+			if foundUser.MFA.LockedAt != nil {
+				c.JSON(http.StatusLocked, mfaLockedResponse)
+				return
+			}
+			mfaToken, challenge, err := createMFAChallenge(ctx, client, c, foundUser.UserID, time.Now())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start MFA challenge"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+				"expires_at":   challenge.ExpiresAt,
+			})
+			return
+		}
 
+		settings, err := loadOrganizationSettings(ctx, client)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
 			return
 		}
-		err = utils.UpdateAllTokens(foundUser.UserID, token, refreshToken, client)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tokens"})
+		response, ok := startLoginSession(c, ctx, client, foundUser, passwordOnlyAuthentication)
+		if !ok {
 			return
 		}
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "access_token",
-			Value:    token,
-			Path:     "/",
-			Domain:   "",
-			MaxAge:   86400,
-			Secure:   true,
-			HttpOnly: true,
-			SameSite: http.SameSiteNoneMode,
-		})
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    refreshToken,
-			Path:     "/",
-			Domain:   "",
-			MaxAge:   86400,
-			Secure:   true,
-			HttpOnly: true,
-			SameSite: http.SameSiteNoneMode,
-		})
-		c.JSON(http.StatusOK, models.UserResponse{
-			UserId:    foundUser.UserID,
-			FirstName: foundUser.FirstName,
-			LastName:  foundUser.LastName,
-			Email:     foundUser.Email,
-			Role:      foundUser.Role,
-			Token: token,
-			RefreshToken: refreshToken,
-		})
+		response.MFAEnrollmentRequired = slices.Contains(settings.MFA.RequiredRoles, foundUser.Role)
+		c.JSON(http.StatusOK, response)
 	}
 }
 
+// startLoginSession creates a session for a user who has proven who they are with the methods in amr,
+// sets the token cookies and returns the login response. It writes the error response and returns
+// false when it fails.
+func startLoginSession(c *gin.Context, ctx context.Context, client *mongo.Client, foundUser models.User, amr []string) (models.UserResponse, bool) {
+	sessionID := bson.NewObjectID().Hex()
+	refreshTokenID := uuid.New().String()
+	token, refreshToken, err := utils.GenerateAllTokens(foundUser.Email, foundUser.FirstName, foundUser.LastName, foundUser.Role, foundUser.UserID, sessionID, refreshTokenID, amr)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return models.UserResponse{}, false
+	}
+	err = createSession(ctx, client, c, foundUser.UserID, sessionID, refreshTokenID, amr, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return models.UserResponse{}, false
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "access_token",
+		Value:    token,
+		Path:     "/",
+		Domain:   "",
+		MaxAge:   86400,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     "/",
+		Domain:   "",
+		MaxAge:   86400,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	return models.UserResponse{
+		UserId:    foundUser.UserID,
+		FirstName: foundUser.FirstName,
+		LastName:  foundUser.LastName,
+		Email:     foundUser.Email,
+		Role:      foundUser.Role,
+		Token: token,
+		RefreshToken: refreshToken,
+	}, true
+}
+
 func RefreshTokenHandler(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
@@ -192,9 +237,31 @@ func RefreshTokenHandler(client *mongo.Client) gin.HandlerFunc {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 			return
 		}
+		if user.DeactivatedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account has been deactivated"})
+			return
+		}
+		if claim.SessionID == "" || claim.ID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been signed out. Please log in again"})
+			return
+		}
 
-		newToken, newRefreshToken, _ := utils.GenerateAllTokens(user.Email, user.FirstName, user.LastName, user.Role, user.UserID)
-		err = utils.UpdateAllTokens(user.UserID, newToken, newRefreshToken, client)
+		// Sign the new pair before rotating, so a failure here leaves the presented token usable
+		newRefreshTokenID := uuid.New().String()
+		newToken, newRefreshToken, err := utils.GenerateAllTokens(user.Email, user.FirstName, user.LastName, user.Role, user.UserID, claim.SessionID, newRefreshTokenID, claim.AMR)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+			return
+		}
+		err = rotateSession(ctx, client, c, claim, newRefreshTokenID, time.Now())
+		if errors.Is(err, errSessionInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been signed out. Please log in again"})
+			return
+		}
+		if errors.Is(err, errRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used. The session has been signed out for safety, please log in again"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tokens"})
 			return
@@ -227,29 +294,29 @@ func RefreshTokenHandler(client *mongo.Client) gin.HandlerFunc {
 	}
 }
 
+// LogoutHandler - Signs the caller's own session out. Its access and refresh tokens stop working
+// right away; the user's other sessions are left alone.
 func LogoutHandler(client *mongo.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Clear the access_token cookie
-
-		var UserLogout struct {
-			UserId string `json:"user_id"`
-		}
-
-		err := c.ShouldBindJSON(&UserLogout)
+		userID, err := utils.GetUserIdFromContext(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
 
-		fmt.Println("User ID from Logout request:", UserLogout.UserId)
-
-		err = utils.UpdateAllTokens(UserLogout.UserId, "", "", client) // Clear tokens in the database
-		// Optionally, you can also remove the user session from the database if needed
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
 
+		err = revokeSessions(ctx, client, bson.D{
+			{Key: "session_id", Value: c.GetString("sessionId")},
+			{Key: "user_id", Value: userID},
+		}, "signed_out", time.Now())
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error logging out"})
 			return
 		}
+
+		// Clear the access_token cookie
 		// c.SetCookie(
 		// 	"access_token",
 		// 	"",
@@ -292,4 +359,84 @@ func LogoutHandler(client *mongo.Client) gin.HandlerFunc {
 
 		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 	}
-}
\ No newline at end of file
+}
+
+// NormalizeStoredEmails rewrites user and employee emails saved before they were normalized.
+// An email whose normalized form already belongs to another user, or to another employee, is left
+// as it is, since merging the two needs a decision; so is the employee record of such a user, so
+// the two stay in step. The skipped emails are returned so an admin can resolve them.
+func NormalizeStoredEmails(ctx context.Context, client *mongo.Client) (int, []string, error) {
+	userCollection := database.OpenCollection("users", client)
+	var users []models.User
+	if err := findNotNormalized(ctx, userCollection, &users); err != nil {
+		return 0, nil, err
+	}
+
+	updated := 0
+	var conflicts []string
+	conflictingUsers := map[string]bool{}
+	for _, user := range users {
+		ok, err := normalizeStoredEmail(ctx, userCollection, "user_id", user.UserID, user.Email)
+		if err != nil {
+			return updated, conflicts, err
+		}
+		if !ok {
+			conflictingUsers[user.UserID] = true
+			conflicts = append(conflicts, user.Email)
+			continue
+		}
+		updated++
+	}
+
+	employeeCollection := database.OpenCollection("employees", client)
+	var employees []models.Employee
+	if err := findNotNormalized(ctx, employeeCollection, &employees); err != nil {
+		return updated, conflicts, err
+	}
+	for _, employee := range employees {
+		if conflictingUsers[employee.UserID] {
+			continue
+		}
+		ok, err := normalizeStoredEmail(ctx, employeeCollection, "employee_id", employee.EmployeeID, employee.Email)
+		if err != nil {
+			return updated, conflicts, err
+		}
+		if !ok {
+			conflicts = append(conflicts, employee.Email)
+			continue
+		}
+		updated++
+	}
+	return updated, conflicts, nil
+}
+
+// findNotNormalized decodes the documents of a collection whose email is not normalized yet
+func findNotNormalized(ctx context.Context, collection *mongo.Collection, out interface{}) error {
+	cursor, err := collection.Find(ctx, bson.D{{Key: "$expr", Value: bson.D{{Key: "$ne", Value: bson.A{
+		"$email",
+		bson.D{{Key: "$toLower", Value: bson.D{{Key: "$trim", Value: bson.D{{Key: "input", Value: "$email"}}}}}},
+	}}}}})
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, out)
+}
+
+// normalizeStoredEmail normalizes the email of the document whose idField is id, unless another
+// document of the collection already has the normalized email. It reports whether it did.
+func normalizeStoredEmail(ctx context.Context, collection *mongo.Collection, idField, id, email string) (bool, error) {
+	normalized := utils.NormalizeEmail(email)
+	count, err := collection.CountDocuments(ctx, bson.D{
+		{Key: "email", Value: normalized},
+		{Key: idField, Value: bson.D{{Key: "$ne", Value: id}}},
+	})
+	if err != nil || count > 0 {
+		return false, err
+	}
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.D{{Key: idField, Value: id}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "email", Value: normalized}}}},
+	)
+	return err == nil, err
+}
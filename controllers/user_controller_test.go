@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestNormalizeStoredEmails(t *testing.T) {
+	client := testClient(t)
+	insertDocuments(t, client, "users",
+		models.User{UserID: "u-jane", Email: "Jane@Example.com"},
+		models.User{UserID: "u-jane-2", Email: "jane@example.com"},
+		models.User{UserID: "u-bob", Email: " Bob@Example.com "},
+	)
+	insertDocuments(t, client, "employees",
+		models.Employee{EmployeeID: "e-jane", UserID: "u-jane", Email: "Jane@Example.com"},
+		models.Employee{EmployeeID: "e-bob", UserID: "u-bob", Email: "BOB@example.com"},
+		models.Employee{EmployeeID: "e-dup", Email: "Dup@Example.com"},
+		models.Employee{EmployeeID: "e-dup-2", Email: "dup@example.com"},
+	)
+
+	migrate := func(ctx context.Context) (bson.M, error) {
+		updated, conflicts, err := NormalizeStoredEmails(ctx, client)
+		return bson.M{"updated": updated, "conflicts": conflicts}, err
+	}
+	ran, err := RunMigrationOnce(context.Background(), client, "normalize_emails", migrate)
+	if err != nil || !ran {
+		t.Fatalf("RunMigrationOnce() = %v, %v; want it to run", ran, err)
+	}
+
+	var migration models.Migration
+	findDocument(t, client, "migrations", bson.D{{Key: "migration_id", Value: "normalize_emails"}}, &migration)
+	if updated := fmt.Sprint(migration.Details["updated"]); updated != "2" {
+		t.Errorf("updated = %v, want 2", migration.Details["updated"])
+	}
+
+	tests := []struct {
+		collection string
+		idField    string
+		id         string
+		want       string
+	}{
+		{collection: "users", idField: "user_id", id: "u-jane", want: "Jane@Example.com"},
+		{collection: "users", idField: "user_id", id: "u-bob", want: "bob@example.com"},
+		{collection: "employees", idField: "employee_id", id: "e-jane", want: "Jane@Example.com"},
+		{collection: "employees", idField: "employee_id", id: "e-bob", want: "bob@example.com"},
+		{collection: "employees", idField: "employee_id", id: "e-dup", want: "Dup@Example.com"},
+	}
+	for _, tt := range tests {
+		var stored struct {
+			Email string `bson:"email"`
+		}
+		findDocument(t, client, tt.collection, bson.D{{Key: tt.idField, Value: tt.id}}, &stored)
+		if stored.Email != tt.want {
+			t.Errorf("%s %s email = %q, want %q", tt.collection, tt.id, stored.Email, tt.want)
+		}
+	}
+
+	conflicts, _ := migration.Details["conflicts"].(bson.A)
+	for _, email := range []string{"Jane@Example.com", "Dup@Example.com"} {
+		if !slices.Contains(conflicts, any(email)) {
+			t.Errorf("conflicts = %v, want %q reported", conflicts, email)
+		}
+	}
+
+	ran, err = RunMigrationOnce(context.Background(), client, "normalize_emails", migrate)
+	if err != nil || ran {
+		t.Errorf("second RunMigrationOnce() = %v, %v; want it skipped", ran, err)
+	}
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	controller "github.com/muhaba7me/coupon-meal-system/controllers"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StartNightlyCouponJobs expires coupon lots and runs the monthly allocation once a night at the given hour.
+// The allocation only issues coupons on the first run of each month.
+func StartNightlyCouponJobs(client *mongo.Client, hour int) {
+	go func() {
+		for {
+			time.Sleep(time.Until(nextRunAt(time.Now(), hour)))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			now := time.Now()
+
+			forfeited, drifted, err := controller.ExpireCouponLots(ctx, client, now)
+			if err != nil {
+				log.Println("Coupon expiry failed:", err)
+			} else if forfeited > 0 {
+				log.Printf("Expired %d coupons", forfeited)
+			}
+			for _, lot := range drifted {
+				log.Printf("ALERT: coupon lot %s of employee %s holds %d coupon(s) the balance does not cover; not expired", lot.LotID, lot.EmployeeID, lot.Remaining)
+			}
+
+			allocated, err := controller.RunMonthlyAllocation(ctx, client, now)
+			if err != nil {
+				log.Println("Monthly allocation failed:", err)
+			} else if allocated > 0 {
+				log.Printf("Issued the monthly allocation to %d employees", allocated)
+			}
+			cancel()
+		}
+	}()
+}
+
+// nextRunAt is the next time the clock reads hour:00 after now
+func nextRunAt(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
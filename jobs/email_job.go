@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	controller "github.com/muhaba7me/coupon-meal-system/controllers"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// NormalizeUserEmails brings emails stored before normalization into the form logins look up.
+// It runs at startup until it has completed once, then never again.
+func NormalizeUserEmails(client *mongo.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	_, err := controller.RunMigrationOnce(ctx, client, "normalize_emails", func(ctx context.Context) (bson.M, error) {
+		updated, conflicts, err := controller.NormalizeStoredEmails(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Normalized %d stored emails", updated)
+		for _, email := range conflicts {
+			log.Printf("Email %q differs from another account only by case or spacing; it cannot sign in until an admin resolves the duplicate", email)
+		}
+		return bson.M{"updated": updated, "conflicts": conflicts}, nil
+	})
+	return err
+}
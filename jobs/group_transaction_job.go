@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	controller "github.com/muhaba7me/coupon-meal-system/controllers"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StartGroupTransactionExpiry cancels group meals whose participants did not all approve in time.
+// It runs in the background every interval until the process exits.
+func StartGroupTransactionExpiry(client *mongo.Client, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			expired, err := controller.ExpireGroupTransactions(ctx, client)
+			cancel()
+
+			if err != nil {
+				log.Println("Group transaction expiry failed:", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Cancelled %d group transactions that were not approved in time", expired)
+			}
+		}
+	}()
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	controller "github.com/muhaba7me/coupon-meal-system/controllers"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StartOutboxDelivery sends queued outbound messages through the configured notifier.
+// It runs in the background every interval until the process exits.
+func StartOutboxDelivery(client *mongo.Client, interval time.Duration) {
+	notifier := utils.NewNotifier()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			sent, err := controller.DeliverOutbox(ctx, client, notifier)
+			cancel()
+
+			if err != nil {
+				log.Println("Outbox delivery failed:", err)
+				continue
+			}
+			if sent > 0 {
+				log.Printf("Delivered %d outbound messages", sent)
+			}
+		}
+	}()
+}
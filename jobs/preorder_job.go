@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	controller "github.com/muhaba7me/coupon-meal-system/controllers"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StartPreorderExpiry releases the coupons held by pre-orders nobody collected.
+// It runs in the background every interval until the process exits.
+func StartPreorderExpiry(client *mongo.Client, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			expired, err := controller.ExpirePreOrders(ctx, client)
+			cancel()
+
+			if err != nil {
+				log.Println("Pre-order expiry failed:", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Released coupons for %d uncollected pre-orders", expired)
+			}
+		}
+	}()
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	controller "github.com/muhaba7me/coupon-meal-system/controllers"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StartSigningKeyRotation loads the JWT keyring and then syncs it every interval, which picks up
+// keys rotated by other instances and rotates the ones that are due. The first load happens before
+// it returns, since no token can be signed or verified without it.
+func StartSigningKeyRotation(client *mongo.Client, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	_, err := controller.SyncSigningKeys(ctx, client)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			rotated, err := controller.SyncSigningKeys(ctx, client)
+			cancel()
+
+			if err != nil {
+				log.Println("Signing key sync failed:", err)
+				continue
+			}
+			if rotated > 0 {
+				log.Printf("Rotated %d JWT signing keys", rotated)
+			}
+		}
+	}()
+	return nil
+}
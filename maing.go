@@ -3,12 +3,15 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/jobs"
 	routes "github.com/muhaba7me/coupon-meal-system/routes"
+	"github.com/muhaba7me/coupon-meal-system/utils"
 )
 
 func main() {
@@ -19,6 +22,9 @@ func main() {
 	if err := godotenv.Load(".env"); err != nil {
 		log.Println("Warning: unable to find .env file")
 	}
+	if err := utils.CheckEncryptionKey(); err != nil {
+		log.Fatal("Refusing to start: ", err)
+	}
 
 	// Connect to MongoDB
 	
@@ -31,9 +37,22 @@ func main() {
 	})
 	
 	client := database.Connect()
+	if err := jobs.NormalizeUserEmails(client); err != nil {
+		log.Println("Failed to normalize stored emails, will retry on the next start:", err)
+	}
 	// Setup routes
 	routes.SetupUnProtectedRoutes(router, client)
 	routes.SetupProtectedRoutes(router, client)
+	routes.SetupSCIMRoutes(router, client)
+
+	// Background jobs
+	if err := jobs.StartSigningKeyRotation(client, time.Duration(utils.GetEnvAsInt("JWT_KEY_SYNC_MINUTES", 5))*time.Minute); err != nil {
+		log.Fatal("Failed to load JWT signing keys: ", err)
+	}
+	jobs.StartPreorderExpiry(client, time.Duration(utils.GetEnvAsInt("PREORDER_EXPIRY_INTERVAL_MINUTES", 5))*time.Minute)
+	jobs.StartGroupTransactionExpiry(client, time.Duration(utils.GetEnvAsInt("GROUP_EXPIRY_INTERVAL_MINUTES", 1))*time.Minute)
+	jobs.StartNightlyCouponJobs(client, utils.GetEnvAsInt("COUPON_JOB_HOUR", 1))
+	jobs.StartOutboxDelivery(client, time.Duration(utils.GetEnvAsInt("OUTBOX_INTERVAL_SECONDS", 15))*time.Second)
 
 	// Start server
 	if err := router.Run(":8080"); err != nil {
@@ -1,14 +1,34 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/muhaba7me/coupon-meal-system/database"
+	"github.com/muhaba7me/coupon-meal-system/models"
 	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 
-func AuthMiddleware() gin.HandlerFunc{
+// mfaSetupPaths stay reachable with a password-only login when the role requires MFA,
+// so the user can set it up
+var mfaSetupPaths = map[string]bool{
+	"/api/auth/mfa":                true,
+	"/api/auth/mfa/enroll":         true,
+	"/api/auth/mfa/enroll/confirm": true,
+	"/api/auth/logout":             true,
+}
+
+// AuthMiddleware admits requests with a valid access token whose session has not been revoked,
+// so logout, password changes and deactivation take effect without waiting for the token to expire.
+// The role and authentication methods come from the user and session documents, not the token claims.
+// Roles that require MFA also need a session whose login used a second factor.
+func AuthMiddleware(client *mongo.Client) gin.HandlerFunc{
 return  func(c *gin.Context) {
 	token, err := utils.GetAccessToken(c)
 	if err !=nil{
@@ -18,6 +38,7 @@ return  func(c *gin.Context) {
 	}
 	if token ==""{
 		c.JSON(http.StatusUnauthorized, gin.H{"error":"Not token provided"})
+		c.Abort()
 		return 
 	}
 	claims, err := utils.ValidateToken(token)
@@ -26,13 +47,102 @@ return  func(c *gin.Context) {
 		c.Abort()
 		return 
 	}
-	c.Set("userId", claims.UserId)
-		c.Set("email", claims.Email)
-		c.Set("firstName", claims.FirstName)
-		c.Set("lastName", claims.LastName)
-		c.Set("role", claims.Role)
+	session, user, err := activeSession(c, client, claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify session"})
+		c.Abort()
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been signed out. Please log in again"})
+		c.Abort()
+		return
+	}
+	if !slices.Contains(session.AMR, "mfa") && !mfaSetupPaths[c.FullPath()] {
+		required, err := mfaRequired(c, client, user.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify session"})
+			c.Abort()
+			return
+		}
+		if required {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                   "Multi-factor authentication is required for your role. Set it up to continue",
+				"mfa_enrollment_required": true,
+			})
+			c.Abort()
+			return
+		}
+	}
+	c.Set("userId", user.UserID)
+		c.Set("email", user.Email)
+		c.Set("firstName", user.FirstName)
+		c.Set("lastName", user.LastName)
+		c.Set("role", user.Role)
+		c.Set("sessionId", session.SessionID)
 	c.Next()
 }
 
 
-}
\ No newline at end of file
+}
+
+// activeSession loads the token's session, provided it still exists, has not been revoked and has not
+// expired, together with its user, provided the account is not deactivated. It returns nil when either
+// is missing, so an access token stops working once its session could no longer be refreshed.
+// Tokens issued before sessions were introduced carry no session and are refused.
+func activeSession(c *gin.Context, client *mongo.Client, claims *utils.SignedDetails) (*models.Session, *models.User, error) {
+	if claims.SessionID == "" {
+		return nil, nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	var session models.Session
+	sessionCollection := database.OpenCollection("sessions", client)
+	err := sessionCollection.FindOne(ctx, bson.D{
+		{Key: "session_id", Value: claims.SessionID},
+		{Key: "user_id", Value: claims.UserId},
+		{Key: "revoked_at", Value: nil},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var user models.User
+	userCollection := database.OpenCollection("users", client)
+	err = userCollection.FindOne(ctx, bson.D{
+		{Key: "user_id", Value: session.UserID},
+		{Key: "deactivated_at", Value: nil},
+	}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &session, &user, nil
+}
+
+// mfaRequired reports whether the organization settings require MFA for the role
+func mfaRequired(c *gin.Context, client *mongo.Client, role string) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	var settings struct {
+		MFA models.MFAPolicy `bson:"mfa"`
+	}
+	settingsCollection := database.OpenCollection("settings", client)
+	err := settingsCollection.FindOne(ctx, bson.D{{Key: "settings_id", Value: "default"}}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(settings.MFA.RequiredRoles, role), nil
+}
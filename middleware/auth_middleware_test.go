@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"github.com/muhaba7me/coupon-meal-system/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// testClient connects to the MongoDB named by TEST_MONGO_URI and points DATABASE_NAME at a fresh
+// database that is dropped when the test ends. Tests that need it are skipped when it is unset.
+func testClient(t *testing.T) *mongo.Client {
+	t.Helper()
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("coupon_meal_test_%d", time.Now().UnixNano())
+	t.Setenv("DATABASE_NAME", name)
+	t.Cleanup(func() {
+		client.Database(name).Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return client
+}
+
+// loadTestKeys fills both keyrings with a fresh key so tokens can be signed
+func loadTestKeys(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", "test-encryption-key-0123456789abcdef")
+	now := time.Now()
+	for _, purpose := range []string{utils.AccessTokenKeys, utils.RefreshTokenKeys} {
+		key, err := utils.NewSigningKey(purpose, "HS256", now.Add(-time.Minute), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := utils.LoadSigningKeys(purpose, []models.SigningKey{key}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		utils.LoadSigningKeys(utils.AccessTokenKeys, nil, now)
+		utils.LoadSigningKeys(utils.RefreshTokenKeys, nil, now)
+	})
+}
+
+// serveAuthenticated runs AuthMiddleware in front of a handler that reports the role it was given
+func serveAuthenticated(client *mongo.Client, authorization string) (*httptest.ResponseRecorder, bool) {
+	gin.SetMode(gin.TestMode)
+	reached := false
+	router := gin.New()
+	router.GET("/api/me", AuthMiddleware(client), func(c *gin.Context) {
+		reached = true
+		c.JSON(http.StatusOK, gin.H{"role": c.GetString("role"), "userId": c.GetString("userId")})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w, reached
+}
+
+func TestAuthMiddlewareRejectsBadTokens(t *testing.T) {
+	loadTestKeys(t)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.SignedDetails{
+		Role:             "ADMIN",
+		UserId:           "user-1",
+		SessionID:        "session-1",
+		AMR:              []string{"pwd", "otp", "mfa"},
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	})
+	kidless, err := forged.SignedString([]byte("coupon-meal-system-dont-reveal-secret-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{name: "no header", authorization: ""},
+		{name: "empty bearer token", authorization: "Bearer "},
+		{name: "garbage", authorization: "Bearer not-a-token"},
+		{name: "token without kid", authorization: "Bearer " + kidless},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No database: every case must be refused before the session is looked up
+			w, reached := serveAuthenticated(nil, tt.authorization)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+			if reached {
+				t.Error("the handler ran after the middleware refused the request")
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareTrustsSessionOverClaims(t *testing.T) {
+	client := testClient(t)
+	loadTestKeys(t)
+	ctx := context.Background()
+	now := time.Now()
+	db := client.Database(os.Getenv("DATABASE_NAME"))
+
+	users := []any{
+		models.User{UserID: "employee", Email: "employee@example.com", Role: "EMPLOYEE"},
+		models.User{UserID: "deactivated", Email: "gone@example.com", Role: "EMPLOYEE", DeactivatedAt: &now},
+	}
+	if _, err := db.Collection("users").InsertMany(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+	revokedAt := now.Add(-time.Minute)
+	sessions := []any{
+		models.Session{SessionID: "pwd-session", UserID: "employee", AMR: []string{"pwd"}, ExpiresAt: now.Add(time.Hour)},
+		models.Session{SessionID: "revoked-session", UserID: "employee", AMR: []string{"pwd"}, ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+		models.Session{SessionID: "expired-session", UserID: "employee", AMR: []string{"pwd"}, ExpiresAt: now.Add(-time.Second)},
+		models.Session{SessionID: "deactivated-session", UserID: "deactivated", AMR: []string{"pwd"}, ExpiresAt: now.Add(time.Hour)},
+	}
+	if _, err := db.Collection("sessions").InsertMany(ctx, sessions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		sessionID  string
+		claimRole  string
+		claimAMR   []string
+		mfaRoles   []string
+		wantStatus int
+		wantRole   string
+	}{
+		{name: "role comes from the user", userID: "employee", sessionID: "pwd-session", claimRole: "ADMIN", claimAMR: []string{"pwd"}, wantStatus: http.StatusOK, wantRole: "EMPLOYEE"},
+		{name: "MFA claim without an MFA session", userID: "employee", sessionID: "pwd-session", claimRole: "EMPLOYEE", claimAMR: []string{"pwd", "otp", "mfa"}, mfaRoles: []string{"EMPLOYEE"}, wantStatus: http.StatusForbidden},
+		{name: "MFA required for another role", userID: "employee", sessionID: "pwd-session", claimRole: "EMPLOYEE", claimAMR: []string{"pwd"}, mfaRoles: []string{"ADMIN"}, wantStatus: http.StatusOK, wantRole: "EMPLOYEE"},
+		{name: "revoked session", userID: "employee", sessionID: "revoked-session", claimRole: "EMPLOYEE", claimAMR: []string{"pwd"}, wantStatus: http.StatusUnauthorized},
+		{name: "expired session", userID: "employee", sessionID: "expired-session", claimRole: "EMPLOYEE", claimAMR: []string{"pwd"}, wantStatus: http.StatusUnauthorized},
+		{name: "session of another user", userID: "deactivated", sessionID: "pwd-session", claimRole: "EMPLOYEE", claimAMR: []string{"pwd"}, wantStatus: http.StatusUnauthorized},
+		{name: "deactivated user", userID: "deactivated", sessionID: "deactivated-session", claimRole: "EMPLOYEE", claimAMR: []string{"pwd"}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.Collection("settings").UpdateOne(ctx,
+				bson.D{{Key: "settings_id", Value: "default"}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "mfa.required_roles", Value: tt.mfaRoles}}}},
+				options.UpdateOne().SetUpsert(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, _, err := utils.GenerateAllTokens("x@example.com", "X", "Y", tt.claimRole, tt.userID, tt.sessionID, "refresh-id", tt.claimAMR)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w, reached := serveAuthenticated(client, "Bearer "+token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler reached = %v for status %d", reached, w.Code)
+			}
+			if tt.wantRole != "" && !strings.Contains(w.Body.String(), `"role":"`+tt.wantRole+`"`) {
+				t.Errorf("body = %s, want role %s", w.Body.String(), tt.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SCIMAuthMiddleware admits identity provider requests carrying the SCIM_BEARER_TOKEN.
+// SCIM is disabled while no token is configured.
+func SCIMAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := os.Getenv("SCIM_BEARER_TOKEN")
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if expected == "" || !found || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			c.Header("Content-Type", "application/scim+json; charset=utf-8")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
+				"status":  "401",
+				"detail":  "Invalid or missing SCIM bearer token",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// BalanceAdjustment - A manual correction of an employee's company coupon balance
+type BalanceAdjustment struct {
+	ID                bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	AdjustmentID      string        `json:"adjustment_id" bson:"adjustment_id"`
+	EmployeeID        string        `json:"employee_id" bson:"employee_id"`
+	Quantity          int           `json:"quantity" bson:"quantity"` // positive credits, negative debits
+	ReasonCode        string        `json:"reason_code" bson:"reason_code"`
+	Note              string        `json:"note" bson:"note"`
+	Status            string        `json:"status" bson:"status"` // pending_approval | applied | rejected
+	RequestedByUserID string        `json:"requested_by_user_id" bson:"requested_by_user_id"`
+	ApprovedByUserID  string        `json:"approved_by_user_id,omitempty" bson:"approved_by_user_id,omitempty"` // the second admin, when approval was required
+	DecisionReason    string        `json:"decision_reason,omitempty" bson:"decision_reason,omitempty"`
+	DecidedAt         *time.Time    `json:"decided_at,omitempty" bson:"decided_at,omitempty"`
+	AppliedAt         *time.Time    `json:"applied_at,omitempty" bson:"applied_at,omitempty"`
+	CreatedAt         time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateBalanceAdjustmentRequest struct {
+	Quantity   int    `json:"quantity" binding:"required,ne=0"`
+	ReasonCode string `json:"reason_code" binding:"required,oneof=system_error missed_meal duplicate_charge goodwill migration other"`
+	Note       string `json:"note" binding:"required,min=3"`
+}
+
+type AdjustmentDecisionRequest struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
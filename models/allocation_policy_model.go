@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AllocationPolicy - A named coupon entitlement for a group of employees
+type AllocationPolicy struct {
+	ID                   bson.ObjectID    `json:"_id,omitempty" bson:"_id,omitempty"`
+	PolicyID             string           `json:"policy_id" bson:"policy_id"`
+	Name                 string           `json:"name" bson:"name"`
+	Description          string           `json:"description,omitempty" bson:"description,omitempty"`
+	CouponsPerWorkingDay int              `json:"coupons_per_working_day,omitempty" bson:"coupons_per_working_day,omitempty"`
+	MonthlyAllocation    int              `json:"monthly_allocation,omitempty" bson:"monthly_allocation,omitempty"` // fixed budget instead of a per-day rate
+	Rules                []AllocationRule `json:"rules" bson:"rules"`
+	Priority             int              `json:"priority" bson:"priority"` // highest matching priority wins
+	IsActive             bool             `json:"is_active" bson:"is_active"`
+	CreatedByUserID      string           `json:"created_by_user_id" bson:"created_by_user_id"`
+	CreatedAt            time.Time        `json:"created_at" bson:"created_at"`
+	UpdatedAt            time.Time        `json:"updated_at" bson:"updated_at"`
+}
+
+// AllocationRule - Matches employees on every field that is set
+type AllocationRule struct {
+	DepartmentID   string `json:"department_id,omitempty" bson:"department_id,omitempty"`
+	JobGrade       string `json:"job_grade,omitempty" bson:"job_grade,omitempty"`
+	ShiftPattern   string `json:"shift_pattern,omitempty" bson:"shift_pattern,omitempty"`
+	EmploymentType string `json:"employment_type,omitempty" bson:"employment_type,omitempty"`
+}
+
+type CreateAllocationPolicyRequest struct {
+	Name                 string           `json:"name" binding:"required"`
+	Description          string           `json:"description"`
+	CouponsPerWorkingDay int              `json:"coupons_per_working_day" binding:"min=0,max=3"`
+	MonthlyAllocation    int              `json:"monthly_allocation" binding:"min=0"`
+	Rules                []AllocationRule `json:"rules"`
+	Priority             int              `json:"priority"`
+}
+
+type UpdateAllocationPolicyRequest struct {
+	Name                 string            `json:"name"`
+	Description          *string           `json:"description"`
+	CouponsPerWorkingDay *int              `json:"coupons_per_working_day" binding:"omitempty,min=0,max=3"`
+	MonthlyAllocation    *int              `json:"monthly_allocation" binding:"omitempty,min=0"`
+	Rules                *[]AllocationRule `json:"rules"`
+	Priority             *int              `json:"priority"`
+	IsActive             *bool             `json:"is_active"`
+}
+
+// AssignAllocationPolicyRequest - An empty policy ID returns the employee to rule-based assignment
+type AssignAllocationPolicyRequest struct {
+	PolicyID string `json:"policy_id"`
+}
+
+// AllocationPreviewRow - What one employee would receive in a period
+type AllocationPreviewRow struct {
+	EmployeeID   string `json:"employee_id"`
+	EmployeeCode string `json:"employee_code"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	PolicyID     string `json:"policy_id,omitempty"`
+	PolicyName   string `json:"policy_name"`
+	AssignedBy   string `json:"assigned_by"` // explicit | rule | default
+	WorkingDays  int    `json:"working_days"`
+	Coupons      int    `json:"coupons"`
+	Note         string `json:"note,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AuditLogEntry - A record of an administrative action, kept for compliance
+type AuditLogEntry struct {
+	ID          bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	AuditID     string        `json:"audit_id" bson:"audit_id"`
+	ActorUserID string        `json:"actor_user_id" bson:"actor_user_id"`
+	Action      string        `json:"action" bson:"action"`           // e.g. adjustment.requested, adjustment.applied
+	EntityType  string        `json:"entity_type" bson:"entity_type"` // e.g. employee, adjustment
+	EntityID    string        `json:"entity_id" bson:"entity_id"`
+	Details     bson.M        `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AutoApprovalRule - Transactions matching an active rule complete without manual approval.
+// Organization rules apply to every employee, employee rules only to their owner.
+type AutoApprovalRule struct {
+	ID              bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	RuleID          string        `json:"rule_id" bson:"rule_id"`
+	Name            string        `json:"name" bson:"name"`
+	Scope           string        `json:"scope" bson:"scope"` // organization | employee
+	EmployeeID      string        `json:"employee_id,omitempty" bson:"employee_id,omitempty"`
+	MaxCoupons      int           `json:"max_coupons" bson:"max_coupons"`
+	SupplierIDs     []string      `json:"supplier_ids,omitempty" bson:"supplier_ids,omitempty"` // empty = any supplier
+	MealWindows     []MealWindow  `json:"meal_windows,omitempty" bson:"meal_windows,omitempty"` // empty = any time
+	IsActive        bool          `json:"is_active" bson:"is_active"`
+	CreatedByUserID string        `json:"created_by_user_id" bson:"created_by_user_id"`
+	CreatedAt       time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type MealWindow struct {
+	Start string `json:"start" bson:"start" binding:"required"` // HH:MM
+	End   string `json:"end" bson:"end" binding:"required"`     // HH:MM
+}
+
+type CreateAutoApprovalRuleRequest struct {
+	Name        string       `json:"name" binding:"required,min=2"`
+	Scope       string       `json:"scope" binding:"omitempty,oneof=organization employee"`
+	EmployeeID  string       `json:"employee_id"`
+	MaxCoupons  int          `json:"max_coupons" binding:"required,min=1,max=3"`
+	SupplierIDs []string     `json:"supplier_ids"`
+	MealWindows []MealWindow `json:"meal_windows" binding:"dive"`
+}
+
+type UpdateAutoApprovalRuleRequest struct {
+	Name        string        `json:"name"`
+	MaxCoupons  int           `json:"max_coupons" binding:"omitempty,min=1,max=3"`
+	SupplierIDs *[]string     `json:"supplier_ids"`
+	MealWindows *[]MealWindow `json:"meal_windows"`
+	IsActive    *bool         `json:"is_active"`
+}
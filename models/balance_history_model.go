@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// BalanceHistoryEntry - One signed change to an employee's coupon balance
+type BalanceHistoryEntry struct {
+	ID               bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	EntryID          string        `json:"entry_id" bson:"entry_id"`
+	EmployeeID       string        `json:"employee_id" bson:"employee_id"`
+	Type             string        `json:"type" bson:"type"`     // meal | transfer_in | transfer_out | preorder_hold | preorder_capture | preorder_release | top_up | allocation | expiry | termination | freeze | unfreeze | adjustment
+	Change           int           `json:"change" bson:"change"` // company-funded coupons
+	BalanceAfter     int           `json:"balance_after" bson:"balance_after"`
+	PaidChange       int           `json:"paid_change,omitempty" bson:"paid_change,omitempty"` // coupons bought through top-ups
+	PaidBalanceAfter int           `json:"paid_balance_after" bson:"paid_balance_after"`
+	Lots             []LotDraw     `json:"lots,omitempty" bson:"lots,omitempty"` // lots the company coupons came from or went to
+	ReferenceID      string        `json:"reference_id,omitempty" bson:"reference_id,omitempty"`
+	Description      string        `json:"description" bson:"description"`
+	CreatedAt        time.Time     `json:"created_at" bson:"created_at"`
+}
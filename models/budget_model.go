@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MealBudget - What a cost center may spend on company-funded meals in a period
+type MealBudget struct {
+	ID                   bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	BudgetID             string        `json:"budget_id" bson:"budget_id"`
+	CostCenter           string        `json:"cost_center" bson:"cost_center"`
+	Period               string        `json:"period" bson:"period"` // YYYY-MM or YYYY-Qn
+	PeriodStart          time.Time     `json:"period_start" bson:"period_start"`
+	PeriodEnd            time.Time     `json:"period_end" bson:"period_end"` // exclusive
+	Amount               float64       `json:"amount" bson:"amount"`
+	Spent                float64       `json:"spent" bson:"spent"`                                   // company-funded amount of completed meals, kept up to date as meals settle
+	SoftThresholdPercent int           `json:"soft_threshold_percent" bson:"soft_threshold_percent"` // alert only
+	HardThresholdPercent int           `json:"hard_threshold_percent" bson:"hard_threshold_percent"` // alert and block allocations and top-ups
+	SoftAlertedAt        *time.Time    `json:"soft_alerted_at,omitempty" bson:"soft_alerted_at,omitempty"`
+	HardAlertedAt        *time.Time    `json:"hard_alerted_at,omitempty" bson:"hard_alerted_at,omitempty"`
+	CreatedByUserID      string        `json:"created_by_user_id" bson:"created_by_user_id"`
+	CreatedAt            time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt            time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateMealBudgetRequest struct {
+	CostCenter           string  `json:"cost_center" binding:"required"`
+	Period               string  `json:"period" binding:"required"`
+	Amount               float64 `json:"amount" binding:"required,gt=0"`
+	SoftThresholdPercent int     `json:"soft_threshold_percent" binding:"omitempty,min=1,max=100"`
+	HardThresholdPercent int     `json:"hard_threshold_percent" binding:"omitempty,min=1,max=200"`
+}
+
+type UpdateMealBudgetRequest struct {
+	Amount               *float64 `json:"amount" binding:"omitempty,gt=0"`
+	SoftThresholdPercent *int     `json:"soft_threshold_percent" binding:"omitempty,min=1,max=100"`
+	HardThresholdPercent *int     `json:"hard_threshold_percent" binding:"omitempty,min=1,max=200"`
+}
+
+// BudgetVsActual - A budget's spend so far and the spend forecast for the whole period
+type BudgetVsActual struct {
+	MealBudget
+	Remaining           float64 `json:"remaining"`
+	PercentUsed         float64 `json:"percent_used"`
+	WorkingDays         int     `json:"working_days"`
+	WorkingDaysElapsed  int     `json:"working_days_elapsed"`
+	ForecastSpend       float64 `json:"forecast_spend"`
+	ForecastPercentUsed float64 `json:"forecast_percent_used"`
+	Status              string  `json:"status"` // ok | soft_exceeded | hard_exceeded
+}
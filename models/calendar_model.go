@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// CalendarDay - A date on which the canteen program is closed
+type CalendarDay struct {
+	ID              bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Date            string        `json:"date" bson:"date"` // YYYY-MM-DD, one entry per date
+	Type            string        `json:"type" bson:"type"` // public_holiday | site_closure
+	Name            string        `json:"name" bson:"name"`
+	Source          string        `json:"source" bson:"source"` // manual | ical | csv
+	CreatedByUserID string        `json:"created_by_user_id" bson:"created_by_user_id"`
+	CreatedAt       time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateCalendarDayRequest struct {
+	Date string `json:"date" binding:"required"`
+	Type string `json:"type" binding:"required,oneof=public_holiday site_closure"`
+	Name string `json:"name" binding:"required"`
+}
+
+type UpdateWorkWeekRequest struct {
+	WeekendDays          []int `json:"weekend_days" binding:"required,max=6,dive,min=0,max=6"` // 0 = Sunday
+	CouponsPerWorkingDay int   `json:"coupons_per_working_day" binding:"required,min=1,max=3"`
+}
+
+// CalendarImportResult - Outcome of an iCal or CSV import
+type CalendarImportResult struct {
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// CouponLot - Company-funded coupons issued together, consumed first-expiring-first
+type CouponLot struct {
+	ID                bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	LotID             string        `json:"lot_id" bson:"lot_id"`
+	EmployeeID        string        `json:"employee_id" bson:"employee_id"`
+	Source            string        `json:"source" bson:"source"` // allocation | transfer_in | ...; the balance history type that issued it
+	Quantity          int           `json:"quantity" bson:"quantity"`
+	Remaining         int           `json:"remaining" bson:"remaining"`
+	ForfeitedQuantity int           `json:"forfeited_quantity,omitempty" bson:"forfeited_quantity,omitempty"`
+	Status            string        `json:"status" bson:"status"` // active | exhausted | expired
+	IssuedAt          time.Time     `json:"issued_at" bson:"issued_at"`
+	ExpiresAt         *time.Time    `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // nil never expires
+	ExpiredAt         *time.Time    `json:"expired_at,omitempty" bson:"expired_at,omitempty"`
+	UpdatedAt         time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// LotDraw - Coupons taken from or given back to one lot
+type LotDraw struct {
+	LotID     string     `json:"lot_id,omitempty" bson:"lot_id,omitempty"`
+	Quantity  int        `json:"quantity" bson:"quantity"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
+
+// UpcomingExpiration - Coupons that expire on the same day
+type UpcomingExpiration struct {
+	Date     string `json:"date"`
+	Quantity int    `json:"quantity"`
+}
+
+// ForfeitureReportRow - Coupons forfeited in a month
+type ForfeitureReportRow struct {
+	Period    string `json:"period" bson:"_id"`
+	Coupons   int    `json:"coupons" bson:"coupons"`
+	Employees int    `json:"employees" bson:"employees"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Department - A node in the department hierarchy. Meal costs are charged to its cost center,
+// or to the nearest parent's when it has none.
+type Department struct {
+	ID                 bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	DepartmentID       string        `json:"department_id" bson:"department_id"`
+	Name               string        `json:"name" bson:"name"`
+	Code               string        `json:"code" bson:"code"`
+	ParentDepartmentID string        `json:"parent_department_id,omitempty" bson:"parent_department_id,omitempty"`
+	CostCenter         string        `json:"cost_center,omitempty" bson:"cost_center,omitempty"`
+	ManagerEmployeeID  string        `json:"manager_employee_id,omitempty" bson:"manager_employee_id,omitempty"`
+	IsActive           bool          `json:"is_active" bson:"is_active"`
+	CreatedAt          time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateDepartmentRequest struct {
+	Name               string `json:"name" binding:"required"`
+	Code               string `json:"code" binding:"required"`
+	ParentDepartmentID string `json:"parent_department_id"`
+	CostCenter         string `json:"cost_center"`
+	ManagerEmployeeID  string `json:"manager_employee_id"`
+}
+
+type UpdateDepartmentRequest struct {
+	Name               string  `json:"name"`
+	ParentDepartmentID *string `json:"parent_department_id"` // empty string makes it a top-level department
+	CostCenter         *string `json:"cost_center"`
+	ManagerEmployeeID  *string `json:"manager_employee_id"`
+	IsActive           *bool   `json:"is_active"`
+}
+
+// DepartmentAssignment - A period an employee belonged to a department; EndedAt is nil for the current one
+type DepartmentAssignment struct {
+	ID               bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	AssignmentID     string        `json:"assignment_id" bson:"assignment_id"`
+	EmployeeID       string        `json:"employee_id" bson:"employee_id"`
+	DepartmentID     string        `json:"department_id" bson:"department_id"`
+	CostCenter       string        `json:"cost_center,omitempty" bson:"cost_center,omitempty"`
+	StartedAt        time.Time     `json:"started_at" bson:"started_at"`
+	EndedAt          *time.Time    `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+	AssignedByUserID string        `json:"assigned_by_user_id" bson:"assigned_by_user_id"`
+}
+
+// ChargebackReportRow - Completed meal costs of one cost center in one month
+type ChargebackReportRow struct {
+	CostCenter          string  `json:"cost_center" bson:"cost_center"`
+	Period              string  `json:"period" bson:"period"`
+	Meals               int     `json:"meals" bson:"meals"`
+	Coupons             int     `json:"coupons" bson:"coupons"`
+	TotalAmount         float64 `json:"total_amount" bson:"total_amount"`
+	CompanyFundedAmount float64 `json:"company_funded_amount" bson:"company_funded_amount"`
+	Employees           int     `json:"employees" bson:"employees"`
+}
+
+// ChargebackEmployeeRow - One employee's meal costs within a cost center and month
+type ChargebackEmployeeRow struct {
+	EmployeeID          string  `json:"employee_id" bson:"employee_id"`
+	EmployeeCode        string  `json:"employee_code" bson:"employee_code"`
+	Name                string  `json:"name" bson:"name"`
+	Period              string  `json:"period" bson:"period"`
+	Meals               int     `json:"meals" bson:"meals"`
+	Coupons             int     `json:"coupons" bson:"coupons"`
+	TotalAmount         float64 `json:"total_amount" bson:"total_amount"`
+	CompanyFundedAmount float64 `json:"company_funded_amount" bson:"company_funded_amount"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// EmployeeImportRow - One row of a bulk employee import file
+type EmployeeImportRow struct {
+	Line                int    `json:"line"`
+	EmployeeCode        string `json:"employee_code"`
+	FirstName           string `json:"first_name"`
+	LastName            string `json:"last_name"`
+	Email               string `json:"email"`
+	Phone               string `json:"phone,omitempty"`
+	HireDate            string `json:"hire_date"`
+	DepartmentCode      string `json:"department_code,omitempty"`
+	ManagerEmployeeCode string `json:"manager_employee_code,omitempty"`
+	JobGrade            string `json:"job_grade,omitempty"`
+	ShiftPattern        string `json:"shift_pattern,omitempty"`
+	EmploymentType      string `json:"employment_type,omitempty"`
+	Notes               string `json:"notes,omitempty"`
+	Password            string `json:"-"` // initial password for new user accounts
+}
+
+// EmployeeImportRowResult - What the import would do, or did, with one row
+type EmployeeImportRowResult struct {
+	Line         int      `json:"line"`
+	EmployeeCode string   `json:"employee_code"`
+	Action       string   `json:"action"` // create | update | skip | error
+	Changes      []string `json:"changes,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// EmployeeImportJob - A committed import running in the background
+type EmployeeImportJob struct {
+	ID              bson.ObjectID            `json:"_id,omitempty" bson:"_id,omitempty"`
+	JobID           string                   `json:"job_id" bson:"job_id"`
+	FileName        string                   `json:"file_name" bson:"file_name"`
+	Status          string                   `json:"status" bson:"status"` // running | completed | failed
+	TotalRows       int                      `json:"total_rows" bson:"total_rows"`
+	Processed       int                      `json:"processed" bson:"processed"`
+	Created         int                      `json:"created" bson:"created"`
+	Updated         int                      `json:"updated" bson:"updated"`
+	Skipped         int                      `json:"skipped" bson:"skipped"`
+	Failed          int                      `json:"failed" bson:"failed"`
+	RowErrors       []EmployeeImportRowError `json:"row_errors" bson:"row_errors"`
+	Failure         string                   `json:"failure,omitempty" bson:"failure,omitempty"` // why the whole job stopped
+	CreatedByUserID string                   `json:"created_by_user_id" bson:"created_by_user_id"`
+	CreatedAt       time.Time                `json:"created_at" bson:"created_at"`
+	FinishedAt      *time.Time               `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	UpdatedAt       time.Time                `json:"updated_at" bson:"updated_at"`
+}
+
+type EmployeeImportRowError struct {
+	Line         int    `json:"line" bson:"line"`
+	EmployeeCode string `json:"employee_code" bson:"employee_code"`
+	Message      string `json:"message" bson:"message"`
+}
@@ -13,15 +13,29 @@ type Employee struct {
     Email                 string         `json:"email" bson:"email"`
     Phone                 string         `json:"phone,omitempty" bson:"phone,omitempty"`
     Status                string         `json:"status" bson:"status"`
+    DepartmentID          string         `json:"department_id,omitempty" bson:"department_id,omitempty"`
+    ManagerEmployeeID     string         `json:"manager_employee_id,omitempty" bson:"manager_employee_id,omitempty"`
+    JobGrade              string         `json:"job_grade,omitempty" bson:"job_grade,omitempty"`
+    ShiftPattern          string         `json:"shift_pattern,omitempty" bson:"shift_pattern,omitempty"`
+    EmploymentType        string         `json:"employment_type,omitempty" bson:"employment_type,omitempty"`
+    AllocationPolicyID    string         `json:"allocation_policy_id,omitempty" bson:"allocation_policy_id,omitempty"` // explicit assignment, overrides policy rules
     MonthlyAllocation     int            `json:"monthly_coupon_allocation" bson:"monthly_coupon_allocation"`
     CurrentBalance        int            `json:"current_coupon_balance" bson:"current_coupon_balance"`
+    HeldBalance           int            `json:"held_coupon_balance" bson:"held_coupon_balance"` // reserved for pre-orders, not spendable
+    PaidBalance           int            `json:"paid_coupon_balance" bson:"paid_coupon_balance"` // bought through top-ups, spent after company coupons
     LastAllocationDate    *time.Time     `json:"last_allocation_date,omitempty" bson:"last_allocation_date,omitempty"`
     HireDate              time.Time      `json:"hire_date" bson:"hire_date"`
     TerminationDate       *time.Time     `json:"termination_date,omitempty" bson:"termination_date,omitempty"`
+    BalanceFrozenAt       *time.Time     `json:"balance_frozen_at,omitempty" bson:"balance_frozen_at,omitempty"` // set when termination froze the balance
     CreatedByAdminID      string         `json:"created_by_admin_id,omitempty" bson:"created_by_admin_id,omitempty"`
     LastLogin             *time.Time     `json:"last_login,omitempty" bson:"last_login,omitempty"`
     IsVerified            bool           `json:"is_verified" bson:"is_verified"`
     Notes                 string         `json:"notes,omitempty" bson:"notes,omitempty"`
+    PinHash               string         `json:"-" bson:"pin_hash,omitempty"`
+    PinFailedAttempts     int            `json:"-" bson:"pin_failed_attempts"`
+    PinLockedUntil        *time.Time     `json:"pin_locked_until,omitempty" bson:"pin_locked_until,omitempty"`
+    PinUpdatedAt          *time.Time     `json:"pin_updated_at,omitempty" bson:"pin_updated_at,omitempty"`
+    LastTransferAt        *time.Time     `json:"-" bson:"last_transfer_at,omitempty"` // touched by every transfer so concurrent ones conflict
     CreatedAt             time.Time      `json:"created_at" bson:"created_at"`
     UpdatedAt             time.Time      `json:"updated_at" bson:"updated_at"`
 }
@@ -33,6 +47,11 @@ type CreateEmployeeRequest struct {
     Email        string `json:"email" binding:"required,email"`
     Phone        string `json:"phone"`
     HireDate     string `json:"hire_date" binding:"required"` 
+    DepartmentID      string `json:"department_id"`
+    ManagerEmployeeID string `json:"manager_employee_id"`
+    JobGrade          string `json:"job_grade"`
+    ShiftPattern      string `json:"shift_pattern"`
+    EmploymentType    string `json:"employment_type"`
     Notes        string `json:"notes"`
 }
 
@@ -40,6 +59,11 @@ type UpdateEmployeeRequest struct {
     Name   string `json:"name"`
     Phone  string `json:"phone"`
     Status string `json:"status"` 
+    DepartmentID      string `json:"department_id"`
+    ManagerEmployeeID string `json:"manager_employee_id"`
+    JobGrade          string `json:"job_grade"`
+    ShiftPattern      string `json:"shift_pattern"`
+    EmploymentType    string `json:"employment_type"`
     Notes  string `json:"notes"`
 }
 type EmployeeResponse struct {
@@ -49,10 +73,23 @@ type EmployeeResponse struct {
     Email              string     `json:"email"`
     Phone              string     `json:"phone"`
     Status             string     `json:"status"`
+    DepartmentID       string     `json:"department_id,omitempty"`
+    ManagerEmployeeID  string     `json:"manager_employee_id,omitempty"`
+    JobGrade           string     `json:"job_grade,omitempty"`
+    ShiftPattern       string     `json:"shift_pattern,omitempty"`
+    EmploymentType     string     `json:"employment_type,omitempty"`
+    AllocationPolicyID string     `json:"allocation_policy_id,omitempty"`
     MonthlyAllocation  int        `json:"monthly_coupon_allocation"`
     CurrentBalance     int        `json:"current_coupon_balance"`
+    PaidBalance        int        `json:"paid_coupon_balance"`
     LastAllocationDate *time.Time `json:"last_allocation_date,omitempty"`
     HireDate           time.Time  `json:"hire_date"`
     IsVerified         bool       `json:"is_verified"`
     CreatedAt          time.Time  `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// SetPinRequest - Employee sets or changes the PIN used on supplier terminals
+type SetPinRequest struct {
+    Pin      string `json:"pin" binding:"required,numeric,min=4,max=6"`
+    Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// GroupTransaction - One supplier checkout bundling a share per participating employee.
+// Shares are regular transactions carrying the group ID; the group completes only when every share is approved.
+type GroupTransaction struct {
+	ID                 bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	GroupTransactionID string        `json:"group_transaction_id" bson:"group_transaction_id"`
+	SupplierID         string        `json:"supplier_id" bson:"supplier_id"`
+	TransactionIDs     []string      `json:"transaction_ids" bson:"transaction_ids"`
+	ParticipantCount   int           `json:"participant_count" bson:"participant_count"`
+	ApprovedCount      int           `json:"approved_count" bson:"approved_count"`
+	TotalCoupons       int           `json:"total_coupons" bson:"total_coupons"`
+	TotalAmount        float64       `json:"total_amount" bson:"total_amount"`
+	Status             string        `json:"status" bson:"status"` // pending | completed | cancelled
+	Notes              string        `json:"notes,omitempty" bson:"notes,omitempty"`
+	CancelReason       string        `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
+	CompletedAt        *time.Time    `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ExpiresAt          time.Time     `json:"expires_at" bson:"expires_at"` // cancelled as a whole if not every share is approved by then
+	CreatedAt          time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type GroupParticipantRequest struct {
+	QRCode      string `json:"qr_code" binding:"required"`
+	CouponsUsed int    `json:"coupons_used" binding:"required,min=1,max=3"`
+}
+
+type InitiateGroupTransactionRequest struct {
+	Participants []GroupParticipantRequest `json:"participants" binding:"required,min=2,max=20,dive"`
+	Latitude     float64                   `json:"latitude,omitempty"`
+	Longitude    float64                   `json:"longitude,omitempty"`
+	Notes        string                    `json:"notes,omitempty"`
+}
+
+type CancelGroupTransactionRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type GroupParticipantStatus struct {
+	TransactionID string  `json:"transaction_id"`
+	EmployeeID    string  `json:"employee_id"`
+	EmployeeName  string  `json:"employee_name"`
+	EmployeeCode  string  `json:"employee_code"`
+	CouponsUsed   int     `json:"coupons_used"`
+	TotalAmount   float64 `json:"total_amount"`
+	Status        string  `json:"status"`
+}
+
+type GroupTransactionResponse struct {
+	GroupTransaction
+	Participants []GroupParticipantStatus `json:"participants"`
+	StatusCounts map[string]int           `json:"status_counts"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// GuestVoucher - Meal voucher for someone without an Employee record, charged to a host employee
+type GuestVoucher struct {
+	ID              bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	VoucherID       string        `json:"voucher_id" bson:"voucher_id"`
+	Code            string        `json:"code" bson:"code"` // UUID scanned by the supplier
+	GuestName       string        `json:"guest_name" bson:"guest_name"`
+	GuestEmail      string        `json:"guest_email,omitempty" bson:"guest_email,omitempty"`
+	GuestType       string        `json:"guest_type" bson:"guest_type"` // visitor | intern | contractor
+	HostEmployeeID  string        `json:"host_employee_id" bson:"host_employee_id"`
+	CostCenter      string        `json:"cost_center,omitempty" bson:"cost_center,omitempty"`
+	MaxUses         int           `json:"max_uses" bson:"max_uses"`
+	UsesRemaining   int           `json:"uses_remaining" bson:"uses_remaining"`
+	CouponsPerUse   int           `json:"coupons_per_use" bson:"coupons_per_use"`
+	ExpiresAt       time.Time     `json:"expires_at" bson:"expires_at"`
+	Status          string        `json:"status" bson:"status"` // active | exhausted | revoked
+	Notes           string        `json:"notes,omitempty" bson:"notes,omitempty"`
+	IssuedByAdminID string        `json:"issued_by_admin_id" bson:"issued_by_admin_id"`
+	LastUsedAt      *time.Time    `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	CreatedAt       time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateGuestVoucherRequest struct {
+	GuestName      string `json:"guest_name" binding:"required,min=2"`
+	GuestEmail     string `json:"guest_email" binding:"omitempty,email"`
+	GuestType      string `json:"guest_type" binding:"required,oneof=visitor intern contractor"`
+	HostEmployeeID string `json:"host_employee_id" binding:"required"`
+	CostCenter     string `json:"cost_center"`
+	MaxUses        int    `json:"max_uses" binding:"omitempty,min=1,max=100"`
+	CouponsPerUse  int    `json:"coupons_per_use" binding:"omitempty,min=1,max=3"`
+	ExpiresAt      string `json:"expires_at" binding:"required"` // YYYY-MM-DD, valid through the end of that day
+	Notes          string `json:"notes"`
+}
+
+type GuestVoucherResponse struct {
+	GuestVoucher
+	QRCodeImage string `json:"qr_code_image"` // Base64 encoded
+}
+
+// GuestUsageReportRow - Guest meals grouped by host employee and cost center
+type GuestUsageReportRow struct {
+	HostEmployeeID string  `json:"host_employee_id" bson:"host_employee_id"`
+	CostCenter     string  `json:"cost_center" bson:"cost_center"`
+	GuestType      string  `json:"guest_type" bson:"guest_type"`
+	Transactions   int     `json:"transactions" bson:"transactions"`
+	TotalCoupons   int     `json:"total_coupons" bson:"total_coupons"`
+	TotalAmount    float64 `json:"total_amount" bson:"total_amount"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Invitation - One-time link a new user follows to set their own password
+type Invitation struct {
+	ID              bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	InvitationID    string        `json:"invitation_id" bson:"invitation_id"`
+	UserID          string        `json:"user_id" bson:"user_id"`
+	Email           string        `json:"email" bson:"email"`
+	TokenHash       string        `json:"-" bson:"token_hash"`
+	ExpiresAt       time.Time     `json:"expires_at" bson:"expires_at"`
+	AcceptedAt      *time.Time    `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	RevokedAt       *time.Time    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"` // replaced by a newer invitation
+	CreatedByUserID string        `json:"created_by_user_id" bson:"created_by_user_id"`
+	CreatedAt       time.Time     `json:"created_at" bson:"created_at"`
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// OnboardEmployeeRequest - Creates the user account and the employee record in one call
+type OnboardEmployeeRequest struct {
+	FirstName         string `json:"first_name" binding:"required,min=2,max=100"`
+	LastName          string `json:"last_name" binding:"required,min=2,max=100"`
+	Email             string `json:"email" binding:"required,email"`
+	EmployeeCode      string `json:"employee_code" binding:"required"`
+	Phone             string `json:"phone"`
+	HireDate          string `json:"hire_date" binding:"required"`
+	DepartmentID      string `json:"department_id"`
+	ManagerEmployeeID string `json:"manager_employee_id"`
+	JobGrade          string `json:"job_grade"`
+	ShiftPattern      string `json:"shift_pattern"`
+	EmploymentType    string `json:"employment_type"`
+	Notes             string `json:"notes"`
+}
+
+// OnboardSupplierRequest - Creates the user account and the supplier profile in one call
+type OnboardSupplierRequest struct {
+	FirstName       string  `json:"first_name" binding:"required,min=2,max=100"`
+	LastName        string  `json:"last_name" binding:"required,min=2,max=100"`
+	Email           string  `json:"email" binding:"required,email"`
+	BusinessName    string  `json:"business_name" binding:"required,min=2"`
+	BusinessLicense string  `json:"business_license"`
+	ContactPerson   string  `json:"contact_person" binding:"required"`
+	Phone           string  `json:"phone" binding:"required"`
+	Address         string  `json:"address" binding:"required"`
+	Latitude        float64 `json:"latitude" binding:"required"`
+	Longitude       float64 `json:"longitude" binding:"required"`
+	LocationRadius  int     `json:"location_radius"`
+	BankAccount     string  `json:"bank_account"`
+	TaxID           string  `json:"tax_id"`
+	Notes           string  `json:"notes"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type MenuItem struct {
+	ID          bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	MenuItemID  string        `json:"menu_item_id" bson:"menu_item_id"`
+	SupplierID  string        `json:"supplier_id" bson:"supplier_id"`
+	Name        string        `json:"name" bson:"name"`
+	Description string        `json:"description,omitempty" bson:"description,omitempty"`
+	Category    string        `json:"category,omitempty" bson:"category,omitempty"`
+	Price       float64       `json:"price" bson:"price"`
+	CouponCost  int           `json:"coupon_cost" bson:"coupon_cost"`
+	IsAvailable bool          `json:"is_available" bson:"is_available"`
+	MealWindows []MealWindow  `json:"meal_windows,omitempty" bson:"meal_windows,omitempty"` // empty = served all day
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateMenuItemRequest struct {
+	Name        string       `json:"name" binding:"required,min=2"`
+	Description string       `json:"description"`
+	Category    string       `json:"category"`
+	Price       float64      `json:"price" binding:"required,gt=0"`
+	CouponCost  int          `json:"coupon_cost" binding:"min=0,max=3"`
+	MealWindows []MealWindow `json:"meal_windows" binding:"dive"`
+}
+
+type UpdateMenuItemRequest struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Category    string        `json:"category"`
+	Price       float64       `json:"price" binding:"omitempty,gt=0"`
+	CouponCost  *int          `json:"coupon_cost" binding:"omitempty,min=0,max=3"`
+	IsAvailable *bool         `json:"is_available"`
+	MealWindows *[]MealWindow `json:"meal_windows"`
+}
+
+// OrderLine - Snapshot of a menu item on a transaction
+type OrderLine struct {
+	MenuItemID string  `json:"menu_item_id" bson:"menu_item_id"`
+	Name       string  `json:"name" bson:"name"`
+	Quantity   int     `json:"quantity" bson:"quantity"`
+	UnitPrice  float64 `json:"unit_price" bson:"unit_price"`
+	CouponCost int     `json:"coupon_cost" bson:"coupon_cost"` // per unit
+	LineTotal  float64 `json:"line_total" bson:"line_total"`
+}
+
+type OrderLineRequest struct {
+	MenuItemID string `json:"menu_item_id" binding:"required"`
+	Quantity   int    `json:"quantity" binding:"required,min=1"`
+}
+
+type TopMenuItem struct {
+	MenuItemID   string  `json:"menu_item_id" bson:"_id"`
+	Name         string  `json:"name" bson:"name"`
+	QuantitySold int     `json:"quantity_sold" bson:"quantity_sold"`
+	OrderCount   int     `json:"order_count" bson:"order_count"`
+	Revenue      float64 `json:"revenue" bson:"revenue"`
+}
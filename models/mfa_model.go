@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// UserMFA - A user's TOTP enrollment. Secrets are sealed with utils.SealSecret.
+type UserMFA struct {
+	Secret                 string     `bson:"secret,omitempty"`
+	SecretEncrypted        bool       `bson:"secret_encrypted,omitempty"`
+	PendingSecret          string     `bson:"pending_secret,omitempty"` // waiting for the first code, replaces Secret when confirmed
+	PendingSecretEncrypted bool       `bson:"pending_secret_encrypted,omitempty"`
+	EnabledAt              *time.Time `bson:"enabled_at,omitempty"`
+	RecoveryCodeHashes     []string   `bson:"recovery_code_hashes,omitempty"`
+	LastUsedStep           int64      `bson:"last_used_step"`      // TOTP time step of the last accepted code
+	FailedAttempts         int        `bson:"failed_attempts"`     // wrong codes since the last accepted one, across all challenges
+	LockedAt               *time.Time `bson:"locked_at,omitempty"` // set after MFA_MAX_FAILURES wrong codes, cleared by an admin
+}
+
+// MFAPolicy - Which roles must sign in with a second factor
+type MFAPolicy struct {
+	RequiredRoles []string `json:"required_roles" bson:"required_roles"`
+}
+
+type UpdateMFAPolicyRequest struct {
+	RequiredRoles []string `json:"required_roles" binding:"dive,oneof=ADMIN EMPLOYEE SUPPLIER"`
+}
+
+// MFAChallenge - The second login step, handed out once the password has been accepted
+type MFAChallenge struct {
+	ID          bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ChallengeID string        `json:"challenge_id" bson:"challenge_id"`
+	UserID      string        `json:"user_id" bson:"user_id"`
+	TokenHash   string        `json:"-" bson:"token_hash"`
+	Attempts    int           `json:"attempts" bson:"attempts"`
+	IPAddress   string        `json:"ip_address" bson:"ip_address"`
+	ExpiresAt   time.Time     `json:"expires_at" bson:"expires_at"`
+	UsedAt      *time.Time    `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// VerifyMFALoginRequest - Completes a login with either a TOTP code or a recovery code
+type VerifyMFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableMFARequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
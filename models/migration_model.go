@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Migration - A one-off data migration that has completed, so it is not run again on the next start
+type Migration struct {
+	ID          bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	MigrationID string        `json:"migration_id" bson:"migration_id"`
+	Details     bson.M        `json:"details,omitempty" bson:"details,omitempty"`
+	CompletedAt time.Time     `json:"completed_at" bson:"completed_at"`
+}
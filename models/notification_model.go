@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Notification struct {
+	ID             bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	NotificationID string        `json:"notification_id" bson:"notification_id"`
+	UserID         string        `json:"user_id" bson:"user_id"`
+	Type           string        `json:"type" bson:"type"`
+	Title          string        `json:"title" bson:"title"`
+	Message        string        `json:"message" bson:"message"`
+	ReferenceID    string        `json:"reference_id,omitempty" bson:"reference_id,omitempty"`
+	IsRead         bool          `json:"is_read" bson:"is_read"`
+	ReadAt         *time.Time    `json:"read_at,omitempty" bson:"read_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at" bson:"created_at"`
+}
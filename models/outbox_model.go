@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// OutboxMessage - A message queued for delivery by the notifier, written in the same
+// transaction as the change it announces
+type OutboxMessage struct {
+	ID            bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	MessageID     string        `json:"message_id" bson:"message_id"`
+	To            string        `json:"to" bson:"to"`
+	Subject       string        `json:"subject" bson:"subject"`
+	Body          string        `json:"-" bson:"body"`        // may carry one-time tokens
+	Kind          string        `json:"kind" bson:"kind"`     // e.g. password_reset
+	Status        string        `json:"status" bson:"status"` // pending | sending | sent | failed
+	Attempts      int           `json:"attempts" bson:"attempts"`
+	LastError     string        `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	NextAttemptAt time.Time     `json:"next_attempt_at" bson:"next_attempt_at"`
+	LockedAt      *time.Time    `json:"locked_at,omitempty" bson:"locked_at,omitempty"`
+	SentAt        *time.Time    `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// PasswordReset - A single-use password reset token, stored as a hash
+type PasswordReset struct {
+	ID        bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ResetID   string        `json:"reset_id" bson:"reset_id"`
+	UserID    string        `json:"user_id" bson:"user_id"`
+	TokenHash string        `json:"-" bson:"token_hash"`
+	ExpiresAt time.Time     `json:"expires_at" bson:"expires_at"`
+	UsedAt    *time.Time    `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"` // replaced by a newer request
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// PreOrder - Meal ordered ahead for a pickup slot. Its coupons are held on the employee
+// until the supplier scans the order code, or released if the order is not collected.
+type PreOrder struct {
+	ID            bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	PreOrderID    string        `json:"preorder_id" bson:"preorder_id"`
+	Code          string        `json:"code" bson:"code"` // UUID scanned at pickup
+	EmployeeID    string        `json:"employee_id" bson:"employee_id"`
+	SupplierID    string        `json:"supplier_id" bson:"supplier_id"`
+	Items         []OrderLine   `json:"items" bson:"items"`
+	CouponsHeld   int           `json:"coupons_held" bson:"coupons_held"`
+	MealPrice     float64       `json:"meal_price" bson:"meal_price"`
+	SlotStart     time.Time     `json:"slot_start" bson:"slot_start"`
+	SlotEnd       time.Time     `json:"slot_end" bson:"slot_end"`
+	Status        string        `json:"status" bson:"status"` // reserved | collected | cancelled | expired
+	TransactionID string        `json:"transaction_id,omitempty" bson:"transaction_id,omitempty"`
+	Notes         string        `json:"notes,omitempty" bson:"notes,omitempty"`
+	CollectedAt   *time.Time    `json:"collected_at,omitempty" bson:"collected_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// PreorderSettings - Supplier configuration for pickup slots
+type PreorderSettings struct {
+	Enabled          bool         `json:"enabled" bson:"enabled"`
+	SlotMinutes      int          `json:"slot_minutes" bson:"slot_minutes"`
+	MaxOrdersPerSlot int          `json:"max_orders_per_slot" bson:"max_orders_per_slot"` // 0 = no cap
+	PickupWindows    []MealWindow `json:"pickup_windows" bson:"pickup_windows"`
+}
+
+type UpdatePreorderSettingsRequest struct {
+	Enabled          bool         `json:"enabled"`
+	SlotMinutes      int          `json:"slot_minutes" binding:"omitempty,min=5,max=120"`
+	MaxOrdersPerSlot int          `json:"max_orders_per_slot" binding:"min=0"`
+	PickupWindows    []MealWindow `json:"pickup_windows" binding:"required,min=1,dive"`
+}
+
+type CreatePreOrderRequest struct {
+	SupplierID string             `json:"supplier_id" binding:"required"`
+	Items      []OrderLineRequest `json:"items" binding:"required,min=1,dive"`
+	PickupAt   time.Time          `json:"pickup_at" binding:"required"`
+	Notes      string             `json:"notes,omitempty"`
+}
+
+type CollectPreOrderRequest struct {
+	Code              string `json:"code" binding:"required"`
+	OtherTenderMethod string `json:"other_tender_method,omitempty" binding:"omitempty,oneof=cash card mobile"`
+}
+
+type PreOrderResponse struct {
+	PreOrder
+	QRCodeImage string `json:"qr_code_image"` // Base64 encoded
+}
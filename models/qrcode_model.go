@@ -38,4 +38,8 @@ type ValidateQRResponse struct {
 	QRCodeID        string    `json:"qr_code_id,omitempty"`
 	ExpiresAt       time.Time `json:"expires_at,omitempty"`
 	Message         string    `json:"message,omitempty"`
+	IsGuestVoucher  bool      `json:"is_guest_voucher,omitempty"`
+	GuestName       string    `json:"guest_name,omitempty"`
+	UsesRemaining   int       `json:"uses_remaining,omitempty"`
+	CouponsPerUse   int       `json:"coupons_per_use,omitempty"`
 }
\ No newline at end of file
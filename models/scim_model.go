@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SCIMSettings - How identity provider data maps onto employees
+type SCIMSettings struct {
+	// AttributeMapping maps an employee field (employee_code, phone, hire_date, job_grade, shift_pattern,
+	// employment_type, manager_user_id) to a SCIM attribute path such as "title" or
+	// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber"
+	AttributeMapping map[string]string `json:"attribute_mapping" bson:"attribute_mapping"`
+	// GroupDepartments maps a SCIM group display name to the department its members are moved to
+	GroupDepartments map[string]string `json:"group_departments" bson:"group_departments"`
+}
+
+type UpdateSCIMSettingsRequest struct {
+	AttributeMapping map[string]string `json:"attribute_mapping" binding:"required"`
+	GroupDepartments map[string]string `json:"group_departments"`
+}
+
+// SCIMGroup - A group pushed by the identity provider
+type SCIMGroup struct {
+	ID            bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	GroupID       string        `json:"group_id" bson:"group_id"`
+	DisplayName   string        `json:"display_name" bson:"display_name"`
+	ExternalID    string        `json:"external_id,omitempty" bson:"external_id,omitempty"`
+	MemberUserIDs []string      `json:"member_user_ids" bson:"member_user_ids"`
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// SCIMListResponse - urn:ietf:params:scim:api:messages:2.0:ListResponse
+type SCIMListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// SCIMPatchRequest - urn:ietf:params:scim:api:messages:2.0:PatchOp
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations" binding:"required,min=1"`
+}
+
+type SCIMPatchOperation struct {
+	Op    string      `json:"op" binding:"required"` // add | replace | remove, any case
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
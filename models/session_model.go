@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Session - One login of a user. Every refresh token issued for the login belongs to the same
+// family (the session), and only the newest one is accepted.
+type Session struct {
+	ID             bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	SessionID      string        `json:"session_id" bson:"session_id"`
+	UserID         string        `json:"user_id" bson:"user_id"`
+	CurrentTokenID string        `json:"-" bson:"current_token_id"` // jti of the only refresh token that may be used next
+	Rotations      int           `json:"rotations" bson:"rotations"`
+	AMR            []string      `json:"amr" bson:"amr"` // authentication methods of the login
+	UserAgent      string        `json:"user_agent" bson:"user_agent"`
+	IPAddress      string        `json:"ip_address" bson:"ip_address"`
+	CreatedAt      time.Time     `json:"created_at" bson:"created_at"`
+	LastUsedAt     time.Time     `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt      time.Time     `json:"expires_at" bson:"expires_at"`
+	RevokedAt      *time.Time    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedReason  string        `json:"revoked_reason,omitempty" bson:"revoked_reason,omitempty"` // signed_out, password_changed, deactivated, refresh_token_reused, mfa_enabled, mfa_reset, mfa_locked
+	Current        bool          `json:"current" bson:"-"`                                         // the session making the request
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// OrganizationSettings - Single document holding org-wide policies
+type OrganizationSettings struct {
+	SettingsID           string            `json:"settings_id" bson:"settings_id"`
+	TransferPolicy       TransferPolicy    `json:"transfer_policy" bson:"transfer_policy"`
+	CarryOverPolicy      CarryOverPolicy   `json:"carry_over_policy" bson:"carry_over_policy"`
+	TerminationPolicy    TerminationPolicy `json:"termination_policy" bson:"termination_policy"`
+	WeekendDays          []int             `json:"weekend_days" bson:"weekend_days"` // time.Weekday values, 0 = Sunday
+	CouponsPerWorkingDay int               `json:"coupons_per_working_day" bson:"coupons_per_working_day"`
+	SCIM                 SCIMSettings      `json:"scim" bson:"scim"`
+	MFA                  MFAPolicy         `json:"mfa" bson:"mfa"`
+	UpdatedByUserID      string            `json:"updated_by_user_id,omitempty" bson:"updated_by_user_id,omitempty"`
+	UpdatedAt            time.Time         `json:"updated_at" bson:"updated_at"`
+}
+
+// TransferPolicy - Limits on employee-to-employee coupon transfers
+type TransferPolicy struct {
+	Enabled                 bool `json:"enabled" bson:"enabled"`
+	MaxPerMonth             int  `json:"max_per_month" bson:"max_per_month"` // coupons sent per calendar month, 0 = no limit
+	SameDepartmentOnly      bool `json:"same_department_only" bson:"same_department_only"`
+	RequiresManagerApproval bool `json:"requires_manager_approval" bson:"requires_manager_approval"`
+}
+
+// CarryOverPolicy - What happens to unused company coupons when the next allocation is issued
+type CarryOverPolicy struct {
+	Mode         string `json:"mode" bson:"mode"`                   // none | capped | full
+	Cap          int    `json:"cap" bson:"cap"`                     // coupons kept in capped mode
+	ExpiryMonths int    `json:"expiry_months" bson:"expiry_months"` // full mode: allocations expire this many months after issue, 0 = never
+}
+
+type UpdateCarryOverPolicyRequest struct {
+	Mode         string `json:"mode" binding:"required,oneof=none capped full"`
+	Cap          int    `json:"cap" binding:"min=0"`
+	ExpiryMonths int    `json:"expiry_months" binding:"min=0"`
+}
+
+// TerminationPolicy - What happens to unused company coupons when an employee is terminated
+type TerminationPolicy struct {
+	UnusedCoupons string `json:"unused_coupons" bson:"unused_coupons"` // forfeit | freeze
+}
+
+type UpdateTerminationPolicyRequest struct {
+	UnusedCoupons string `json:"unused_coupons" binding:"required,oneof=forfeit freeze"`
+}
+
+type UpdateTransferPolicyRequest struct {
+	Enabled                 *bool `json:"enabled"`
+	MaxPerMonth             *int  `json:"max_per_month" binding:"omitempty,min=0"`
+	SameDepartmentOnly      *bool `json:"same_department_only"`
+	RequiresManagerApproval *bool `json:"requires_manager_approval"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SigningKey - A key of the JWT keyring. The newest active key of a purpose signs new tokens;
+// every key that has not expired verifies them, so rotating does not sign anyone out.
+type SigningKey struct {
+	ID          bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	KID         string        `json:"kid" bson:"kid"`
+	Purpose     string        `json:"purpose" bson:"purpose"`     // access or refresh
+	Algorithm   string        `json:"algorithm" bson:"algorithm"` // HS256, RS256 or EdDSA
+	PrivateKey  string        `json:"-" bson:"private_key"`       // base64 HMAC secret or PKCS #8 key
+	Encrypted   bool          `json:"-" bson:"encrypted"`         // PrivateKey is sealed with JWT_KEY_ENCRYPTION_KEY
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+	ActivatesAt time.Time     `json:"activates_at" bson:"activates_at"`                 // published before it signs, so every instance knows it first
+	RetiredAt   *time.Time    `json:"retired_at,omitempty" bson:"retired_at,omitempty"` // stops signing
+	ExpiresAt   *time.Time    `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // stops verifying
+}
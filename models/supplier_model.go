@@ -22,6 +22,8 @@ type Supplier struct {
 	BankAccount     string        `json:"bank_account,omitempty" bson:"bank_account,omitempty"`
 	TaxID           string        `json:"tax_id,omitempty" bson:"tax_id,omitempty"`
 	Notes           string        `json:"notes,omitempty" bson:"notes,omitempty"` 
+	Preorder        *PreorderSettings `json:"preorder,omitempty" bson:"preorder,omitempty"`
+	ServiceSlots    []ServiceSlot `json:"service_slots,omitempty" bson:"service_slots,omitempty"`
 	CreatedByAdminID string       `json:"created_by_admin_id,omitempty" bson:"created_by_admin_id,omitempty"`
 	CreatedAt       time.Time     `json:"created_at" bson:"created_at"`
 	UpdatedAt       time.Time     `json:"updated_at" bson:"updated_at"`
@@ -84,4 +86,43 @@ type SupplierTotalsResponse struct {
 	CompletedThisMonth int     `json:"completed_this_month"`
 	EarningsToday      float64 `json:"earnings_today"`
 	EarningsThisMonth  float64 `json:"earnings_this_month"`
-}
\ No newline at end of file
+}
+
+// SupplierCloseOutResponse - Daily reconciliation of full takings against the company-payable portion
+type SupplierCloseOutResponse struct {
+	SupplierID        string             `json:"supplier_id"`
+	BusinessName      string             `json:"business_name"`
+	Date              string             `json:"date"`
+	Transactions      int                `json:"transactions"`
+	TotalCoupons      int                `json:"total_coupons"`
+	TotalTakings      float64            `json:"total_takings"`
+	CouponAmount      float64            `json:"coupon_amount"` // payable by the company
+	OtherTenderAmount float64            `json:"other_tender_amount"`
+	OtherTenders      map[string]float64 `json:"other_tenders"`
+}
+
+// ServiceSlot - Opening window with the number of meals the supplier can serve per hour
+type ServiceSlot struct {
+	Start        string `json:"start" bson:"start" binding:"required"` // HH:MM
+	End          string `json:"end" bson:"end" binding:"required"`     // HH:MM
+	MealsPerHour int    `json:"meals_per_hour" bson:"meals_per_hour" binding:"required,min=1"`
+}
+
+type UpdateServiceSlotsRequest struct {
+	ServiceSlots []ServiceSlot `json:"service_slots" binding:"required,dive"`
+}
+
+// SupplierAvailabilityResponse - Live open state and load of a supplier
+type SupplierAvailabilityResponse struct {
+	SupplierID           string  `json:"supplier_id"`
+	BusinessName         string  `json:"business_name"`
+	Address              string  `json:"address"`
+	DistanceMeters       int     `json:"distance_meters,omitempty"`
+	IsOpen               bool    `json:"is_open"`
+	OpenUntil            string  `json:"open_until,omitempty"`
+	CapacityPerHour      int     `json:"capacity_per_hour"`
+	RecentTransactions   int     `json:"recent_transactions"`
+	DemandPerHour        float64 `json:"demand_per_hour"`
+	LoadLevel            string  `json:"load_level"` // low | moderate | high | unknown | closed
+	EstimatedWaitMinutes int     `json:"estimated_wait_minutes"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// CouponTopUp - Coupons an employee bought on top of the allocation, recovered through payroll
+type CouponTopUp struct {
+	ID            bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	TopUpID       string        `json:"top_up_id" bson:"top_up_id"`
+	EmployeeID    string        `json:"employee_id" bson:"employee_id"`
+	Quantity      int           `json:"quantity" bson:"quantity"`
+	UnitPrice     float64       `json:"unit_price" bson:"unit_price"`
+	TotalCost     float64       `json:"total_cost" bson:"total_cost"`
+	PayrollPeriod string        `json:"payroll_period" bson:"payroll_period"` // YYYY-MM the cost is deducted in
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at"`
+}
+
+type CreateTopUpRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+// PayrollDeductionRow - One employee's top-up cost for a payroll period
+type PayrollDeductionRow struct {
+	EmployeeID   string  `json:"employee_id" bson:"_id"`
+	EmployeeCode string  `json:"employee_code" bson:"employee_code"`
+	Name         string  `json:"name" bson:"name"`
+	Quantity     int     `json:"quantity" bson:"quantity"`
+	TotalCost    float64 `json:"total_cost" bson:"total_cost"`
+	TopUpCount   int     `json:"top_up_count" bson:"top_up_count"`
+}
+
+// MealFundingReportRow - Completed meals split by who funded the coupons
+type MealFundingReportRow struct {
+	Period               string  `json:"period" bson:"_id"`
+	Meals                int     `json:"meals" bson:"meals"`
+	CompanyCoupons       int     `json:"company_coupons" bson:"company_coupons"`
+	PaidCoupons          int     `json:"paid_coupons" bson:"paid_coupons"`
+	CompanyFundedAmount  float64 `json:"company_funded_amount" bson:"company_funded_amount"`
+	EmployeeFundedAmount float64 `json:"employee_funded_amount" bson:"employee_funded_amount"`
+}
@@ -11,11 +11,24 @@ type Transaction struct {
 	EmployeeID       string        `json:"employee_id" bson:"employee_id"`
 	SupplierID       string        `json:"supplier_id" bson:"supplier_id"`
 	QRCodeID         string        `json:"qr_code_id" bson:"qr_code_id"`
+	GroupTransactionID string      `json:"group_transaction_id,omitempty" bson:"group_transaction_id,omitempty"`
+	PreOrderID       string        `json:"preorder_id,omitempty" bson:"preorder_id,omitempty"`
+	GuestVoucherID   string        `json:"guest_voucher_id,omitempty" bson:"guest_voucher_id,omitempty"`
+	DepartmentID     string        `json:"department_id,omitempty" bson:"department_id,omitempty"` // snapshot at approval
+	CostCenter       string        `json:"cost_center,omitempty" bson:"cost_center,omitempty"` // snapshot at approval, for chargeback
 	CouponsUsed      int           `json:"coupons_used" bson:"coupons_used"` // 1-3
-	TotalAmount      float64       `json:"total_amount" bson:"total_amount"` // CouponsUsed × 45
+	Items            []OrderLine   `json:"items,omitempty" bson:"items,omitempty"`
+	TotalAmount      float64       `json:"total_amount" bson:"total_amount"` // CouponsUsed × 45, the coupon value owed to the supplier
+	PaidCoupons      int           `json:"paid_coupons,omitempty" bson:"paid_coupons,omitempty"` // part of CouponsUsed drawn from top-ups
+	EmployeeFundedAmount float64   `json:"employee_funded_amount,omitempty" bson:"employee_funded_amount,omitempty"` // part of TotalAmount covered by paid top-ups
+	MealPrice        float64       `json:"meal_price,omitempty" bson:"meal_price,omitempty"` // full price of the meal
+	OtherTenderAmount float64      `json:"other_tender_amount,omitempty" bson:"other_tender_amount,omitempty"` // MealPrice - TotalAmount, paid by the employee
+	OtherTenderMethod string       `json:"other_tender_method,omitempty" bson:"other_tender_method,omitempty"` // cash | card | mobile
 	EmployeeLatitude  float64      `json:"employee_latitude,omitempty" bson:"employee_latitude,omitempty"`
 	EmployeeLongitude float64      `json:"employee_longitude,omitempty" bson:"employee_longitude,omitempty"`
 	Status           string        `json:"status" bson:"status"`
+	ApprovalMethod   string        `json:"approval_method,omitempty" bson:"approval_method,omitempty"` // app | pin | auto | preorder | voucher
+	AutoApprovalRuleID string      `json:"auto_approval_rule_id,omitempty" bson:"auto_approval_rule_id,omitempty"`
 	Notes            string        `json:"notes,omitempty" bson:"notes,omitempty"`
 	ProcessedAt      time.Time     `json:"processed_at" bson:"processed_at"`
 	CreatedAt        time.Time     `json:"created_at" bson:"created_at"`
@@ -25,16 +38,20 @@ type Transaction struct {
 // Request models
 type InitiateTransactionRequest struct {
 	QRCode      string  `json:"qr_code" binding:"required"`
-	CouponsUsed int     `json:"coupons_used" binding:"required,min=1,max=3"`
+	CouponsUsed int     `json:"coupons_used" binding:"omitempty,min=1,max=3"` // derived from items when they are given
+	Items       []OrderLineRequest `json:"items,omitempty" binding:"omitempty,dive"`
 	SupplierID  string  `json:"supplier_id" binding:"required"`
 	Latitude    float64 `json:"latitude,omitempty"`
 	Longitude   float64 `json:"longitude,omitempty"`
 	Notes       string  `json:"notes,omitempty"`
+	Pin         string  `json:"pin,omitempty"` // entered by the employee on the supplier device
+	MealPrice   float64 `json:"meal_price,omitempty" binding:"omitempty,gt=0"` // defaults to the coupon value
+	OtherTenderMethod string `json:"other_tender_method,omitempty" binding:"omitempty,oneof=cash card mobile"`
 }
 
 type ApproveTransactionRequest struct {
 	TransactionID string `json:"transaction_id" binding:"required"`
-	Approved      bool   `json:"approved" binding:"required"`
+	Approved      *bool  `json:"approved" binding:"required"` // a pointer, so an explicit false passes required
 	Reason        string `json:"reason,omitempty"` 
 }
 
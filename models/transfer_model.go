@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type CouponTransfer struct {
+	ID                 bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	TransferID         string        `json:"transfer_id" bson:"transfer_id"`
+	FromEmployeeID     string        `json:"from_employee_id" bson:"from_employee_id"`
+	ToEmployeeID       string        `json:"to_employee_id" bson:"to_employee_id"`
+	Quantity           int           `json:"quantity" bson:"quantity"`
+	Message            string        `json:"message,omitempty" bson:"message,omitempty"`
+	Status             string        `json:"status" bson:"status"` // pending_approval | completed | rejected | cancelled
+	ApproverEmployeeID string        `json:"approver_employee_id,omitempty" bson:"approver_employee_id,omitempty"`
+	DecisionReason     string        `json:"decision_reason,omitempty" bson:"decision_reason,omitempty"`
+	DecidedAt          *time.Time    `json:"decided_at,omitempty" bson:"decided_at,omitempty"`
+	CompletedAt        *time.Time    `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	CreatedAt          time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateTransferRequest struct {
+	ToEmployeeCode string `json:"to_employee_code" binding:"required"`
+	Quantity       int    `json:"quantity" binding:"required,min=1"`
+	Message        string `json:"message"`
+}
+
+type TransferDecisionRequest struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
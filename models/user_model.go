@@ -18,6 +18,9 @@ type User struct {
 	UpdatedAt       time.Time     `json:"update_at" bson:"update_at"`
 	Token           string        `json:"token" bson:"token"`
 	RefreshToken    string        `json:"refresh_token" bson:"refresh_token"`
+	ExternalID      string        `json:"external_id,omitempty" bson:"external_id,omitempty"` // identity provider ID for SCIM-provisioned users
+	DeactivatedAt   *time.Time    `json:"deactivated_at,omitempty" bson:"deactivated_at,omitempty"`
+	MFA             *UserMFA      `json:"-" bson:"mfa,omitempty"`
 
 }
  type UserRequest struct {
@@ -39,4 +42,5 @@ type UserResponse struct {
 	Role            string  `json:"role"`
 	Token           string  `json:"token"`
 	RefreshToken    string  `json:"refresh_token"`
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"` // the role requires MFA, which has to be set up before anything else
 }
\ No newline at end of file
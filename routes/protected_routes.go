@@ -11,7 +11,7 @@ import (
 func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
 	api := router.Group("/api")
 	protected := api.Group("")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(client))
 
 	// =======================================
 	// 👑 ADMIN ROUTES
@@ -21,6 +21,16 @@ func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
 	{
 		// ✅ Admin-only: Register new users (employees/suppliers)
 		admin.POST("/register", controller.RegisterUser(client))
+		admin.POST("/users/:id/invitation", controller.ResendInvitation(client))
+		admin.POST("/users/:id/mfa/reset", controller.ResetUserMFA(client))
+		admin.POST("/users/:id/mfa/unlock", controller.UnlockUserMFA(client))
+
+		// --- Onboarding ---
+		onboarding := admin.Group("/onboarding")
+		{
+			onboarding.POST("/employees", controller.OnboardEmployee(client))
+			onboarding.POST("/suppliers", controller.OnboardSupplier(client))
+		}
 
 		// --- Employees Management ---
 		employees := admin.Group("/employees")
@@ -30,6 +40,44 @@ func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
 			employees.GET("/:id", controller.GetEmployeeByID(client))
 			employees.GET("/code/:code", controller.GetEmployeeByCode(client))
 			employees.PATCH("/:id", controller.UpdateEmployee(client))
+			employees.PATCH("/:id/pin/unlock", controller.UnlockEmployeePin(client))
+			employees.GET("/:id/balance-history", controller.GetEmployeeBalanceHistory(client))
+			employees.PUT("/:id/allocation-policy", controller.AssignAllocationPolicy(client))
+			employees.GET("/:id/departments", controller.GetEmployeeDepartmentHistory(client))
+			employees.POST("/:id/adjustments", controller.CreateBalanceAdjustment(client))
+		}
+
+		// --- Employee Imports ---
+		imports := admin.Group("/employee-imports")
+		{
+			imports.POST("/dry-run", controller.DryRunEmployeeImport(client))
+			imports.POST("", controller.StartEmployeeImport(client))
+			imports.GET("", controller.GetEmployeeImports(client))
+			imports.GET("/:id", controller.GetEmployeeImport(client))
+			imports.GET("/:id/errors", controller.GetEmployeeImportErrors(client))
+		}
+
+		// --- Departments & Cost Centers ---
+		departments := admin.Group("/departments")
+		{
+			departments.POST("", controller.CreateDepartment(client))
+			departments.GET("", controller.GetDepartments(client))
+			departments.PATCH("/:id", controller.UpdateDepartment(client))
+		}
+
+		// --- Balance Adjustments & Audit ---
+		admin.GET("/adjustments", controller.GetBalanceAdjustments(client))
+		admin.POST("/adjustments/:id/decision", controller.DecideBalanceAdjustment(client))
+		admin.GET("/audit-log", controller.GetAuditLog(client))
+		admin.GET("/outbox", controller.GetOutboxMessages(client))
+
+		// --- Meal Budgets ---
+		budgets := admin.Group("/budgets")
+		{
+			budgets.POST("", controller.CreateMealBudget(client))
+			budgets.GET("", controller.GetMealBudgets(client))
+			budgets.GET("/vs-actual", controller.GetBudgetVsActual(client))
+			budgets.PATCH("/:id", controller.UpdateMealBudget(client))
 		}
 
 		// --- Suppliers Management ---
@@ -40,9 +88,101 @@ func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
 			suppliers.GET("/:id", controller.GetSupplierByID(client))
 			suppliers.PATCH("/:id", controller.UpdateSupplier(client))
 			suppliers.PATCH("/:id/activate", controller.ActivateSupplier(client))
+			suppliers.GET("/:id/top-items", controller.GetSupplierTopMenuItems(client))
 			// suppliers.PATCH("/:id/verify", controller.Ve(client))
 		}
+
+		// --- Guest Vouchers ---
+		vouchers := admin.Group("/guest-vouchers")
+		{
+			vouchers.POST("", controller.CreateGuestVoucher(client))
+			vouchers.GET("", controller.GetGuestVouchers(client))
+			vouchers.GET("/:id", controller.GetGuestVoucherByID(client))
+			vouchers.POST("/:id/revoke", controller.RevokeGuestVoucher(client))
+		}
+
+		// --- Reports ---
+		reports := admin.Group("/reports")
+		{
+			reports.GET("/guest-usage", controller.GetGuestUsageReport(client))
+			reports.GET("/meal-funding", controller.GetMealFundingReport(client))
+			reports.GET("/payroll-top-ups", controller.GetPayrollDeductions(client))
+			reports.GET("/forfeitures", controller.GetForfeitureReport(client))
+			reports.GET("/chargeback", controller.GetChargebackReport(client))
+			reports.GET("/chargeback/:cost_center", controller.GetChargebackEmployees(client))
+		}
+
+		// --- Auto-Approval Rules ---
+		rules := admin.Group("/auto-approval-rules")
+		{
+			rules.POST("", controller.CreateAutoApprovalRule(client))
+			rules.GET("", controller.GetAutoApprovalRules(client))
+			rules.PATCH("/:id", controller.UpdateAutoApprovalRule(client))
+			rules.DELETE("/:id", controller.DeleteAutoApprovalRule(client))
+		}
+
+		// --- Organization Settings ---
+		settings := admin.Group("/settings")
+		{
+			settings.GET("", controller.GetOrganizationSettings(client))
+			settings.PUT("/transfer-policy", controller.UpdateTransferPolicy(client))
+			settings.PUT("/carry-over-policy", controller.UpdateCarryOverPolicy(client))
+			settings.PUT("/termination-policy", controller.UpdateTerminationPolicy(client))
+			settings.PUT("/scim", controller.UpdateSCIMSettings(client))
+			settings.PUT("/mfa", controller.UpdateMFAPolicy(client))
+		}
+
+		// --- Signing Keys ---
+		admin.GET("/signing-keys", controller.GetSigningKeys(client))
+		admin.POST("/signing-keys/rotate", controller.RotateSigningKeys(client))
+
+		// --- Calendar ---
+		calendar := admin.Group("/calendar")
+		{
+			calendar.GET("", controller.GetCalendar(client))
+			calendar.PUT("/work-week", controller.UpdateWorkWeek(client))
+			calendar.POST("/days", controller.CreateCalendarDay(client))
+			calendar.DELETE("/days/:date", controller.DeleteCalendarDay(client))
+			calendar.POST("/import", controller.ImportCalendar(client))
+		}
+
+		// --- Allocations ---
+		admin.POST("/allocations/run", controller.RunAllocation(client))
+		allocationPolicies := admin.Group("/allocation-policies")
+		{
+			allocationPolicies.POST("", controller.CreateAllocationPolicy(client))
+			allocationPolicies.GET("", controller.GetAllocationPolicies(client))
+			allocationPolicies.GET("/preview", controller.PreviewAllocation(client))
+			allocationPolicies.PUT("/:id", controller.UpdateAllocationPolicy(client))
+		}
+	}
+
+	// =======================================
+	// 🔑 ACCOUNT (any authenticated user)
+	// =======================================
+	account := protected.Group("/auth")
+	{
+		account.POST("/logout", controller.LogoutHandler(client))
+		account.POST("/password/change", controller.ChangePassword(client))
+		account.GET("/sessions", controller.GetMySessions(client))
+		account.DELETE("/sessions/:id", controller.RevokeMySession(client))
+
+		// --- Multi-Factor Authentication ---
+		account.GET("/mfa", controller.GetMyMFA(client))
+		account.POST("/mfa/enroll", controller.StartMFAEnrollment(client))
+		account.POST("/mfa/enroll/confirm", controller.ConfirmMFAEnrollment(client))
+		account.POST("/mfa/recovery-codes", controller.RegenerateRecoveryCodes(client))
+		account.POST("/mfa/disable", controller.DisableMyMFA(client))
+	}
+
+	// =======================================
+	// 🍽️ SUPPLIER DIRECTORY (any authenticated user)
+	// =======================================
+	directory := protected.Group("/suppliers")
+	{
+		directory.GET("/availability", controller.GetSupplierAvailability(client))
 	}
+	protected.GET("/calendar", controller.GetMonthCalendar(client))
 
 	// =======================================
 	// 👷 EMPLOYEE ROUTES
@@ -51,6 +191,36 @@ func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
 	{
 		employee.GET("/profile", controller.GetMyProfile(client))
 		employee.GET("/balance", controller.GetMyBalance(client))
+		employee.GET("/balance/history", controller.GetMyBalanceHistory(client))
+		employee.PUT("/pin", controller.SetMyPin(client))
+
+		// --- Notifications ---
+		employee.GET("/notifications", controller.GetMyNotifications(client))
+		employee.PATCH("/notifications/:id/read", controller.MarkNotificationRead(client))
+
+		// --- Auto-Approval Rules ---
+		employee.GET("/auto-approval-rules", controller.GetMyAutoApprovalRules(client))
+		employee.POST("/auto-approval-rules", controller.CreateMyAutoApprovalRule(client))
+		employee.DELETE("/auto-approval-rules/:id", controller.DeleteMyAutoApprovalRule(client))
+
+		// --- Supplier Menus ---
+		employee.GET("/suppliers/:id/menu", controller.GetSupplierMenu(client))
+
+		// --- Pre-Orders ---
+		employee.POST("/preorders", controller.CreatePreOrder(client))
+		employee.GET("/preorders", controller.GetMyPreOrders(client))
+		employee.POST("/preorders/:id/cancel", controller.CancelMyPreOrder(client))
+
+		// --- Coupon Top-Ups ---
+		employee.POST("/top-ups", controller.PurchaseTopUp(client))
+		employee.GET("/top-ups", controller.GetMyTopUps(client))
+
+		// --- Coupon Transfers ---
+		employee.POST("/transfers", controller.CreateTransfer(client))
+		employee.GET("/transfers", controller.GetMyTransfers(client))
+		employee.GET("/transfers/approvals", controller.GetTransferApprovals(client))
+		employee.POST("/transfers/:id/decision", controller.DecideTransfer(client))
+		employee.POST("/transfers/:id/cancel", controller.CancelMyTransfer(client))
 
 		// --- QR Codes ---
 		qr := employee.Group("/qr-codes")
@@ -73,14 +243,37 @@ func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
 	{
 		supplier.GET("/profile", controller.GetMySupplierProfile(client))
 		supplier.GET("/totals", controller.GetMyTotals(client))
+		supplier.GET("/close-out", controller.GetMyDailyCloseOut(client))
+		supplier.PUT("/service-slots", controller.UpdateServiceSlots(client))
 
 		supplier.POST("/validate-qr", controller.ValidateQRcode(client))
 
+		menu := supplier.Group("/menu")
+		{
+			menu.POST("", controller.CreateMenuItem(client))
+			menu.GET("", controller.GetMyMenu(client))
+			menu.GET("/top-items", controller.GetMyTopMenuItems(client))
+			menu.PATCH("/:id", controller.UpdateMenuItem(client))
+			menu.DELETE("/:id", controller.DeleteMenuItem(client))
+		}
+
+		preorders := supplier.Group("/preorders")
+		{
+			preorders.PUT("/settings", controller.UpdatePreorderSettings(client))
+			preorders.GET("", controller.GetSupplierPreOrders(client))
+			preorders.POST("/collect", controller.CollectPreOrder(client))
+		}
+
 		transactions := supplier.Group("/transactions")
 		{
 			transactions.POST("/initiate", controller.InitiateTransaction(client))
 			transactions.GET("/daily", controller.GetSupplierTransactions(client))
 			transactions.GET("/monthly", controller.GetSupplierTransactions(client))
+
+			// --- Group Meals ---
+			transactions.POST("/group", controller.InitiateGroupTransaction(client))
+			transactions.GET("/group/:id", controller.GetGroupTransaction(client))
+			transactions.POST("/group/:id/cancel", controller.CancelGroupTransaction(client))
 		}
 	}
 }
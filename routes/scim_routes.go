@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"log"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	controller "github.com/muhaba7me/coupon-meal-system/controllers"
+	"github.com/muhaba7me/coupon-meal-system/middleware"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// scimMinTokenLength keeps guessable bearer tokens from guarding user provisioning
+const scimMinTokenLength = 32
+
+// SetupSCIMRoutes exposes SCIM 2.0 provisioning for the identity provider.
+// The endpoints are not mounted at all unless a strong SCIM_BEARER_TOKEN is configured.
+func SetupSCIMRoutes(router *gin.Engine, client *mongo.Client) {
+	if token := os.Getenv("SCIM_BEARER_TOKEN"); len(token) < scimMinTokenLength {
+		log.Printf("SCIM provisioning is disabled: SCIM_BEARER_TOKEN must be set to at least %d characters", scimMinTokenLength)
+		return
+	}
+
+	scim := router.Group("/scim/v2")
+	scim.Use(middleware.SCIMAuthMiddleware())
+	{
+		scim.GET("/ServiceProviderConfig", controller.GetSCIMServiceProviderConfig())
+
+		users := scim.Group("/Users")
+		{
+			users.GET("", controller.GetSCIMUsers(client))
+			users.POST("", controller.CreateSCIMUser(client))
+			users.GET("/:id", controller.GetSCIMUser(client))
+			users.PUT("/:id", controller.ReplaceSCIMUser(client))
+			users.PATCH("/:id", controller.PatchSCIMUser(client))
+			users.DELETE("/:id", controller.DeleteSCIMUser(client))
+		}
+
+		groups := scim.Group("/Groups")
+		{
+			groups.GET("", controller.GetSCIMGroups(client))
+			groups.POST("", controller.CreateSCIMGroup(client))
+			groups.GET("/:id", controller.GetSCIMGroup(client))
+			groups.PUT("/:id", controller.ReplaceSCIMGroup(client))
+			groups.PATCH("/:id", controller.PatchSCIMGroup(client))
+			groups.DELETE("/:id", controller.DeleteSCIMGroup(client))
+		}
+	}
+}
@@ -7,10 +7,16 @@ import (
 )
 
 func SetupUnProtectedRoutes(router *gin.Engine, client *mongo.Client) {
+	router.GET("/.well-known/jwks.json", controller.GetJWKS())
+
 	public := router.Group("/api/auth")
 	{
 		public.POST("/login", controller.LoginUser(client))
+		public.POST("/login/mfa", controller.VerifyMFALogin(client))
 		public.POST("/refresh", controller.RefreshTokenHandler(client))
-		public.POST("/logout", controller.LogoutHandler(client))
+		public.GET("/invitations/:token", controller.GetInvitation(client))
+		public.POST("/invitations/accept", controller.AcceptInvitation(client))
+		public.POST("/password/forgot", controller.ForgotPassword(client))
+		public.POST("/password/reset", controller.ResetPassword(client))
 	}
 }
@@ -3,6 +3,7 @@ package utils
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 func GetEnvAsInt(key string, defaultValue int) int {
@@ -12,4 +13,10 @@ func GetEnvAsInt(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// NormalizeEmail is the one form emails are stored and looked up in, so an address matches
+// however the user typed it
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICalEvent - One day covered by a calendar event
+type ICalEvent struct {
+	Date    string // YYYY-MM-DD
+	Summary string
+}
+
+// ParseICalDays reads the VEVENTs of an iCalendar file and returns one entry per day they cover.
+// All-day events end the day before DTEND, as RFC 5545 defines DTEND as exclusive.
+func ParseICalDays(r io.Reader) ([]ICalEvent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []ICalEvent
+	var inEvent bool
+	var start, end time.Time
+	var allDay bool
+	var summary string
+
+	for _, line := range lines {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		params := strings.Split(name, ";")
+		switch strings.ToUpper(params[0]) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent = true
+				start, end, allDay, summary = time.Time{}, time.Time{}, false, ""
+			}
+		case "DTSTART":
+			if inEvent {
+				start, allDay, err = parseICalDate(value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "DTEND":
+			if inEvent {
+				end, _, err = parseICalDate(value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "SUMMARY":
+			if inEvent {
+				summary = unescapeICalText(value)
+			}
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("event %q has no DTSTART", summary)
+			}
+
+			last := start
+			if !end.IsZero() {
+				last = end
+				if allDay {
+					last = end.AddDate(0, 0, -1)
+				}
+			}
+			for day := start; !day.After(last); day = day.AddDate(0, 0, 1) {
+				days = append(days, ICalEvent{Date: day.Format("2006-01-02"), Summary: summary})
+			}
+		}
+	}
+	return days, nil
+}
+
+// unfoldICalLines joins continuation lines, which start with a space or tab
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalDate reads a DATE (20261225) or DATE-TIME (20261225T090000Z) value as a local calendar date
+func parseICalDate(value string) (time.Time, bool, error) {
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("invalid iCal date %q", value)
+	}
+	date, err := time.ParseInLocation("20060102", value[:8], time.Local)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid iCal date %q", value)
+	}
+	return date, len(value) == 8, nil
+}
+
+func unescapeICalText(value string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(replacer.Replace(value))
+}
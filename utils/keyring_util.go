@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/muhaba7me/coupon-meal-system/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Keyring purposes. Access and refresh tokens never share keys, so one cannot pass for the other.
+const (
+	AccessTokenKeys  = "access"
+	RefreshTokenKeys = "refresh"
+)
+
+var signingMethods = map[string]jwt.SigningMethod{
+	"HS256": jwt.SigningMethodHS256,
+	"RS256": jwt.SigningMethodRS256,
+	"EdDSA": jwt.SigningMethodEdDSA,
+}
+
+type keyringKey struct {
+	kid         string
+	method      jwt.SigningMethod
+	signKey     interface{}
+	verifyKey   interface{}
+	activatesAt time.Time
+	retiredAt   *time.Time
+	expiresAt   *time.Time
+}
+
+type keyring struct {
+	mu   sync.RWMutex
+	keys []keyringKey // newest first
+}
+
+var keyrings = map[string]*keyring{
+	AccessTokenKeys:  {},
+	RefreshTokenKeys: {},
+}
+
+// SigningAlgorithm returns the algorithm new keys of a purpose use, from JWT_ACCESS_ALGORITHM or
+// JWT_REFRESH_ALGORITHM. Refresh tokens are only ever read by this server, so HS256 is the default.
+func SigningAlgorithm(purpose string) string {
+	algorithm := os.Getenv("JWT_" + strings.ToUpper(purpose) + "_ALGORITHM")
+	if _, found := signingMethods[algorithm]; !found {
+		return "HS256"
+	}
+	return algorithm
+}
+
+// NewSigningKey generates a key for the keyring. Its private part is sealed with
+// JWT_KEY_ENCRYPTION_KEY.
+func NewSigningKey(purpose, algorithm string, activatesAt, now time.Time) (models.SigningKey, error) {
+	var material []byte
+	var err error
+	switch algorithm {
+	case "HS256":
+		material = make([]byte, 64)
+		_, err = rand.Read(material)
+	case "RS256":
+		var privateKey *rsa.PrivateKey
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err == nil {
+			material, err = x509.MarshalPKCS8PrivateKey(privateKey)
+		}
+	case "EdDSA":
+		var privateKey ed25519.PrivateKey
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+		if err == nil {
+			material, err = x509.MarshalPKCS8PrivateKey(privateKey)
+		}
+	default:
+		return models.SigningKey{}, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	sealed, err := SealSecret(material)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	return models.SigningKey{
+		KID:         bson.NewObjectID().Hex(),
+		Purpose:     purpose,
+		Algorithm:   algorithm,
+		PrivateKey:  sealed,
+		Encrypted:   true,
+		CreatedAt:   now,
+		ActivatesAt: activatesAt,
+	}, nil
+}
+
+// LoadSigningKeys replaces the keys of a purpose with the stored ones. Expired keys are dropped.
+func LoadSigningKeys(purpose string, stored []models.SigningKey, now time.Time) error {
+	ring, found := keyrings[purpose]
+	if !found {
+		return fmt.Errorf("unknown keyring %q", purpose)
+	}
+
+	keys := make([]keyringKey, 0, len(stored))
+	for _, key := range stored {
+		if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+			continue
+		}
+		parsed, err := parseSigningKey(key)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", key.KID, err)
+		}
+		keys = append(keys, parsed)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].activatesAt.After(keys[j].activatesAt)
+	})
+
+	ring.mu.Lock()
+	ring.keys = keys
+	ring.mu.Unlock()
+	return nil
+}
+
+func parseSigningKey(key models.SigningKey) (keyringKey, error) {
+	method, found := signingMethods[key.Algorithm]
+	if !found {
+		return keyringKey{}, fmt.Errorf("unsupported signing algorithm %q", key.Algorithm)
+	}
+	material, err := OpenSecret(key.PrivateKey, key.Encrypted)
+	if err != nil {
+		return keyringKey{}, err
+	}
+
+	parsed := keyringKey{
+		kid:         key.KID,
+		method:      method,
+		activatesAt: key.ActivatesAt,
+		retiredAt:   key.RetiredAt,
+		expiresAt:   key.ExpiresAt,
+	}
+	if key.Algorithm == "HS256" {
+		parsed.signKey, parsed.verifyKey = material, material
+		return parsed, nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(material)
+	if err != nil {
+		return keyringKey{}, err
+	}
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key.Algorithm == "RS256" {
+			parsed.signKey, parsed.verifyKey = privateKey, &privateKey.PublicKey
+			return parsed, nil
+		}
+	case ed25519.PrivateKey:
+		if key.Algorithm == "EdDSA" {
+			parsed.signKey, parsed.verifyKey = privateKey, privateKey.Public()
+			return parsed, nil
+		}
+	}
+	return keyringKey{}, errors.New("private key does not match its algorithm")
+}
+
+// signWithKeyring signs claims with the newest active key of a purpose and names it in the kid header
+func signWithKeyring(purpose string, claims jwt.Claims) (string, error) {
+	ring := keyrings[purpose]
+	now := time.Now()
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	for _, key := range ring.keys {
+		if key.activatesAt.After(now) || (key.retiredAt != nil && !now.Before(*key.retiredAt)) {
+			continue
+		}
+		token := jwt.NewWithClaims(key.method, claims)
+		token.Header["kid"] = key.kid
+		return token.SignedString(key.signKey)
+	}
+	return "", fmt.Errorf("no active %s signing key", purpose)
+}
+
+// parseWithKeyring verifies a token with the key its kid header names, accepting only that key's algorithm.
+// Tokens without a kid predate the keyring and are refused; they carried no session and could not be
+// used since sessions became mandatory.
+func parseWithKeyring(purpose, tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no key id")
+		}
+
+		ring := keyrings[purpose]
+		now := time.Now()
+		ring.mu.RLock()
+		defer ring.mu.RUnlock()
+		for _, key := range ring.keys {
+			if key.kid != kid {
+				continue
+			}
+			if key.expiresAt != nil && !now.Before(*key.expiresAt) {
+				break
+			}
+			if token.Method != key.method {
+				return nil, errors.New("unexpected signing method")
+			}
+			return key.verifyKey, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}, jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}))
+}
+
+// PublicJWKS returns the public access token keys as a JSON Web Key Set. HMAC keys are secret
+// and never listed, so only RS256 and EdDSA keys appear.
+func PublicJWKS() []map[string]string {
+	ring := keyrings[AccessTokenKeys]
+	now := time.Now()
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	jwks := []map[string]string{}
+	for _, key := range ring.keys {
+		if key.expiresAt != nil && !now.Before(*key.expiresAt) {
+			continue
+		}
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": key.kid,
+				"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"use": "sig",
+				"alg": "EdDSA",
+				"kid": key.kid,
+				"x":   base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+	return jwks
+}
+
+// minEncryptionKeyLength keeps JWT_KEY_ENCRYPTION_KEY from being a guessable word
+const minEncryptionKeyLength = 32
+
+// developmentEncryptionKey is the key the checked-in .env uses so a fresh checkout starts.
+// It is public, so it is only accepted with APP_ENV=development.
+const developmentEncryptionKey = "development-only-encryption-key-do-not-deploy"
+
+var errNoEncryptionKey = errors.New("JWT_KEY_ENCRYPTION_KEY is not set")
+
+// CheckEncryptionKey reports whether JWT_KEY_ENCRYPTION_KEY is usable. The server refuses to start
+// without one, since signing keys and MFA secrets would otherwise be stored in the clear.
+func CheckEncryptionKey() error {
+	secret := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if secret == "" {
+		return errNoEncryptionKey
+	}
+	if len(secret) < minEncryptionKeyLength {
+		return fmt.Errorf("JWT_KEY_ENCRYPTION_KEY must be at least %d characters", minEncryptionKeyLength)
+	}
+	if secret == developmentEncryptionKey && os.Getenv("APP_ENV") != "development" {
+		return errors.New("JWT_KEY_ENCRYPTION_KEY is the development key; generate one with `openssl rand -base64 32`")
+	}
+	return nil
+}
+
+// SealSecret encrypts a secret kept in the database, such as private key material or a TOTP secret,
+// with AES-GCM under JWT_KEY_ENCRYPTION_KEY. It refuses to store anything without that key.
+func SealSecret(material []byte) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	if gcm == nil {
+		return "", errNoEncryptionKey
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, material, nil)), nil
+}
+
+// OpenSecret reverses SealSecret. Secrets stored unencrypted by earlier versions are only decoded.
+func OpenSecret(sealed string, encrypted bool) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || !encrypted {
+		return data, err
+	}
+	gcm, err := secretCipher()
+	if err != nil {
+		return nil, err
+	}
+	if gcm == nil {
+		return nil, errNoEncryptionKey
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted secret is too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	secret := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if secret == "" {
+		return nil, nil
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/muhaba7me/coupon-meal-system/models"
+)
+
+const testEncryptionKey = "test-encryption-key-0123456789abcdef"
+
+func TestCheckEncryptionKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		appEnv  string
+		wantErr bool
+	}{
+		{name: "unset", key: "", wantErr: true},
+		{name: "too short", key: "change-me", wantErr: true},
+		{name: "long enough", key: testEncryptionKey},
+		{name: "development key in development", key: developmentEncryptionKey, appEnv: "development"},
+		{name: "development key in production", key: developmentEncryptionKey, appEnv: "production", wantErr: true},
+		{name: "development key without APP_ENV", key: developmentEncryptionKey, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_KEY_ENCRYPTION_KEY", tt.key)
+			t.Setenv("APP_ENV", tt.appEnv)
+			if err := CheckEncryptionKey(); (err != nil) != tt.wantErr {
+				t.Errorf("CheckEncryptionKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSealSecret(t *testing.T) {
+	material := []byte("JBSWY3DPEHPK3PXP")
+
+	t.Run("refuses without a key", func(t *testing.T) {
+		t.Setenv("JWT_KEY_ENCRYPTION_KEY", "")
+		if _, err := SealSecret(material); err == nil {
+			t.Fatal("SealSecret() stored a secret without an encryption key")
+		}
+	})
+
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", testEncryptionKey)
+	sealed, err := SealSecret(material)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := SealSecret(material)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := base64.StdEncoding.EncodeToString(material)
+	tampered := []byte(sealed)
+	tampered[len(tampered)-3] ^= 1
+
+	tests := []struct {
+		name      string
+		sealed    string
+		encrypted bool
+		key       string
+		wantErr   bool
+	}{
+		{name: "round trip", sealed: sealed, encrypted: true, key: testEncryptionKey},
+		{name: "unencrypted legacy value", sealed: legacy, encrypted: false, key: testEncryptionKey},
+		{name: "wrong key", sealed: sealed, encrypted: true, key: "another-encryption-key-0123456789ab", wantErr: true},
+		{name: "key removed", sealed: sealed, encrypted: true, key: "", wantErr: true},
+		{name: "tampered", sealed: string(tampered), encrypted: true, key: testEncryptionKey, wantErr: true},
+		{name: "too short", sealed: base64.StdEncoding.EncodeToString([]byte("short")), encrypted: true, key: testEncryptionKey, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_KEY_ENCRYPTION_KEY", tt.key)
+			opened, err := OpenSecret(tt.sealed, tt.encrypted)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(opened, material) {
+				t.Errorf("OpenSecret() = %q, want %q", opened, material)
+			}
+		})
+	}
+
+	if sealed == again {
+		t.Error("SealSecret() reused a nonce")
+	}
+	if strings.Contains(sealed, legacy) {
+		t.Error("SealSecret() stored the secret readable")
+	}
+}
+
+// loadTestKeyring fills the access keyring with one key per algorithm, plus a retired one and an
+// expired one, and empties it again when the test ends
+func loadTestKeyring(t *testing.T, now time.Time) map[string]models.SigningKey {
+	t.Helper()
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", testEncryptionKey)
+
+	keys := map[string]models.SigningKey{}
+	for name, algorithm := range map[string]string{"hs": "HS256", "rs": "RS256", "ed": "EdDSA", "retired": "EdDSA", "expired": "EdDSA"} {
+		key, err := NewSigningKey(AccessTokenKeys, algorithm, now.Add(-time.Hour), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[name] = key
+	}
+	retiredAt, graceEnds, expiredAt := now.Add(-time.Minute), now.Add(time.Hour), now.Add(-time.Second)
+	retired := keys["retired"]
+	retired.RetiredAt, retired.ExpiresAt = &retiredAt, &graceEnds
+	keys["retired"] = retired
+	expired := keys["expired"]
+	expired.RetiredAt, expired.ExpiresAt = &retiredAt, &expiredAt
+	keys["expired"] = expired
+
+	stored := make([]models.SigningKey, 0, len(keys))
+	for _, key := range keys {
+		stored = append(stored, key)
+	}
+	if err := LoadSigningKeys(AccessTokenKeys, stored, now); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { LoadSigningKeys(AccessTokenKeys, nil, now) })
+	return keys
+}
+
+// signWithStoredKey signs claims with a stored key directly, bypassing the keyring's choice of key
+func signWithStoredKey(t *testing.T, key models.SigningKey, method jwt.SigningMethod, kid string, claims jwt.Claims) string {
+	t.Helper()
+	parsed, err := parseSigningKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signKey := parsed.signKey
+	if method == jwt.SigningMethodHS256 && parsed.method != jwt.SigningMethodHS256 {
+		// Algorithm confusion: an HMAC token keyed with the published public key of an asymmetric key
+		publicKey, err := x509.MarshalPKIXPublicKey(parsed.verifyKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signKey = publicKey
+	}
+	signed, err := token.SignedString(signKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParseWithKeyring(t *testing.T) {
+	now := time.Now()
+	keys := loadTestKeyring(t, now)
+	claims := jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour))}
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	legacyToken, err := legacy.SignedString([]byte("coupon-meal-system-dont-reveal-secret-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := signWithKeyring(AccessTokenKeys, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredClaims := jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(now.Add(-time.Minute))}
+
+	tests := []struct {
+		name    string
+		purpose string
+		token   string
+		wantErr bool
+	}{
+		{name: "newest active key", purpose: AccessTokenKeys, token: current},
+		{name: "HS256 key", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["hs"], jwt.SigningMethodHS256, keys["hs"].KID, claims)},
+		{name: "RS256 key", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["rs"], jwt.SigningMethodRS256, keys["rs"].KID, claims)},
+		{name: "EdDSA key", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["ed"], jwt.SigningMethodEdDSA, keys["ed"].KID, claims)},
+		{name: "retired key within its grace period", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["retired"], jwt.SigningMethodEdDSA, keys["retired"].KID, claims)},
+		{name: "expired key", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["expired"], jwt.SigningMethodEdDSA, keys["expired"].KID, claims), wantErr: true},
+		{name: "unknown kid", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["ed"], jwt.SigningMethodEdDSA, "unknown", claims), wantErr: true},
+		{name: "kid of another key", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["ed"], jwt.SigningMethodEdDSA, keys["retired"].KID, claims), wantErr: true},
+		{name: "algorithm differs from the key", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["rs"], jwt.SigningMethodHS256, keys["rs"].KID, claims), wantErr: true},
+		{name: "wrong purpose", purpose: RefreshTokenKeys, token: current, wantErr: true},
+		{name: "expired token", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["ed"], jwt.SigningMethodEdDSA, keys["ed"].KID, expiredClaims), wantErr: true},
+		{name: "legacy token without kid", purpose: AccessTokenKeys, token: legacyToken, wantErr: true},
+		{name: "no kid, signed with an HMAC key of the keyring", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["hs"], jwt.SigningMethodHS256, "", claims), wantErr: true},
+		{name: "no kid and not HS256", purpose: AccessTokenKeys, token: signWithStoredKey(t, keys["ed"], jwt.SigningMethodEdDSA, "", claims), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseWithKeyring(tt.purpose, tt.token, &jwt.RegisteredClaims{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseWithKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
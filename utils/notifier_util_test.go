@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	token, err := GenerateSecureToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "reset link",
+			text: "Use this link: http://localhost:5173/reset-password?token=" + token,
+			want: "Use this link: http://localhost:5173/reset-password?token=[redacted]",
+		},
+		{
+			name: "bare token",
+			text: "Your invitation code is " + token + ".",
+			want: "Your invitation code is [redacted].",
+		},
+		{
+			name: "ordinary text is kept",
+			text: "Hello Jane,\n\nIf you did not ask for this, you can ignore this message.",
+			want: "Hello Jane,\n\nIf you did not ask for this, you can ignore this message.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RedactSecrets(tt.text)
+			if got != tt.want {
+				t.Errorf("RedactSecrets() = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(got, token) {
+				t.Errorf("RedactSecrets() kept the token")
+			}
+		})
+	}
+}
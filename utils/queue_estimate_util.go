@@ -0,0 +1,20 @@
+package utils
+
+import "math"
+
+// EstimateWaitMinutes - Approximate queue wait from demand and service capacity (meals per hour).
+// Uses the single-server queue formula and caps the result once demand reaches capacity.
+func EstimateWaitMinutes(demandPerHour, capacityPerHour float64, maxWait int) int {
+	if capacityPerHour <= 0 {
+		return 0
+	}
+
+	utilization := demandPerHour / capacityPerHour
+	if utilization >= 1 {
+		return maxWait
+	}
+
+	serviceMinutes := 60 / capacityPerHour
+	wait := utilization / (1 - utilization) * serviceMinutes
+	return int(math.Min(math.Ceil(wait), float64(maxWait)))
+}
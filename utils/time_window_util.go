@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseClockTime - Convert an "HH:MM" string to minutes after midnight
+func ParseClockTime(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// WithinTimeWindow - Check if the clock time of t falls between start and end ("HH:MM").
+// Windows that end before they start are treated as running past midnight.
+func WithinTimeWindow(start, end string, t time.Time) bool {
+	startMinutes, err := ParseClockTime(start)
+	if err != nil {
+		return false
+	}
+	endMinutes, err := ParseClockTime(end)
+	if err != nil {
+		return false
+	}
+	current := t.Hour()*60 + t.Minute()
+
+	if startMinutes <= endMinutes {
+		return current >= startMinutes && current < endMinutes
+	}
+	return current >= startMinutes || current < endMinutes
+}
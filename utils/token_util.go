@@ -1,17 +1,12 @@
 package utils
 
 import (
-	"context"
 	"errors"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	jwt "github.com/golang-jwt/jwt/v5"
-	"github.com/muhaba7me/coupon-meal-system/database"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 
@@ -22,13 +17,22 @@ type SignedDetails struct {
 	LastName  string
 	Role      string
 	UserId    string
+	SessionID string   // the login session (refresh token family) the token belongs to
+	AMR       []string `json:"amr,omitempty"` // how the user authenticated: pwd, plus otp and mfa after a second factor
 	jwt.RegisteredClaims
 }
 
-var SECRET_KEY string = os.Getenv("SECRET_KEY")
-var SECRET_REFRESH_KEY string = os.Getenv("SECRET_REFRESH_KEY")
+// AccessTokenTTL is how long an access token stays valid
+const AccessTokenTTL = 7 * 24 * time.Hour
 
-func GenerateAllTokens(email, firstName, lastName, role, userID string) (string, string, error) {
+// RefreshTokenTTL is how long a refresh token, and a session that is not refreshed, stays valid
+const RefreshTokenTTL = 24 * time.Hour
+
+
+// GenerateAllTokens signs an access and a refresh token for a session. refreshTokenID becomes the
+// refresh token's jti, which the session stores so each refresh token can be used only once.
+// amr is carried by both, so a refreshed pair keeps the login's authentication methods.
+func GenerateAllTokens(email, firstName, lastName, role, userID, sessionID, refreshTokenID string, amr []string) (string, string, error) {
 
 	claims := &SignedDetails{
 		Email:     email,
@@ -36,14 +40,15 @@ func GenerateAllTokens(email, firstName, lastName, role, userID string) (string,
 		LastName:  lastName,
 		Role:      role,
 		UserId:    userID,
+		SessionID: sessionID,
+		AMR:       amr,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "couponmeal",
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(SECRET_KEY))
+	signedToken, err := signWithKeyring(AccessTokenKeys, claims)
 
 	if err != nil {
 		return "", "", err
@@ -54,14 +59,16 @@ func GenerateAllTokens(email, firstName, lastName, role, userID string) (string,
 		LastName:  lastName,
 		Role:      role,
 		UserId:    userID,
+		SessionID: sessionID,
+		AMR:       amr,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        refreshTokenID,
 			Issuer:    "couponmeal",
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenTTL)),
 		},
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	signedRefreshToken, err := refreshToken.SignedString([]byte(SECRET_REFRESH_KEY))
+	signedRefreshToken, err := signWithKeyring(RefreshTokenKeys, refreshClaims)
 
 	if err != nil {
 		return "", "", err
@@ -69,29 +76,6 @@ func GenerateAllTokens(email, firstName, lastName, role, userID string) (string,
 	return signedToken, signedRefreshToken, nil
 }
 
-func UpdateAllTokens(userId, token, refreshToken string, client *mongo.Client) (err error) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	defer cancel()
-
-	updateAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-
-	updateData := bson.M{
-		"$set": bson.M{
-			"token":         token,
-			"refresh_token": refreshToken,
-			"update_at":     updateAt,
-		},
-	}
-	var userCollection *mongo.Collection = database.OpenCollection("users", client)
-	_, err = userCollection.UpdateOne(ctx, bson.M{"user_id": userId}, updateData)
-
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func GetAccessToken(c *gin.Context) (string, error) {
 
 	authHeader := c.Request.Header.Get("Authorization")
@@ -112,19 +96,11 @@ func GetAccessToken(c *gin.Context) (string, error) {
 func ValidateToken(tokenString string) (*SignedDetails, error) {
 	claims := &SignedDetails{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(SECRET_KEY), nil
-
-	})
+	_, err := parseWithKeyring(AccessTokenKeys, tokenString, claims)
 	if err != nil {
 		return nil, err
 	}
 
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, errors.New("invaild signing mehtod")
-
-	}
-
 	if claims.ExpiresAt.Time.Before(time.Now()) {
 		return nil, errors.New("token has expired")
 	}
@@ -146,19 +122,11 @@ func GetUserIdFromContext(c *gin.Context)(string, error){
 }
 func ValidateRefreshToken(tokenString string) (*SignedDetails, error) {
 	claims := &SignedDetails{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-
-		return []byte(SECRET_REFRESH_KEY), nil
-	})
-
+	_, err := parseWithKeyring(RefreshTokenKeys, tokenString, claims)
 	if err != nil {
 		return nil, err
 	}
 
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, err
-	}
-
 	if claims.ExpiresAt.Time.Before(time.Now()) {
 		return nil, errors.New("refresh token has expired")
 	}
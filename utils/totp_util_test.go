@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 test key of RFC 6238, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	at := func(unix int64) time.Time { return time.Unix(unix, 0) }
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      time.Time
+		wantStep int64
+		wantOK   bool
+	}{
+		// The RFC 6238 vectors are 8 digits; a 6 digit code is their last six
+		{name: "RFC vector 59", secret: rfc6238Secret, code: "287082", now: at(59), wantStep: 1, wantOK: true},
+		{name: "RFC vector 1111111109", secret: rfc6238Secret, code: "081804", now: at(1111111109), wantStep: 37037036, wantOK: true},
+		{name: "RFC vector 1234567890", secret: rfc6238Secret, code: "005924", now: at(1234567890), wantStep: 41152263, wantOK: true},
+		{name: "RFC vector 2000000000", secret: rfc6238Secret, code: "279037", now: at(2000000000), wantStep: 66666666, wantOK: true},
+		{name: "previous step within skew", secret: rfc6238Secret, code: "287082", now: at(89), wantStep: 1, wantOK: true},
+		{name: "next step within skew", secret: rfc6238Secret, code: "287082", now: at(29), wantStep: 1, wantOK: true},
+		{name: "two steps late", secret: rfc6238Secret, code: "287082", now: at(119)},
+		{name: "spaces are ignored", secret: rfc6238Secret, code: "287 082", now: at(59), wantStep: 1, wantOK: true},
+		{name: "lower case secret", secret: strings.ToLower(rfc6238Secret), code: "287082", now: at(59), wantStep: 1, wantOK: true},
+		{name: "wrong code", secret: rfc6238Secret, code: "287083", now: at(59)},
+		{name: "too short", secret: rfc6238Secret, code: "28708", now: at(59)},
+		{name: "eight digits", secret: rfc6238Secret, code: "94287082", now: at(59)},
+		{name: "invalid secret", secret: "not base32!", code: "287082", now: at(59)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, tt.now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = %d, %v; want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("GenerateTOTPSecret() length = %d, want 32 base32 characters for 160 bits", len(secret))
+	}
+	code := totpCode(mustDecodeTOTPSecret(t, secret), time.Now().Unix()/totpPeriod)
+	if _, ok := ValidateTOTP(secret, code, time.Now()); !ok {
+		t.Error("a code for a generated secret does not validate")
+	}
+}
+
+func mustDecodeTOTPSecret(t *testing.T, secret string) []byte {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("recovery code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q was generated twice", code)
+		}
+		seen[code] = true
+	}
+
+	tests := []struct {
+		typed string
+		want  string
+	}{
+		{typed: "abcde-fghij", want: "abcdefghij"},
+		{typed: " ABCDE-FGHIJ ", want: "abcdefghij"},
+		{typed: "abcde fghij", want: "abcdefghij"},
+		{typed: "abcdefghij", want: "abcdefghij"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.typed); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.typed, got, tt.want)
+		}
+	}
+}
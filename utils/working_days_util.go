@@ -0,0 +1,29 @@
+package utils
+
+import "time"
+
+// WorkingDaysBetween counts the days from the date of from to the date of to, both inclusive,
+// that are neither a weekend day nor one of the closed dates (YYYY-MM-DD)
+func WorkingDaysBetween(from, to time.Time, weekendDays []int, closedDates map[string]bool) int {
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	last := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, from.Location())
+
+	days := 0
+	for !day.After(last) {
+		if !IsWeekendDay(day, weekendDays) && !closedDates[day.Format("2006-01-02")] {
+			days++
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return days
+}
+
+// IsWeekendDay reports whether t falls on one of the weekend days
+func IsWeekendDay(t time.Time, weekendDays []int) bool {
+	for _, weekday := range weekendDays {
+		if int(t.Weekday()) == weekday {
+			return true
+		}
+	}
+	return false
+}
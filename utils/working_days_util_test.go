@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkingDaysBetween(t *testing.T) {
+	satSun := []int{0, 6}
+	friSat := []int{5, 6}
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		weekendDays []int
+		closedDates map[string]bool
+		want        int
+	}{
+		{name: "single weekday", from: date(2026, 3, 16), to: date(2026, 3, 16), weekendDays: satSun, want: 1},
+		{name: "single weekend day", from: date(2026, 3, 21), to: date(2026, 3, 21), weekendDays: satSun, want: 0},
+		{name: "full week", from: date(2026, 3, 16), to: date(2026, 3, 22), weekendDays: satSun, want: 5},
+		{name: "whole month", from: date(2026, 3, 1), to: date(2026, 3, 31), weekendDays: satSun, want: 22},
+		{name: "other weekend", from: date(2026, 3, 16), to: date(2026, 3, 22), weekendDays: friSat, want: 5},
+		{name: "no weekend", from: date(2026, 3, 16), to: date(2026, 3, 22), want: 7},
+		{name: "holiday on a weekday", from: date(2026, 3, 16), to: date(2026, 3, 22), weekendDays: satSun, closedDates: map[string]bool{"2026-03-18": true}, want: 4},
+		{name: "holiday on a weekend day", from: date(2026, 3, 16), to: date(2026, 3, 22), weekendDays: satSun, closedDates: map[string]bool{"2026-03-21": true}, want: 5},
+		{name: "times of day are ignored", from: time.Date(2026, 3, 16, 23, 30, 0, 0, time.UTC), to: time.Date(2026, 3, 17, 0, 15, 0, 0, time.UTC), weekendDays: satSun, want: 2},
+		{name: "to before from", from: date(2026, 3, 20), to: date(2026, 3, 16), weekendDays: satSun, want: 0},
+		{name: "across a month end", from: date(2026, 1, 29), to: date(2026, 2, 3), weekendDays: satSun, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WorkingDaysBetween(tt.from, tt.to, tt.weekendDays, tt.closedDates); got != tt.want {
+				t.Errorf("WorkingDaysBetween(%s, %s) = %d, want %d", tt.from.Format("2006-01-02"), tt.to.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSXRows returns the cell text of the first worksheet of an .xlsx file, one slice per row.
+// Numbers are returned as stored; use ExcelSerialDate for date columns.
+func ReadXLSXRows(r io.ReaderAt, size int64) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("not an xlsx file")
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var shared xlsxSharedStrings
+	if file, found := files["xl/sharedStrings.xml"]; found {
+		if err := decodeXLSXPart(file, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetFile, err := firstXLSXSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	var sheet xlsxWorksheet
+	if err := decodeXLSXPart(sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var values []string
+		for i, cell := range row.Cells {
+			column := i
+			if cell.Ref != "" {
+				column = xlsxColumnIndex(cell.Ref)
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, errors.New("xlsx cell " + cell.Ref + " refers to a missing shared string")
+				}
+				values[column] = shared.Items[index].String()
+			case "inlineStr":
+				values[column] = cell.Inline.String()
+			default:
+				values[column] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// ExcelSerialDate converts a spreadsheet date serial number, as xlsx stores dates, to a time
+func ExcelSerialDate(value string) (time.Time, error) {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial)), nil
+}
+
+// firstXLSXSheet finds the first worksheet listed in the workbook, falling back to sheet1.xml
+func firstXLSXSheet(files map[string]*zip.File) (*zip.File, error) {
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	workbookFile, hasWorkbook := files["xl/workbook.xml"]
+	relsFile, hasRels := files["xl/_rels/workbook.xml.rels"]
+	if hasWorkbook && hasRels {
+		if err := decodeXLSXPart(workbookFile, &workbook); err != nil {
+			return nil, err
+		}
+		if err := decodeXLSXPart(relsFile, &rels); err != nil {
+			return nil, err
+		}
+		if len(workbook.Sheets) > 0 {
+			for _, rel := range rels.Relationships {
+				if rel.ID != workbook.Sheets[0].RelID {
+					continue
+				}
+				target := path.Join("xl", rel.Target)
+				if strings.HasPrefix(rel.Target, "/") {
+					target = strings.TrimPrefix(rel.Target, "/")
+				}
+				if file, found := files[target]; found {
+					return file, nil
+				}
+			}
+		}
+	}
+
+	if file, found := files["xl/worksheets/sheet1.xml"]; found {
+		return file, nil
+	}
+	return nil, errors.New("xlsx file has no worksheet")
+}
+
+func decodeXLSXPart(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return xml.NewDecoder(reader).Decode(v)
+}
+
+// xlsxColumnIndex turns the letters of a cell reference such as "AB12" into a zero-based column index
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}